@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type AntiCheatConfig struct {
+	MinCompletionInterval time.Duration // Minimum plausible time between two task completions
+	DailySpikeMultiplier  float64       // Flag when today's points exceed this multiple of the typical daily total
+	DailySpikeMinPoints   int           // Ignore spikes below this many points
+	BaselineDays          int           // Number of past days used to compute the typical daily total
+}
+
+// NewAntiCheatConfig loads the ANTICHEAT_* thresholds
+func NewAntiCheatConfig() (*AntiCheatConfig, error) {
+	minIntervalSeconds, err := getInt("ANTICHEAT_MIN_COMPLETION_INTERVAL_SECONDS", 60, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	spikeMultiplier := 3.0
+	if v := getEnv("ANTICHEAT_DAILY_SPIKE_MULTIPLIER", ""); v != "" {
+		spikeMultiplier, err = strconv.ParseFloat(v, 64)
+		if err != nil || spikeMultiplier <= 1 {
+			return nil, fmt.Errorf("invalid ANTICHEAT_DAILY_SPIKE_MULTIPLIER %q: must be a number above 1", v)
+		}
+	}
+
+	spikeMinPoints, err := getInt("ANTICHEAT_DAILY_SPIKE_MIN_POINTS", 150, 0)
+	if err != nil {
+		return nil, err
+	}
+	baselineDays, err := getInt("ANTICHEAT_BASELINE_DAYS", 14, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AntiCheatConfig{
+		MinCompletionInterval: time.Duration(minIntervalSeconds) * time.Second,
+		DailySpikeMultiplier:  spikeMultiplier,
+		DailySpikeMinPoints:   spikeMinPoints,
+		BaselineDays:          baselineDays,
+	}, nil
+}
@@ -1,66 +1,151 @@
 package config
 
 import (
-	"os"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 type AuthConfig struct {
-	GoogleOAuth       *oauth2.Config
-	JWTSecret         string
-	JWTExpiration     time.Duration
+	OIDCProviders     []OIDCProviderConfig // Identity providers users can sign in with
+	JWTSecret         string       `config:"secret"` // HMAC secret, used only when no signing keys are configured
+	SigningKeys       []SigningKey // Asymmetric keys loaded from JWT_KEYSET_FILE
+	AccessTokenExpiration  time.Duration
+	RefreshTokenExpiration time.Duration
 	CookieDomain      string
 	CookieSecure      bool
 	CookieHttpOnly    bool
 	CookieSameSite    string
+	AdminEmails       []string
+	FrontendURL       string // Base URL of the web app, used for redirects and emailed links
+	PostLoginRedirectURL string   // Where the browser lands after a provider login
+	AllowedRedirectURLs  []string // URL prefixes a login's return_to may point to
+	CSRFSecret           []byte   `config:"secret"` // Key CSRF tokens are signed with
 }
 
-func NewAuthConfig() *AuthConfig {
-	googleOAuth := &oauth2.Config{
-		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		},
-		Endpoint: google.Endpoint,
+// NewAuthConfig loads authentication settings from the environment. It fails when
+// neither a JWT keyset nor a strong JWT secret is configured, or when an identity
+// provider is misconfigured.
+func NewAuthConfig() (*AuthConfig, error) {
+	oidcProviders, err := loadOIDCProviders()
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC configuration: %w", err)
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-super-secret-key-change-this-in-production"
+	// Prefer asymmetric keys so other services can verify tokens through the JWKS
+	jwtSecret := getEnv("JWT_SECRET", "")
+	var signingKeys []SigningKey
+	if keysetFile := getEnv("JWT_KEYSET_FILE", ""); keysetFile != "" {
+		keys, err := LoadSigningKeys(keysetFile)
+		if err != nil {
+			return nil, err
+		}
+		signingKeys = keys
+		jwtSecret = ""
+	} else if err := validateJWTSecret(jwtSecret); err != nil {
+		return nil, fmt.Errorf("invalid JWT configuration: %w", err)
+	}
+
+	accessTokenMinutes, err := getInt("ACCESS_TOKEN_EXPIRATION_MINUTES", 15, 1)
+	if err != nil {
+		return nil, err
+	}
+	refreshTokenDays, err := getInt("REFRESH_TOKEN_EXPIRATION_DAYS", 30, 1)
+	if err != nil {
+		return nil, err
 	}
 
-	jwtExpiration := 24 * time.Hour // 24 hours
-	if exp := os.Getenv("JWT_EXPIRATION_HOURS"); exp != "" {
-		if hours, err := time.ParseDuration(exp + "h"); err == nil {
-			jwtExpiration = hours
+	cookieDomain := getEnv("COOKIE_DOMAIN", "localhost")
+	cookieSecure, err := getBool("COOKIE_SECURE", false)
+	if err != nil {
+		return nil, err
+	}
+	cookieHttpOnly, err := getBool("COOKIE_HTTP_ONLY", true)
+	if err != nil {
+		return nil, err
+	}
+
+	cookieSameSite := getEnv("COOKIE_SAME_SITE", "Lax")
+	switch cookieSameSite {
+	case "Strict", "Lax":
+	case "None":
+		if !cookieSecure {
+			return nil, fmt.Errorf("COOKIE_SAME_SITE=None requires COOKIE_SECURE=true")
 		}
+	default:
+		return nil, fmt.Errorf("invalid COOKIE_SAME_SITE %q: must be Strict, Lax or None", cookieSameSite)
 	}
 
-	cookieDomain := os.Getenv("COOKIE_DOMAIN")
-	if cookieDomain == "" {
-		cookieDomain = "localhost"
+	// Comma-separated list of emails that are granted the admin role on login
+	var adminEmails []string
+	for _, email := range strings.Split(getEnv("ADMIN_EMAILS", ""), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			adminEmails = append(adminEmails, strings.ToLower(email))
+		}
 	}
 
-	cookieSecure := os.Getenv("COOKIE_SECURE") == "true"
-	cookieHttpOnly := os.Getenv("COOKIE_HTTP_ONLY") != "false" // Default to true
+	// Post-login redirects may only target the frontend and explicitly allowed URLs
+	frontendURL := strings.TrimRight(getEnv("FRONTEND_URL", "http://localhost:3000"), "/")
+	postLoginRedirectURL := getEnv("AUTH_POST_LOGIN_REDIRECT_URL", frontendURL+"/auth-callback")
+	allowedRedirectURLs := append([]string{frontendURL + "/"}, splitList(getEnv("AUTH_ALLOWED_REDIRECT_URLS", ""))...)
+	for _, redirectURL := range append([]string{frontendURL, postLoginRedirectURL}, allowedRedirectURLs...) {
+		if err := validateRedirectURL(redirectURL); err != nil {
+			return nil, fmt.Errorf("invalid redirect configuration: %w", err)
+		}
+	}
 
-	cookieSameSite := os.Getenv("COOKIE_SAME_SITE")
-	if cookieSameSite == "" {
-		cookieSameSite = "Lax"
+	csrfSecret, err := loadCSRFSecret(jwtSecret)
+	if err != nil {
+		return nil, err
 	}
 
 	return &AuthConfig{
-		GoogleOAuth:       googleOAuth,
+		OIDCProviders:     oidcProviders,
 		JWTSecret:         jwtSecret,
-		JWTExpiration:     jwtExpiration,
+		SigningKeys:       signingKeys,
+		AccessTokenExpiration:  time.Duration(accessTokenMinutes) * time.Minute,
+		RefreshTokenExpiration: time.Duration(refreshTokenDays) * 24 * time.Hour,
 		CookieDomain:      cookieDomain,
 		CookieSecure:      cookieSecure,
 		CookieHttpOnly:    cookieHttpOnly,
 		CookieSameSite:    cookieSameSite,
+		AdminEmails:       adminEmails,
+		FrontendURL:       frontendURL,
+		PostLoginRedirectURL: postLoginRedirectURL,
+		AllowedRedirectURLs:  allowedRedirectURLs,
+		CSRFSecret:           csrfSecret,
+	}, nil
+}
+
+// loadCSRFSecret reads CSRF_SECRET, or derives a key from the JWT secret. With a JWT keyset
+// there is no shared secret to derive it from, so CSRF_SECRET is required: a key made up by
+// each process would not verify tokens or OAuth flows across replicas or restarts.
+func loadCSRFSecret(jwtSecret string) ([]byte, error) {
+	if secret := getEnv("CSRF_SECRET", ""); secret != "" {
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("CSRF_SECRET must be at least 32 characters")
+		}
+		return []byte(secret), nil
+	}
+	if jwtSecret == "" {
+		return nil, fmt.Errorf("CSRF_SECRET must be set when JWT_KEYSET_FILE is used")
 	}
-} 
\ No newline at end of file
+	mac := hmac.New(sha256.New, []byte(jwtSecret))
+	mac.Write([]byte("fithero-csrf"))
+	return mac.Sum(nil), nil
+}
+
+// validateRedirectURL requires an absolute http(s) URL without credentials or fragment
+func validateRedirectURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%q: %w", rawURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil || u.Fragment != "" {
+		return fmt.Errorf("%q must be an absolute http(s) URL", rawURL)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeKeyset writes a keyset with one Ed25519 key and returns the manifest's path
+func writeKeyset(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"keys": [{"kid": "key-1", "alg": "EdDSA", "private_key_file": "key.pem", "active_from": "2024-01-01T00:00:00Z"}]}`
+	path := filepath.Join(dir, "keyset.json")
+	if err := os.WriteFile(path, []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewAuthConfigRequiresCSRFSecretWithKeyset(t *testing.T) {
+	useSource(nil, nil)
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_KEYSET_FILE", writeKeyset(t))
+	t.Setenv("CSRF_SECRET", "")
+
+	_, err := NewAuthConfig()
+	if err == nil || err.Error() != "CSRF_SECRET must be set when JWT_KEYSET_FILE is used" {
+		t.Fatalf("NewAuthConfig: err = %v, want CSRF_SECRET to be required", err)
+	}
+
+	t.Setenv("CSRF_SECRET", "short")
+	if _, err := NewAuthConfig(); err == nil || !strings.Contains(err.Error(), "at least 32 characters") {
+		t.Errorf("short CSRF_SECRET: err = %v", err)
+	}
+
+	t.Setenv("CSRF_SECRET", strings.Repeat("c", 32))
+	authConfig, err := NewAuthConfig()
+	if err != nil {
+		t.Fatalf("NewAuthConfig: %v", err)
+	}
+	if string(authConfig.CSRFSecret) != strings.Repeat("c", 32) || len(authConfig.SigningKeys) != 1 || authConfig.JWTSecret != "" {
+		t.Errorf("config = %+v", authConfig)
+	}
+}
+
+func TestNewAuthConfigDerivesCSRFSecretFromJWTSecret(t *testing.T) {
+	useSource(nil, nil)
+	t.Setenv("JWT_KEYSET_FILE", "")
+	t.Setenv("CSRF_SECRET", "")
+	t.Setenv("JWT_SECRET", strings.Repeat("j", 32))
+
+	first, err := NewAuthConfig()
+	if err != nil {
+		t.Fatalf("NewAuthConfig: %v", err)
+	}
+	second, err := NewAuthConfig()
+	if err != nil {
+		t.Fatalf("NewAuthConfig: %v", err)
+	}
+
+	// Every replica sharing the JWT secret derives the same key, which is not the secret itself
+	if len(first.CSRFSecret) != 32 || !bytes.Equal(first.CSRFSecret, second.CSRFSecret) || string(first.CSRFSecret) == first.JWTSecret {
+		t.Errorf("derived CSRF secrets %x and %x", first.CSRFSecret, second.CSRFSecret)
+	}
+}
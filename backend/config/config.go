@@ -0,0 +1,203 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Config holds every setting of the backend. Settings are read from, in increasing
+// precedence, a KEY=VALUE config file, the environment and -set flags.
+type Config struct {
+	Server      *ServerConfig
+	Database    *DatabaseConfig
+	CORS        *CORSConfig
+	Auth        *AuthConfig
+	Mail        *MailConfig
+	Storage     *StorageConfig
+	Logging     *LoggingConfig
+	Tracing     *TracingConfig
+	RateLimit   *RateLimitConfig
+	Timeouts    *RequestTimeoutConfig
+	Idempotency *IdempotencyConfig
+	Health      *HealthConfig
+	Username    *UsernameConfig
+	AntiCheat   *AntiCheatConfig
+	Jobs        *JobConfig
+	Outbox      *OutboxConfig
+	Webhooks    *WebhookConfig
+}
+
+// settingFlags collects repeated -set KEY=VALUE flags
+type settingFlags map[string]string
+
+func (f settingFlags) String() string {
+	return ""
+}
+
+func (f settingFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("%q is not KEY=VALUE", value)
+	}
+	f[key] = val
+	return nil
+}
+
+// Load parses the command line flags in args, reads the config file they or CONFIG_FILE name,
+// and loads and validates all settings. Every invalid setting is reported, not just the first.
+func Load(args []string) (*Config, error) {
+	overrides := settingFlags{}
+	flags := flag.NewFlagSet("fithero-backend", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "file of KEY=VALUE settings, overridden by the environment")
+	flags.Var(overrides, "set", "override a setting, e.g. -set PORT=9090 (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	var file map[string]string
+	if *configFile != "" {
+		var err error
+		if file, err = readConfigFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+	useSource(file, overrides)
+
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	cfg := &Config{Storage: NewStorageConfig()}
+	var err error
+	cfg.Server, err = NewServerConfig()
+	check(err)
+	cfg.Database, err = NewDatabaseConfig()
+	check(err)
+	cfg.Auth, err = NewAuthConfig()
+	check(err)
+	frontendURL := getEnv("FRONTEND_URL", "")
+	if cfg.Auth != nil {
+		frontendURL = cfg.Auth.FrontendURL
+	}
+	cfg.CORS, err = NewCORSConfig(frontendURL)
+	check(err)
+	cfg.Mail, err = NewMailConfig()
+	check(err)
+	cfg.Logging, err = NewLoggingConfig()
+	check(err)
+	cfg.Tracing, err = NewTracingConfig()
+	check(err)
+	cfg.RateLimit, err = NewRateLimitConfig()
+	check(err)
+	cfg.Timeouts, err = NewRequestTimeoutConfig()
+	check(err)
+	cfg.Idempotency, err = NewIdempotencyConfig()
+	check(err)
+	cfg.Health, err = NewHealthConfig()
+	check(err)
+	cfg.Username, err = NewUsernameConfig()
+	check(err)
+	cfg.AntiCheat, err = NewAntiCheatConfig()
+	check(err)
+	cfg.Jobs, err = NewJobConfig()
+	check(err)
+	cfg.Outbox, err = NewOutboxConfig()
+	check(err)
+	cfg.Webhooks, err = NewWebhookConfig()
+	check(err)
+
+	if cfg.Server != nil && cfg.Timeouts != nil && cfg.Server.WriteTimeout > 0 {
+		// Responses to requests that hit their deadline must still be written
+		for name, timeout := range map[string]time.Duration{
+			"REQUEST_TIMEOUT_AUTH":   cfg.Timeouts.Auth,
+			"REQUEST_TIMEOUT_API":    cfg.Timeouts.API,
+			"REQUEST_TIMEOUT_PUBLIC": cfg.Timeouts.Public,
+			"REQUEST_TIMEOUT_ADMIN":  cfg.Timeouts.Admin,
+		} {
+			if timeout >= cfg.Server.WriteTimeout {
+				errs = append(errs, fmt.Errorf("%s (%s) must be shorter than SERVER_WRITE_TIMEOUT (%s)", name, timeout, cfg.Server.WriteTimeout))
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Print writes every setting as "Section.Field = value". Fields tagged config:"secret" are
+// redacted, so the output is safe to share.
+func (c *Config) Print(w io.Writer) {
+	printValue(w, "", reflect.ValueOf(c).Elem())
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+func printValue(w io.Writer, name string, v reflect.Value) {
+	if v.Type().Implements(stringerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			fmt.Fprintf(w, "%s = <nil>\n", name)
+			return
+		}
+		fmt.Fprintf(w, "%s = %s\n", name, v.Interface())
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			fmt.Fprintf(w, "%s = <nil>\n", name)
+			return
+		}
+		printValue(w, name, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldName := field.Name
+			if name != "" {
+				fieldName = name + "." + field.Name
+			}
+			if field.Tag.Get("config") == "secret" {
+				fmt.Fprintf(w, "%s = %s\n", fieldName, redact(v.Field(i)))
+				continue
+			}
+			printValue(w, fieldName, v.Field(i))
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct || v.Len() == 0 {
+			fmt.Fprintf(w, "%s = %q\n", name, v.Interface())
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			printValue(w, fmt.Sprintf("%s[%d]", name, i), v.Index(i))
+		}
+	case reflect.String:
+		fmt.Fprintf(w, "%s = %q\n", name, v.String())
+	default:
+		fmt.Fprintf(w, "%s = %v\n", name, v.Interface())
+	}
+}
+
+// redact hides a secret, showing only whether it is set
+func redact(v reflect.Value) string {
+	if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+		return `""`
+	}
+	return "[redacted]"
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testJWTSecret = "0123456789abcdefghijklmnopqrstuv"
+
+// unsetenv removes environment variables for the duration of the test
+func unsetenv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fithero.env")
+	if err := os.WriteFile(file, []byte("JWT_SECRET="+testJWTSecret+"\nPORT=7000\nDB_NAME=from_file\nDB_MAX_OPEN_CONNS=40\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	unsetenv(t, "JWT_SECRET", "JWT_KEYSET_FILE", "DB_MAX_OPEN_CONNS")
+	t.Setenv("PORT", "8000")
+	t.Setenv("DB_NAME", "from_env")
+
+	cfg, err := Load([]string{"-config", file, "-set", "PORT=9000"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Port != 9000 {
+		t.Errorf("port = %d, want the flag to override the environment and file", cfg.Server.Port)
+	}
+	if cfg.Database.Name != "from_env" {
+		t.Errorf("database name = %q, want the environment to override the file", cfg.Database.Name)
+	}
+	if cfg.Database.MaxOpenConns != 40 || cfg.Auth.JWTSecret != testJWTSecret {
+		t.Errorf("settings only in the file were not read: %d connections", cfg.Database.MaxOpenConns)
+	}
+}
+
+func TestLoadReportsEveryInvalidSetting(t *testing.T) {
+	t.Setenv("JWT_KEYSET_FILE", "")
+	_, err := Load([]string{
+		"-set", "JWT_SECRET=" + testJWTSecret,
+		"-set", "DB_MAX_OPEN_CONNS=5",
+		"-set", "DB_MAX_IDLE_CONNS=10",
+		"-set", "COOKIE_SAME_SITE=Sometimes",
+		"-set", "CORS_ALLOWED_ORIGINS=https://app.example.com/path",
+		"-set", "REQUEST_TIMEOUT_ADMIN=60s",
+		"-set", "SERVER_WRITE_TIMEOUT=45s",
+		"-set", "OUTBOX_BATCH_SIZE=none",
+	})
+	if err == nil {
+		t.Fatal("Load accepted invalid settings")
+	}
+	for _, want := range []string{"DB_MAX_IDLE_CONNS (10) cannot exceed DB_MAX_OPEN_CONNS (5)", "COOKIE_SAME_SITE", "OUTBOX_BATCH_SIZE", "CORS_ALLOWED_ORIGINS", "REQUEST_TIMEOUT_ADMIN (1m0s) must be shorter than SERVER_WRITE_TIMEOUT (45s)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadRejectsMissingJWTSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_KEYSET_FILE", "")
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "JWT_SECRET or JWT_KEYSET_FILE must be configured") {
+		t.Errorf("Load: err = %v", err)
+	}
+	if _, err := Load([]string{"-set", "JWT_SECRET=short"}); err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Errorf("short secret: err = %v", err)
+	}
+	if _, err := Load([]string{"-set", "JWT_SECRET=" + testJWTSecret, "unexpected"}); err == nil || !strings.Contains(err.Error(), `unexpected argument "unexpected"`) {
+		t.Errorf("extra argument: err = %v", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	t.Setenv("JWT_KEYSET_FILE", "")
+	cfg, err := Load([]string{"-set", "JWT_SECRET=" + testJWTSecret, "-set", "DB_PASSWORD=hunter2-database", "-set", "SMTP_PASSWORD="})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var out bytes.Buffer
+	cfg.Print(&out)
+	printed := out.String()
+	for _, secret := range []string{testJWTSecret, "hunter2-database"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("printed config contains the secret %q", secret)
+		}
+	}
+	for _, line := range []string{"Auth.JWTSecret = [redacted]", "Database.Password = [redacted]", `Mail.SMTPPassword = ""`, "Server.ReadTimeout = 30s"} {
+		if !strings.Contains(printed, line+"\n") {
+			t.Errorf("printed config lacks %q", line)
+		}
+	}
+}
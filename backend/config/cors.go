@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type CORSConfig struct {
+	AllowedOrigins []string // Browser origins allowed to call the API with credentials
+}
+
+// NewCORSConfig loads CORS_ALLOWED_ORIGINS. The frontend's origin is always allowed.
+func NewCORSConfig(frontendURL string) (*CORSConfig, error) {
+	var origins []string
+	for _, origin := range splitList(getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3000,http://127.0.0.1:3000")) {
+		origin = strings.TrimSuffix(origin, "/")
+		if err := validateOrigin(origin); err != nil {
+			return nil, fmt.Errorf("invalid CORS_ALLOWED_ORIGINS: %w", err)
+		}
+		origins = append(origins, origin)
+	}
+
+	if frontend, err := url.Parse(frontendURL); err == nil && frontend.Host != "" {
+		frontendOrigin := frontend.Scheme + "://" + frontend.Host
+		allowed := false
+		for _, origin := range origins {
+			allowed = allowed || origin == frontendOrigin
+		}
+		if !allowed {
+			origins = append(origins, frontendOrigin)
+		}
+	}
+
+	return &CORSConfig{AllowedOrigins: origins}, nil
+}
+
+// validateOrigin requires a scheme and host without a path, e.g. https://app.example.com
+func validateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("%q: %w", origin, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+		return fmt.Errorf("%q must be an origin such as https://app.example.com", origin)
+	}
+	return nil
+}
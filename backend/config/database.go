@@ -1,12 +1,16 @@
 package config
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
-	"os"
+	"log/slog"
+	"strings"
 	"time"
 
+	"fithero-backend/logging"
 	"fithero-backend/models"
+	"fithero-backend/tracing"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -14,37 +18,108 @@ import (
 
 var DB *gorm.DB
 
-// InitDB initializes the database connection and returns the instance
-func InitDB() (*gorm.DB, error) {
-	// Database configuration from environment variables
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnv("DB_PORT", "5432")
-	dbUser := getEnv("DB_USER", "fithero_user")
-	dbPassword := getEnv("DB_PASSWORD", "fithero_password")
-	dbName := getEnv("DB_NAME", "fithero")
+// GORM log levels accepted by DB_LOG_LEVEL
+var gormLogLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
 
+type DatabaseConfig struct {
+	Host               string
+	Port               int
+	User               string
+	Password           string `config:"secret"`
+	Name               string
+	SSLMode            string
+	MaxOpenConns       int           // 0 means unlimited
+	MaxIdleConns       int
+	ConnMaxLifetime    time.Duration // 0 keeps connections open indefinitely
+	LogLevel           string        // silent, error, warn or info; queries are only printed when LOG_LEVEL=debug
+	SlowQueryThreshold time.Duration // Queries slower than this are logged as warnings
+}
+
+// NewDatabaseConfig loads the DB_* connection and pool settings
+func NewDatabaseConfig() (*DatabaseConfig, error) {
+	port, err := getInt("DB_PORT", 5432, 1)
+	if err != nil {
+		return nil, err
+	}
+	maxOpenConns, err := getInt("DB_MAX_OPEN_CONNS", 25, 0)
+	if err != nil {
+		return nil, err
+	}
+	maxIdleConns, err := getInt("DB_MAX_IDLE_CONNS", 10, 0)
+	if err != nil {
+		return nil, err
+	}
+	if maxOpenConns > 0 && maxIdleConns > maxOpenConns {
+		return nil, fmt.Errorf("DB_MAX_IDLE_CONNS (%d) cannot exceed DB_MAX_OPEN_CONNS (%d)", maxIdleConns, maxOpenConns)
+	}
+	connMaxLifetime, err := getDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	slowQueryThreshold, err := getDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &DatabaseConfig{
+		Host:               getEnv("DB_HOST", "localhost"),
+		Port:               port,
+		User:               getEnv("DB_USER", "fithero_user"),
+		Password:           getEnv("DB_PASSWORD", "fithero_password"),
+		Name:               getEnv("DB_NAME", "fithero"),
+		SSLMode:            getEnv("DB_SSL_MODE", "disable"),
+		MaxOpenConns:       maxOpenConns,
+		MaxIdleConns:       maxIdleConns,
+		ConnMaxLifetime:    connMaxLifetime,
+		LogLevel:           strings.ToLower(getEnv("DB_LOG_LEVEL", "info")),
+		SlowQueryThreshold: slowQueryThreshold,
+	}
+
+	if _, ok := gormLogLevels[cfg.LogLevel]; !ok {
+		return nil, fmt.Errorf("invalid DB_LOG_LEVEL %q: must be silent, error, warn or info", cfg.LogLevel)
+	}
+	switch cfg.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		return nil, fmt.Errorf("invalid DB_SSL_MODE %q", cfg.SSLMode)
+	}
+
+	return cfg, nil
+}
+
+// InitDB initializes the database connection and returns the instance
+func InitDB(cfg *DatabaseConfig) (*gorm.DB, error) {
 	// Create connection string
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
-		dbHost, dbUser, dbPassword, dbName, dbPort)
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=UTC",
+		cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Port, cfg.SSLMode)
 
 	var err error
 	
 	// Try to connect to the database with retries
 	for i := 0; i < 30; i++ {
 		DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
+			Logger: logging.NewGormLogger(gormLogLevels[cfg.LogLevel], cfg.SlowQueryThreshold),
 		})
 		if err == nil {
 			// Test the connection
-			sqlDB, err := DB.DB()
+			var sqlDB *sql.DB
+			sqlDB, err = DB.DB()
 			if err == nil {
+				sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+				sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+				sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 				err = sqlDB.Ping()
 				if err == nil {
 					break
 				}
 			}
 		}
-		log.Println("Waiting for database to be ready...")
+		slog.Info("Waiting for database to be ready", "attempt", i+1)
 		time.Sleep(2 * time.Second)
 	}
 
@@ -52,21 +127,33 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	log.Println("Successfully connected to database with GORM")
+	slog.Info("Connected to database", "host", cfg.Host, "database", cfg.Name)
+
+	// Record a span for each query made while handling a traced request
+	if err := DB.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
 
 	// Auto-migrate the schema
 	err = AutoMigrate(DB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
+	if err := runMigrations(DB); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
 
-	log.Println("Database migration completed")
+	slog.Info("Database migration completed")
 	return DB, nil
 }
 
 // InitDatabase initializes the database connection using GORM (legacy function)
 func InitDatabase() {
-	db, err := InitDB()
+	cfg, err := NewDatabaseConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := InitDB(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -77,10 +164,25 @@ func InitDatabase() {
 func AutoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.User{},
+		&models.UserIdentity{},
+		&models.UsernameHistory{},
 		&models.Task{},
 		&models.DailyTask{},
 		&models.Achievement{},
 		&models.UserAchievement{},
+		&models.AchievementAuditLog{},
+		&models.AntiCheatFlag{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.EmailToken{},
+		&models.PersonalAccessToken{},
+		&models.IdempotencyKey{},
+		&models.JobRun{},
+		&models.LeaderboardSnapshot{},
+		&models.OutboxEvent{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.SchemaMigration{},
 	)
 }
 
@@ -88,11 +190,4 @@ func AutoMigrate(db *gorm.DB) error {
 func GetDB() *gorm.DB {
 	return DB
 }
-
-// getEnv gets environment variable with fallback
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-} 
\ No newline at end of file
+ 
\ No newline at end of file
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"time"
+)
+
+type HealthConfig struct {
+	CacheTTL     time.Duration // How long a readiness report is reused before dependencies are checked again
+	CheckTimeout time.Duration // How long each dependency check may take
+}
+
+// NewHealthConfig loads HEALTH_CACHE_SECONDS and HEALTH_CHECK_TIMEOUT_SECONDS
+func NewHealthConfig() (*HealthConfig, error) {
+	cacheSeconds, err := getInt("HEALTH_CACHE_SECONDS", 5, 0)
+	if err != nil {
+		return nil, err
+	}
+	checkTimeoutSeconds, err := getInt("HEALTH_CHECK_TIMEOUT_SECONDS", 2, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HealthConfig{
+		CacheTTL:     time.Duration(cacheSeconds) * time.Second,
+		CheckTimeout: time.Duration(checkTimeoutSeconds) * time.Second,
+	}, nil
+}
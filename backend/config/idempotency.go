@@ -0,0 +1,33 @@
+package config
+
+import (
+	"time"
+)
+
+type IdempotencyConfig struct {
+	KeyTTL          time.Duration // How long responses are kept for retries
+	LockTimeout     time.Duration // After this long an unfinished request is presumed dead and its key can be retried
+	CleanupInterval time.Duration // How often expired keys are deleted
+}
+
+// NewIdempotencyConfig loads the IDEMPOTENCY_* settings
+func NewIdempotencyConfig() (*IdempotencyConfig, error) {
+	keyTTLHours, err := getInt("IDEMPOTENCY_KEY_TTL_HOURS", 24, 1)
+	if err != nil {
+		return nil, err
+	}
+	lockTimeoutSeconds, err := getInt("IDEMPOTENCY_LOCK_TIMEOUT_SECONDS", 60, 1)
+	if err != nil {
+		return nil, err
+	}
+	cleanupIntervalMinutes, err := getInt("IDEMPOTENCY_CLEANUP_INTERVAL_MINUTES", 60, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdempotencyConfig{
+		KeyTTL:          time.Duration(keyTTLHours) * time.Hour,
+		LockTimeout:     time.Duration(lockTimeoutSeconds) * time.Second,
+		CleanupInterval: time.Duration(cleanupIntervalMinutes) * time.Minute,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"time"
+)
+
+type JobConfig struct {
+	Enabled      bool
+	MaxAttempts  int           // Attempts per run before it is recorded as failed
+	RetryBackoff time.Duration // Wait before the first retry, doubled for every further retry
+
+	// Cron expressions, evaluated in UTC. The task and streak jobs work through each user
+	// timezone and skip what is already done, so they run hourly to follow local midnights.
+	PregenerateSchedule   string
+	ExpireSchedule        string
+	StreakSchedule        string
+	LeaderboardSchedule   string
+	PurgeOutboxSchedule   string
+	PurgeWebhooksSchedule string
+
+	PregenerateActiveDays   int // Only users who logged in within this many days get tasks generated in advance
+	LeaderboardSnapshotSize int // Number of top users recorded in the daily leaderboard snapshot
+}
+
+// NewJobConfig loads the JOBS_ENABLED and JOB_* settings
+func NewJobConfig() (*JobConfig, error) {
+	enabled, err := getBool("JOBS_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts, err := getInt("JOB_MAX_ATTEMPTS", 3, 1)
+	if err != nil {
+		return nil, err
+	}
+	retryBackoff, err := getDuration("JOB_RETRY_BACKOFF", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	pregenerateSchedule, err := getCron("JOB_SCHEDULE_PREGENERATE_TASKS", "0 * * * *")
+	if err != nil {
+		return nil, err
+	}
+	expireSchedule, err := getCron("JOB_SCHEDULE_EXPIRE_TASKS", "10 * * * *")
+	if err != nil {
+		return nil, err
+	}
+	streakSchedule, err := getCron("JOB_SCHEDULE_EVALUATE_STREAKS", "20 * * * *")
+	if err != nil {
+		return nil, err
+	}
+	leaderboardSchedule, err := getCron("JOB_SCHEDULE_LEADERBOARD_SNAPSHOT", "5 0 * * *")
+	if err != nil {
+		return nil, err
+	}
+	purgeOutboxSchedule, err := getCron("JOB_SCHEDULE_PURGE_OUTBOX", "30 3 * * *")
+	if err != nil {
+		return nil, err
+	}
+	purgeWebhooksSchedule, err := getCron("JOB_SCHEDULE_PURGE_WEBHOOK_DELIVERIES", "40 3 * * *")
+	if err != nil {
+		return nil, err
+	}
+	activeDays, err := getInt("JOB_PREGENERATE_ACTIVE_DAYS", 14, 1)
+	if err != nil {
+		return nil, err
+	}
+	snapshotSize, err := getInt("JOB_LEADERBOARD_SNAPSHOT_SIZE", 100, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JobConfig{
+		Enabled:                 enabled,
+		MaxAttempts:             maxAttempts,
+		RetryBackoff:            retryBackoff,
+		PregenerateSchedule:     pregenerateSchedule,
+		ExpireSchedule:          expireSchedule,
+		StreakSchedule:          streakSchedule,
+		LeaderboardSchedule:     leaderboardSchedule,
+		PurgeOutboxSchedule:     purgeOutboxSchedule,
+		PurgeWebhooksSchedule:   purgeWebhooksSchedule,
+		PregenerateActiveDays:   activeDays,
+		LeaderboardSnapshotSize: snapshotSize,
+	}, nil
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Supported JWT signing algorithms
+const (
+	SigningAlgHS256 = "HS256"
+	SigningAlgRS256 = "RS256"
+	SigningAlgEdDSA = "EdDSA"
+)
+
+// minJWTSecretLength is the shortest HMAC secret accepted at startup
+const minJWTSecretLength = 32
+
+// insecureJWTSecrets are placeholder values from old templates that must never be used
+var insecureJWTSecrets = map[string]bool{
+	"your-super-secret-key-change-this-in-production":       true,
+	"your_super_secret_jwt_key_at_least_32_characters_long": true,
+}
+
+// SigningKey is an asymmetric key used to sign access tokens. Keys are selected by
+// kid; the newest key whose ActiveFrom has passed signs new tokens, and every key
+// that is not yet retired is accepted for verification and published in the JWKS.
+type SigningKey struct {
+	KID         string
+	Algorithm   string
+	PrivateKey  crypto.Signer `config:"secret"`
+	ActiveFrom  time.Time
+	RetireAfter *time.Time
+}
+
+// PublicKey returns the public half of the key
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// IsRetired reports whether the key should no longer verify tokens
+func (k *SigningKey) IsRetired(now time.Time) bool {
+	return k.RetireAfter != nil && !now.Before(*k.RetireAfter)
+}
+
+// keysetFile is the JSON manifest pointed to by JWT_KEYSET_FILE
+type keysetFile struct {
+	Keys []struct {
+		KID            string     `json:"kid"`
+		Algorithm      string     `json:"alg"`
+		PrivateKeyFile string     `json:"private_key_file"`
+		ActiveFrom     time.Time  `json:"active_from"`
+		RetireAfter    *time.Time `json:"retire_after,omitempty"`
+	} `json:"keys"`
+}
+
+// LoadSigningKeys reads the keyset manifest and the PEM private keys it references.
+// Relative key paths are resolved against the manifest's directory.
+func LoadSigningKeys(path string) ([]SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT keyset: %w", err)
+	}
+
+	var manifest keysetFile
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse JWT keyset: %w", err)
+	}
+	if len(manifest.Keys) == 0 {
+		return nil, errors.New("JWT keyset contains no keys")
+	}
+
+	seen := make(map[string]bool, len(manifest.Keys))
+	keys := make([]SigningKey, 0, len(manifest.Keys))
+	for _, entry := range manifest.Keys {
+		if entry.KID == "" {
+			return nil, errors.New("JWT keyset entry is missing kid")
+		}
+		if seen[entry.KID] {
+			return nil, fmt.Errorf("duplicate kid %q in JWT keyset", entry.KID)
+		}
+		seen[entry.KID] = true
+
+		keyPath := entry.PrivateKeyFile
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(filepath.Dir(path), keyPath)
+		}
+		privateKey, err := loadPrivateKey(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", entry.KID, err)
+		}
+
+		switch privateKey.(type) {
+		case *rsa.PrivateKey:
+			if entry.Algorithm != SigningAlgRS256 {
+				return nil, fmt.Errorf("key %q: RSA keys must use %s", entry.KID, SigningAlgRS256)
+			}
+		case ed25519.PrivateKey:
+			if entry.Algorithm != SigningAlgEdDSA {
+				return nil, fmt.Errorf("key %q: Ed25519 keys must use %s", entry.KID, SigningAlgEdDSA)
+			}
+		default:
+			return nil, fmt.Errorf("key %q: unsupported key type %T", entry.KID, privateKey)
+		}
+
+		keys = append(keys, SigningKey{
+			KID:         entry.KID,
+			Algorithm:   entry.Algorithm,
+			PrivateKey:  privateKey,
+			ActiveFrom:  entry.ActiveFrom,
+			RetireAfter: entry.RetireAfter,
+		})
+	}
+
+	return keys, nil
+}
+
+// validateJWTSecret rejects missing, placeholder and short HMAC secrets
+func validateJWTSecret(secret string) error {
+	if secret == "" {
+		return errors.New("JWT_SECRET or JWT_KEYSET_FILE must be configured")
+	}
+	if insecureJWTSecrets[secret] {
+		return errors.New("JWT_SECRET is set to a placeholder value")
+	}
+	if len(secret) < minJWTSecretLength {
+		return fmt.Errorf("JWT_SECRET must be at least %d characters", minJWTSecretLength)
+	}
+	return nil
+}
+
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
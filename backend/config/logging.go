@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+
+	"fithero-backend/logging"
+)
+
+type LoggingConfig struct {
+	Level  slog.Level
+	Format string // json or text
+}
+
+// NewLoggingConfig loads LOG_LEVEL (debug, info, warn, error) and LOG_FORMAT (json, text)
+func NewLoggingConfig() (*LoggingConfig, error) {
+	level, err := logging.ParseLevel(getEnv("LOG_LEVEL", "info"))
+	if err != nil {
+		return nil, err
+	}
+
+	format := getEnv("LOG_FORMAT", logging.FormatText)
+	switch format {
+	case logging.FormatJSON, logging.FormatText:
+	default:
+		return nil, fmt.Errorf("unknown LOG_FORMAT %q", format)
+	}
+
+	return &LoggingConfig{
+		Level:  level,
+		Format: format,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Mail drivers
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverFile = "file"
+	MailDriverLog  = "log"
+)
+
+type MailConfig struct {
+	Driver       string // smtp, file or log
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string `config:"secret"`
+	FileDir      string // Directory .eml files are written to by the file driver
+}
+
+// NewMailConfig loads mailer settings from the environment. Without MAIL_DRIVER emails are only logged.
+func NewMailConfig() (*MailConfig, error) {
+	cfg := &MailConfig{
+		Driver:       getEnv("MAIL_DRIVER", MailDriverLog),
+		From:         getEnv("MAIL_FROM", "FitHero <no-reply@fithero.local>"),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     587,
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		FileDir:      getEnv("MAIL_FILE_DIR", "mail"),
+	}
+
+	port, err := getInt("SMTP_PORT", cfg.SMTPPort, 1)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SMTPPort = port
+
+	switch cfg.Driver {
+	case MailDriverSMTP:
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("MAIL_DRIVER=smtp requires SMTP_HOST")
+		}
+	case MailDriverFile, MailDriverLog:
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", cfg.Driver)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+)
+
+// migrationLockID is the Postgres advisory lock held while a migration runs, so instances
+// starting together apply each migration once
+const migrationLockID = 7_302_114
+
+// migration repeats the changes of a file in migrations/ for databases created before it was
+// added, since Postgres only runs those files when it creates a new volume. Migrations must be
+// idempotent: on a new volume the file has already been applied.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *gorm.DB) error
+}
+
+// migrations are applied in order at startup, after AutoMigrate has created the tables and
+// columns of the models. The tables of 001_init.sql need no further changes.
+var migrations = []migration{
+	{version: 2, name: "002_achievement_requirements", up: seedAchievementRequirements},
+}
+
+// SchemaVersion is the database schema version this build expects: the version of the last
+// migration, which is only recorded once it has run
+var SchemaVersion = migrations[len(migrations)-1].version
+
+// achievementLevelGates are the minimum levels of achievements further along a progression chain
+var achievementLevelGates = map[int][]string{
+	2: {"Health Guardian", "Fitness Coach"},
+	3: {"Wellness Warrior", "Wellness Expert"},
+	4: {"Fitness Champion", "Fitness Director"},
+	5: {"Ultimate Hero", "Health Guru"},
+}
+
+// achievementChains pairs each career and character achievement with the one it requires
+var achievementChains = [][2]string{
+	{"Fitness Coach", "Personal Trainer"},
+	{"Wellness Expert", "Fitness Coach"},
+	{"Fitness Director", "Wellness Expert"},
+	{"Health Guru", "Fitness Director"},
+	{"Health Guardian", "Fitness Apprentice"},
+	{"Wellness Warrior", "Health Guardian"},
+	{"Fitness Champion", "Wellness Warrior"},
+	{"Ultimate Hero", "Fitness Champion"},
+}
+
+// runMigrations applies the migrations not yet recorded in schema_migrations. Each migration
+// runs in its own transaction together with the record of its version.
+func runMigrations(db *gorm.DB) error {
+	for _, m := range migrations {
+		applied := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+				return err
+			}
+			var count int64
+			if err := tx.Model(&models.SchemaMigration{}).Where("version = ?", m.version).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			applied = true
+			return tx.Create(&models.SchemaMigration{Version: m.version, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		if applied {
+			slog.Info("Applied database migration", "version", m.version, "name", m.name)
+		}
+	}
+	return nil
+}
+
+// seedAchievementRequirements applies the level gates and prerequisite chains of
+// migrations/002_achievement_requirements.sql. Levels are only raised from the default, and
+// existing prerequisites are kept.
+func seedAchievementRequirements(tx *gorm.DB) error {
+	for level, titles := range achievementLevelGates {
+		err := tx.Exec("UPDATE achievements SET min_level = ? WHERE title IN ? AND min_level = 1", level, titles).Error
+		if err != nil {
+			return err
+		}
+	}
+	for _, chain := range achievementChains {
+		err := tx.Exec(`INSERT INTO achievement_prerequisites (achievement_id, prerequisite_id)
+			SELECT a.id, p.id FROM achievements a, achievements p
+			WHERE a.title = ? AND p.title = ? AND a.deleted_at IS NULL AND p.deleted_at IS NULL
+			ON CONFLICT DO NOTHING`, chain[0], chain[1]).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// googleIssuerURL is the OpenID Connect issuer for Google accounts
+const googleIssuerURL = "https://accounts.google.com"
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// OIDCProviderConfig describes an OpenID Connect identity provider users can sign in with
+type OIDCProviderConfig struct {
+	Name         string // URL-safe identifier, e.g. "google" or "corp"
+	DisplayName  string
+	IssuerURL    string // Endpoints and signing keys are discovered from the issuer
+	ClientID     string
+	ClientSecret string `config:"secret"`
+	RedirectURL  string
+	Scopes       []string
+}
+
+// loadOIDCProviders reads the configured identity providers. Google is configured through
+// GOOGLE_CLIENT_ID/GOOGLE_CLIENT_SECRET/GOOGLE_REDIRECT_URL; further providers are listed in
+// OIDC_PROVIDERS and configured through OIDC_<NAME>_ISSUER_URL, _CLIENT_ID, _CLIENT_SECRET,
+// _REDIRECT_URL, _SCOPES and _DISPLAY_NAME.
+func loadOIDCProviders() ([]OIDCProviderConfig, error) {
+	var providers []OIDCProviderConfig
+
+	if clientID := getEnv("GOOGLE_CLIENT_ID", ""); clientID != "" {
+		providers = append(providers, OIDCProviderConfig{
+			Name:         "google",
+			DisplayName:  "Google",
+			IssuerURL:    googleIssuerURL,
+			ClientID:     clientID,
+			ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("GOOGLE_REDIRECT_URL", ""),
+			Scopes:       defaultOIDCScopes(),
+		})
+	}
+
+	for _, name := range splitList(getEnv("OIDC_PROVIDERS", "")) {
+		name = strings.ToLower(name)
+		if !providerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid OIDC provider name %q", name)
+		}
+		for _, existing := range providers {
+			if existing.Name == name {
+				return nil, fmt.Errorf("OIDC provider %q configured twice", name)
+			}
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			DisplayName:  getEnv(prefix+"DISPLAY_NAME", name),
+			IssuerURL:    getEnv(prefix+"ISSUER_URL", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       splitList(getEnv(prefix+"SCOPES", "")),
+		}
+		if provider.IssuerURL == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			return nil, fmt.Errorf("OIDC provider %q requires %sISSUER_URL, %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix, prefix)
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = defaultOIDCScopes()
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+func defaultOIDCScopes() []string {
+	return []string{"openid", "email", "profile"}
+}
+
+// splitList splits a comma or space separated environment value
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+type OutboxConfig struct {
+	PollInterval   time.Duration // How often the dispatcher looks for new events
+	BatchSize      int           // Events claimed per poll
+	HandlerTimeout time.Duration // Time allowed to deliver one event to all of its handlers
+	MaxAttempts    int           // Deliveries before an event is given up
+	RetryBackoff   time.Duration // Wait after the first failed delivery, doubled for every further failure
+	MaxBackoff     time.Duration
+	Retention      time.Duration // How long delivered events are kept
+}
+
+// NewOutboxConfig loads the OUTBOX_* settings
+func NewOutboxConfig() (*OutboxConfig, error) {
+	pollInterval, err := getDuration("OUTBOX_POLL_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := getInt("OUTBOX_BATCH_SIZE", 20, 1)
+	if err != nil {
+		return nil, err
+	}
+	handlerTimeout, err := getDuration("OUTBOX_HANDLER_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts, err := getInt("OUTBOX_MAX_ATTEMPTS", 20, 1)
+	if err != nil {
+		return nil, err
+	}
+	retryBackoff, err := getDuration("OUTBOX_RETRY_BACKOFF", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxBackoff, err := getDuration("OUTBOX_MAX_BACKOFF", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	retentionDays, err := getInt("OUTBOX_RETENTION_DAYS", 7, 1)
+	if err != nil {
+		return nil, err
+	}
+	if pollInterval <= 0 || handlerTimeout <= 0 {
+		return nil, errors.New("OUTBOX_POLL_INTERVAL and OUTBOX_HANDLER_TIMEOUT must be longer than 0s")
+	}
+
+	return &OutboxConfig{
+		PollInterval:   pollInterval,
+		BatchSize:      batchSize,
+		HandlerTimeout: handlerTimeout,
+		MaxAttempts:    maxAttempts,
+		RetryBackoff:   retryBackoff,
+		MaxBackoff:     maxBackoff,
+		Retention:      time.Duration(retentionDays) * 24 * time.Hour,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limit stores
+const (
+	RateLimitStoreMemory = "memory"
+)
+
+// RateLimit is a token bucket: Requests tokens are refilled evenly over Period, and up to
+// Burst tokens can be saved up
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// String formats the limit as accepted by the RATE_LIMIT_* variables, e.g. "10/1m0s"
+func (l RateLimit) String() string {
+	if l.Burst != l.Requests {
+		return fmt.Sprintf("%d/%s/%d", l.Requests, l.Period, l.Burst)
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	Store   string // Backend holding the token buckets
+
+	IP                RateLimit // Every request except health probes, metrics and the JWKS, per client IP
+	User              RateLimit // Authenticated requests, per user
+	Auth              RateLimit // Sign-in, registration and email sending endpoints, per client IP
+	TaskCompletion    RateLimit // Completing daily tasks, per user
+	AchievementUnlock RateLimit // Unlocking and refunding achievements, per user
+}
+
+// NewRateLimitConfig loads rate limits from the environment. Limits are written as
+// "<requests>/<period>" with an optional "/<burst>", e.g. RATE_LIMIT_AUTH=10/1m/20.
+func NewRateLimitConfig() (*RateLimitConfig, error) {
+	enabled, err := getBool("RATE_LIMIT_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &RateLimitConfig{
+		Enabled:           enabled,
+		Store:             getEnv("RATE_LIMIT_STORE", RateLimitStoreMemory),
+		IP:                RateLimit{Requests: 300, Period: time.Minute, Burst: 300},
+		User:              RateLimit{Requests: 120, Period: time.Minute, Burst: 120},
+		Auth:              RateLimit{Requests: 10, Period: time.Minute, Burst: 20},
+		TaskCompletion:    RateLimit{Requests: 20, Period: time.Minute, Burst: 20},
+		AchievementUnlock: RateLimit{Requests: 10, Period: time.Minute, Burst: 10},
+	}
+
+	limits := map[string]*RateLimit{
+		"RATE_LIMIT_IP":                 &cfg.IP,
+		"RATE_LIMIT_USER":               &cfg.User,
+		"RATE_LIMIT_AUTH":               &cfg.Auth,
+		"RATE_LIMIT_TASK_COMPLETION":    &cfg.TaskCompletion,
+		"RATE_LIMIT_ACHIEVEMENT_UNLOCK": &cfg.AchievementUnlock,
+	}
+	for name, limit := range limits {
+		if v := getEnv(name, ""); v != "" {
+			parsed, err := parseRateLimit(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*limit = parsed
+		}
+	}
+
+	switch cfg.Store {
+	case RateLimitStoreMemory:
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", cfg.Store)
+	}
+
+	return cfg, nil
+}
+
+// parseRateLimit parses "<requests>/<period>[/<burst>]"
+func parseRateLimit(value string) (RateLimit, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return RateLimit{}, fmt.Errorf("%q is not <requests>/<period>[/<burst>]", value)
+	}
+
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid request count %q", parts[0])
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid period %q", parts[1])
+	}
+	burst := requests
+	if len(parts) == 3 {
+		burst, err = strconv.Atoi(parts[2])
+		if err != nil || burst <= 0 {
+			return RateLimit{}, fmt.Errorf("invalid burst %q", parts[2])
+		}
+	}
+
+	return RateLimit{Requests: requests, Period: period, Burst: burst}, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+type ServerConfig struct {
+	Port              int
+	ReadHeaderTimeout time.Duration // Time allowed to read request headers
+	ReadTimeout       time.Duration // Time allowed to read the whole request, including uploads
+	WriteTimeout      time.Duration // Time allowed from the end of the request headers to the end of the response
+	IdleTimeout       time.Duration // How long idle keep-alive connections are kept open
+	DrainDelay        time.Duration // How long /readyz fails before shutdown starts, so load balancers stop sending traffic
+	ShutdownTimeout   time.Duration // How long in-flight requests may take to finish during shutdown
+	TrustedProxies    []string      // IPs and CIDRs of reverse proxies whose X-Forwarded-For is believed
+}
+
+// NewServerConfig loads PORT, the SERVER_* durations such as "30s" and SERVER_TRUSTED_PROXIES
+// from the environment
+func NewServerConfig() (*ServerConfig, error) {
+	port, err := getInt("PORT", 8080, 1)
+	if err != nil {
+		return nil, err
+	}
+	if port > 65535 {
+		return nil, fmt.Errorf("invalid PORT %d", port)
+	}
+
+	cfg := &ServerConfig{
+		Port:              port,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      45 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		DrainDelay:        5 * time.Second,
+		ShutdownTimeout:   30 * time.Second,
+	}
+
+	durations := map[string]*time.Duration{
+		"SERVER_READ_HEADER_TIMEOUT": &cfg.ReadHeaderTimeout,
+		"SERVER_READ_TIMEOUT":        &cfg.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":       &cfg.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":        &cfg.IdleTimeout,
+		"SERVER_DRAIN_DELAY":         &cfg.DrainDelay,
+		"SERVER_SHUTDOWN_TIMEOUT":    &cfg.ShutdownTimeout,
+	}
+	for name, duration := range durations {
+		parsed, err := getDuration(name, *duration)
+		if err != nil {
+			return nil, err
+		}
+		*duration = parsed
+	}
+
+	// Without trusted proxies the client IP is the connection's address, so clients cannot
+	// pick their rate limit bucket through X-Forwarded-For
+	for _, proxy := range splitList(getEnv("SERVER_TRUSTED_PROXIES", "")) {
+		if strings.Contains(proxy, "/") {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return nil, fmt.Errorf("invalid SERVER_TRUSTED_PROXIES entry %q", proxy)
+			}
+		} else if net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("invalid SERVER_TRUSTED_PROXIES entry %q", proxy)
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+	}
+
+	return cfg, nil
+}
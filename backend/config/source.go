@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
+)
+
+// source holds the settings read from the config file and -set flags. Lookups prefer flags,
+// then the environment, then the file.
+type source struct {
+	file  map[string]string
+	flags map[string]string
+}
+
+var settings = &source{}
+
+// useSource replaces the settings read by the loaders in this package
+func useSource(file, flags map[string]string) {
+	settings = &source{file: file, flags: flags}
+}
+
+// readConfigFile reads KEY=VALUE settings in the same format as a .env file
+func readConfigFile(path string) (map[string]string, error) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return values, nil
+}
+
+// lookupSetting returns the value of a setting from the highest precedence source that has it
+func lookupSetting(key string) (string, bool) {
+	if value, ok := settings.flags[key]; ok {
+		return value, true
+	}
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	value, ok := settings.file[key]
+	return value, ok
+}
+
+// getEnv gets a setting with fallback for missing or empty values
+func getEnv(key, defaultValue string) string {
+	if value, ok := lookupSetting(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getInt parses an integer setting that must be at least min
+func getInt(key string, defaultValue, min int) (int, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(v)
+	if err != nil || value < min {
+		return 0, fmt.Errorf("invalid %s %q: must be a whole number of at least %d", key, v, min)
+	}
+	return value, nil
+}
+
+// getDuration parses a non-negative duration setting such as "30s"
+func getDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return defaultValue, nil
+	}
+	value, err := time.ParseDuration(v)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a duration such as 30s", key, v)
+	}
+	return value, nil
+}
+
+// getBool parses a true/false setting
+func getBool(key string, defaultValue bool) (bool, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.ParseBool(strings.ToLower(v))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: must be true or false", key, v)
+	}
+	return value, nil
+}
+
+// getCron reads a standard five-field cron expression such as "0 * * * *"
+func getCron(key, defaultValue string) (string, error) {
+	v := getEnv(key, defaultValue)
+	if _, err := cron.ParseStandard(v); err != nil {
+		return "", fmt.Errorf("invalid %s %q: must be a cron expression such as \"0 * * * *\"", key, v)
+	}
+	return v, nil
+}
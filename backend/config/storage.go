@@ -0,0 +1,12 @@
+package config
+
+type StorageConfig struct {
+	EvidenceDir string // Directory uploaded task evidence photos are stored in
+}
+
+// NewStorageConfig loads EVIDENCE_UPLOAD_DIR
+func NewStorageConfig() *StorageConfig {
+	return &StorageConfig{
+		EvidenceDir: getEnv("EVIDENCE_UPLOAD_DIR", "uploads/evidence"),
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"time"
+)
+
+// RequestTimeoutConfig holds the deadline given to requests in each route group. A zero
+// timeout leaves the group's requests without a deadline.
+type RequestTimeoutConfig struct {
+	Auth   time.Duration // Sign-in routes, which wait on identity providers and mail servers
+	API    time.Duration // Authenticated and personal access token routes
+	Public time.Duration // Unauthenticated read-only routes
+	Admin  time.Duration // Administration routes
+}
+
+// NewRequestTimeoutConfig loads REQUEST_TIMEOUT_* durations such as "10s" from the environment
+func NewRequestTimeoutConfig() (*RequestTimeoutConfig, error) {
+	cfg := &RequestTimeoutConfig{
+		Auth:   15 * time.Second,
+		API:    10 * time.Second,
+		Public: 5 * time.Second,
+		Admin:  30 * time.Second,
+	}
+
+	timeouts := map[string]*time.Duration{
+		"REQUEST_TIMEOUT_AUTH":   &cfg.Auth,
+		"REQUEST_TIMEOUT_API":    &cfg.API,
+		"REQUEST_TIMEOUT_PUBLIC": &cfg.Public,
+		"REQUEST_TIMEOUT_ADMIN":  &cfg.Admin,
+	}
+	for name, timeout := range timeouts {
+		parsed, err := getDuration(name, *timeout)
+		if err != nil {
+			return nil, err
+		}
+		*timeout = parsed
+	}
+
+	return cfg, nil
+}
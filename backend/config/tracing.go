@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	"fithero-backend/tracing"
+)
+
+type TracingConfig struct {
+	Exporter    string  // none, otlp or stdout
+	ServiceName string  // Reported as service.name on every span
+	SampleRatio float64 // Fraction of new traces recorded; requests continuing a caller's trace follow its decision
+}
+
+// NewTracingConfig loads TRACING_EXPORTER, TRACING_SERVICE_NAME and TRACING_SAMPLE_RATIO
+func NewTracingConfig() (*TracingConfig, error) {
+	exporter := getEnv("TRACING_EXPORTER", tracing.ExporterNone)
+	switch exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		return nil, fmt.Errorf("unknown TRACING_EXPORTER %q", exporter)
+	}
+
+	sampleRatio, err := strconv.ParseFloat(getEnv("TRACING_SAMPLE_RATIO", "1"), 64)
+	if err != nil || sampleRatio < 0 || sampleRatio > 1 {
+		return nil, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	return &TracingConfig{
+		Exporter:    exporter,
+		ServiceName: getEnv("TRACING_SERVICE_NAME", "fithero-backend"),
+		SampleRatio: sampleRatio,
+	}, nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+type UsernameConfig struct {
+	ChangeCooldown time.Duration // Minimum time between two username changes
+	Blocklist      []string      // Additional words usernames may not contain
+}
+
+// NewUsernameConfig loads USERNAME_CHANGE_COOLDOWN_DAYS and USERNAME_BLOCKLIST
+func NewUsernameConfig() (*UsernameConfig, error) {
+	cooldownDays, err := getInt("USERNAME_CHANGE_COOLDOWN_DAYS", 30, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocklist []string
+	for _, word := range splitList(getEnv("USERNAME_BLOCKLIST", "")) {
+		blocklist = append(blocklist, strings.ToLower(word))
+	}
+
+	return &UsernameConfig{
+		ChangeCooldown: time.Duration(cooldownDays) * 24 * time.Hour,
+		Blocklist:      blocklist,
+	}, nil
+}
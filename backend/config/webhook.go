@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+type WebhookConfig struct {
+	Timeout              time.Duration // Time allowed for one delivery request
+	MaxAttempts          int           // Attempts before a delivery is recorded as failed
+	RetryBackoff         time.Duration // Wait after the first failed attempt, doubled for every further failure
+	MaxBackoff           time.Duration
+	DisableAfterFailures int           // Failed attempts in a row after which a subscription is disabled
+	PollInterval         time.Duration // How often the worker looks for due deliveries
+	BatchSize            int           // Deliveries claimed per poll
+	AllowPrivateNetworks bool          // Allow http:// URLs and loopback or private addresses, e.g. for local receivers
+	Retention            time.Duration // How long finished deliveries are kept in the delivery log
+}
+
+// NewWebhookConfig loads the WEBHOOK_* settings
+func NewWebhookConfig() (*WebhookConfig, error) {
+	timeout, err := getDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts, err := getInt("WEBHOOK_MAX_ATTEMPTS", 8, 1)
+	if err != nil {
+		return nil, err
+	}
+	retryBackoff, err := getDuration("WEBHOOK_RETRY_BACKOFF", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxBackoff, err := getDuration("WEBHOOK_MAX_BACKOFF", 6*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	disableAfter, err := getInt("WEBHOOK_DISABLE_AFTER_FAILURES", 20, 1)
+	if err != nil {
+		return nil, err
+	}
+	pollInterval, err := getDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := getInt("WEBHOOK_BATCH_SIZE", 20, 1)
+	if err != nil {
+		return nil, err
+	}
+	allowPrivate, err := getBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
+	if err != nil {
+		return nil, err
+	}
+	retentionDays, err := getInt("WEBHOOK_DELIVERY_RETENTION_DAYS", 30, 1)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 || pollInterval <= 0 {
+		return nil, errors.New("WEBHOOK_TIMEOUT and WEBHOOK_POLL_INTERVAL must be longer than 0s")
+	}
+
+	return &WebhookConfig{
+		Timeout:              timeout,
+		MaxAttempts:          maxAttempts,
+		RetryBackoff:         retryBackoff,
+		MaxBackoff:           maxBackoff,
+		DisableAfterFailures: disableAfter,
+		PollInterval:         pollInterval,
+		BatchSize:            batchSize,
+		AllowPrivateNetworks: allowPrivate,
+		Retention:            time.Duration(retentionDays) * 24 * time.Hour,
+	}, nil
+}
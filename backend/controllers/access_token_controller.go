@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"fithero-backend/middleware"
+	"fithero-backend/models"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type AccessTokenController struct {
+	accessTokenService *services.AccessTokenService
+	validator          *validator.Validate
+}
+
+func NewAccessTokenController(accessTokenService *services.AccessTokenService) *AccessTokenController {
+	return &AccessTokenController{
+		accessTokenService: accessTokenService,
+		validator:          validator.New(),
+	}
+}
+
+// ListTokens handles GET /api/auth/tokens
+func (atc *AccessTokenController) ListTokens(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	tokens, err := atc.accessTokenService.ListTokens(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve access tokens",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+		"scopes": models.AccessTokenScopes,
+	})
+}
+
+// CreateToken handles POST /api/auth/tokens
+func (atc *AccessTokenController) CreateToken(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := atc.validator.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := atc.accessTokenService.CreateToken(c.Request.Context(), userID, &req)
+	if err != nil {
+		if err.Error() == "too many access tokens" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Access token limit reached, revoke an unused token first"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// RevokeToken handles DELETE /api/auth/tokens/:id
+func (atc *AccessTokenController) RevokeToken(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid access token ID",
+		})
+		return
+	}
+
+	if err := atc.accessTokenService.RevokeToken(c.Request.Context(), userID, uint(tokenID)); err != nil {
+		if err.Error() == "access token not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Access token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access token revoked successfully",
+	})
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"fithero-backend/models"
 	"fithero-backend/services"
 	"fithero-backend/middleware"
 	"github.com/gin-gonic/gin"
@@ -24,7 +26,7 @@ func NewAchievementController(achievementService *services.AchievementService) *
 
 // GetAllAchievements handles GET /api/achievements and GET /api/public/achievements
 func (ac *AchievementController) GetAllAchievements(c *gin.Context) {
-	achievements, err := ac.achievementService.GetAllAchievements()
+	achievements, err := ac.achievementService.GetAllAchievements(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve achievements"})
 		return
@@ -33,6 +35,27 @@ func (ac *AchievementController) GetAllAchievements(c *gin.Context) {
 	c.JSON(http.StatusOK, achievements)
 }
 
+// GetAchievementsForUser handles GET /api/achievements with locked/available/owned status for the current user
+func (ac *AchievementController) GetAchievementsForUser(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	achievements, err := ac.achievementService.GetAchievementsForUser(c.Request.Context(), userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve achievements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, achievements)
+}
+
 // GetUserAchievements handles GET /api/achievements/user (for current user)
 func (ac *AchievementController) GetUserAchievements(c *gin.Context) {
 	// Get current user ID from middleware
@@ -42,7 +65,7 @@ func (ac *AchievementController) GetUserAchievements(c *gin.Context) {
 		return
 	}
 
-	userAchievements, err := ac.achievementService.GetUserAchievements(userID)
+	userAchievements, err := ac.achievementService.GetUserAchievements(c.Request.Context(), userID)
 	if err != nil {
 		if err.Error() == "user not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -72,8 +95,13 @@ func (ac *AchievementController) UnlockAchievement(c *gin.Context) {
 		return
 	}
 
-	userAchievement, err := ac.achievementService.UnlockAchievement(userID, uint(achievementID))
+	userAchievement, err := ac.achievementService.UnlockAchievement(c.Request.Context(), userID, uint(achievementID))
 	if err != nil {
+		var requirementErr *services.RequirementError
+		if errors.As(err, &requirementErr) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Achievement locked", "reason": requirementErr.Reason})
+			return
+		}
 		switch err.Error() {
 		case "user not found":
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -95,4 +123,203 @@ func (ac *AchievementController) UnlockAchievement(c *gin.Context) {
 		"message": "Achievement unlocked successfully",
 		"user_achievement": userAchievement,
 	})
-} 
\ No newline at end of file
+}
+
+// GetLoadout handles GET /api/profile/loadout
+func (ac *AchievementController) GetLoadout(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	loadout, err := ac.achievementService.GetLoadout(c.Request.Context(), userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve loadout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"loadout": loadout})
+}
+
+// UpdateLoadout handles PUT /api/profile/loadout
+func (ac *AchievementController) UpdateLoadout(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req models.UpdateLoadoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	loadout, err := ac.achievementService.UpdateLoadout(c.Request.Context(), userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case "achievement not owned":
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only equip achievements you own"})
+		case "too many badges in loadout":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Too many badges", "max_badges": models.MaxShowcaseBadges})
+		case "duplicate badge in loadout", "achievement cannot be equipped in this slot":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update loadout"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Loadout updated successfully",
+		"loadout": loadout,
+	})
+}
+
+// RefundAchievement handles POST /api/achievements/:id/refund
+func (ac *AchievementController) RefundAchievement(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	achievementID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid achievement ID"})
+		return
+	}
+
+	var req models.RefundAchievementRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if err := ac.validator.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	entry, err := ac.achievementService.RefundAchievement(c.Request.Context(), userID, uint(achievementID), req.Reason)
+	if err != nil {
+		switch err.Error() {
+		case "achievement not owned":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Achievement not owned"})
+		case "achievement was not purchased":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only purchased achievements can be refunded"})
+		case "refund period has expired":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Refund period has expired"})
+		case "achievement is required by other owned achievements":
+			c.JSON(http.StatusConflict, gin.H{"error": "Refund the achievements that require this one first"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refund achievement"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Achievement refunded successfully",
+		"points_refunded": entry.PointsDelta,
+		"audit":           entry,
+	})
+}
+
+// RevokeAchievement handles POST /api/admin/users/:id/achievements/:achievement_id/revoke
+func (ac *AchievementController) RevokeAchievement(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	achievementID, err := strconv.ParseUint(c.Param("achievement_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid achievement ID"})
+		return
+	}
+
+	var req models.RevokeAchievementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := ac.validator.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	entries, err := ac.achievementService.RevokeAchievement(c.Request.Context(), actorID, uint(userID), uint(achievementID), req.Reason, req.Refund)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case "achievement not owned":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User does not own this achievement"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke achievement"})
+		}
+		return
+	}
+
+	// The achievements that required the revoked one were revoked with it
+	pointsRefunded := 0
+	for _, entry := range entries {
+		pointsRefunded += entry.PointsDelta
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Achievement revoked successfully",
+		"points_refunded": pointsRefunded,
+		"audit":           entries[len(entries)-1],
+		"also_revoked":    entries[:len(entries)-1],
+	})
+}
+
+// GetAuditLog handles GET /api/admin/achievements/audit
+func (ac *AchievementController) GetAuditLog(c *gin.Context) {
+	var userID uint64
+	if userIDParam := c.Query("user_id"); userIDParam != "" {
+		var err error
+		userID, err = strconv.ParseUint(userIDParam, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+	}
+
+	entries, err := ac.achievementService.GetAuditLog(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"fithero-backend/middleware"
+	"fithero-backend/models"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type AntiCheatController struct {
+	antiCheatService *services.AntiCheatService
+	validator        *validator.Validate
+}
+
+// NewAntiCheatController creates a new anti-cheat controller
+func NewAntiCheatController(antiCheatService *services.AntiCheatService) *AntiCheatController {
+	return &AntiCheatController{
+		antiCheatService: antiCheatService,
+		validator:        validator.New(),
+	}
+}
+
+// GetFlags handles GET /api/admin/anticheat/flags
+func (ac *AntiCheatController) GetFlags(c *gin.Context) {
+	status := c.DefaultQuery("status", models.FlagStatusOpen)
+	if status == "all" {
+		status = ""
+	}
+
+	flags, err := ac.antiCheatService.GetFlags(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flags": flags})
+}
+
+// ReviewFlag handles POST /api/admin/anticheat/flags/:id/review
+func (ac *AntiCheatController) ReviewFlag(c *gin.Context) {
+	reviewerID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	flagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flag ID"})
+		return
+	}
+
+	var req models.ReviewFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := ac.validator.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	flag, err := ac.antiCheatService.ReviewFlag(c.Request.Context(), reviewerID, uint(flagID), &req)
+	if err != nil {
+		switch err.Error() {
+		case "flag not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Flag not found"})
+		case "flag already reviewed":
+			c.JSON(http.StatusConflict, gin.H{"error": "Flag already reviewed"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review flag"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Flag reviewed successfully",
+		"flag":    flag,
+	})
+}
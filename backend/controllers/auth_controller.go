@@ -1,74 +1,111 @@
 package controllers
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"fithero-backend/config"
+	"fithero-backend/logging"
+	"fithero-backend/models"
 	"fithero-backend/services"
 	"fithero-backend/middleware"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
+// refreshCookiePath limits the refresh token cookie to the auth endpoints that use it
+const refreshCookiePath = "/api/auth"
+
+// oauthFlowCookie holds the state of a login in progress between redirect and callback
+const oauthFlowCookie = "oauth_flow"
+
 type AuthController struct {
-	authService *services.AuthService
-	authConfig  *config.AuthConfig
+	authService      *services.AuthService
+	localAuthService *services.LocalAuthService
+	authConfig       *config.AuthConfig
+	validator        *validator.Validate
 }
 
-func NewAuthController(authService *services.AuthService, authConfig *config.AuthConfig) *AuthController {
+func NewAuthController(authService *services.AuthService, localAuthService *services.LocalAuthService, authConfig *config.AuthConfig) *AuthController {
 	return &AuthController{
-		authService: authService,
-		authConfig:  authConfig,
+		authService:      authService,
+		localAuthService: localAuthService,
+		authConfig:       authConfig,
+		validator:        validator.New(),
 	}
 }
 
-// GoogleLogin initiates Google OAuth flow
-func (ac *AuthController) GoogleLogin(c *gin.Context) {
-	// Generate state for CSRF protection
-	state, err := generateRandomState()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate state",
+// ListProviders handles GET /api/auth/providers
+func (ac *AuthController) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": ac.authService.ListProviders(),
+	})
+}
+
+// Login initiates the OIDC flow for the provider in the :provider route parameter.
+// Signed-in users can pass ?link=true to attach the provider account to their profile.
+func (ac *AuthController) Login(c *gin.Context) {
+	link := c.Query("link") == "true"
+	if _, authenticated := middleware.GetCurrentUserID(c); link && !authenticated {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required to link an account",
 		})
 		return
 	}
 
-	// Store state in session/cookie for verification
-	c.SetCookie(
-		"oauth_state",
-		state,
-		300, // 5 minutes
-		"/",
-		ac.authConfig.CookieDomain,
-		ac.authConfig.CookieSecure,
-		ac.authConfig.CookieHttpOnly,
-	)
+	authURL, flow, err := ac.authService.StartLogin(c.Request.Context(), c.Param("provider"), link, c.Query("return_to"))
+	if err != nil {
+		switch err.Error() {
+		case "unknown provider":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+			return
+		case "invalid return_to":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "return_to is not an allowed redirect URL"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "Failed to start login",
+		})
+		return
+	}
 
-	// Get Google OAuth URL
-	url := ac.authService.GetGoogleAuthURL(state)
+	// Keep state, PKCE verifier and nonce for verification in the callback
+	if err := ac.setOAuthFlowCookie(c, flow); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start login",
+		})
+		return
+	}
 
 	// Return the OAuth URL as JSON for frontend to handle redirect
 	c.JSON(http.StatusOK, gin.H{
-		"auth_url": url,
+		"auth_url": authURL,
 	})
 }
 
-// GoogleCallback handles Google OAuth callback
-func (ac *AuthController) GoogleCallback(c *gin.Context) {
-	// Verify state parameter
-	state := c.Query("state")
-	savedState, err := c.Cookie("oauth_state")
-	if err != nil || state != savedState {
+// Callback handles the OIDC provider callback
+func (ac *AuthController) Callback(c *gin.Context) {
+	// Verify state parameter against the flow started by this browser
+	flow, err := ac.oauthFlowCookie(c)
+	if err != nil || flow.Provider != c.Param("provider") || c.Query("state") != flow.State {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid state parameter",
 		})
 		return
 	}
 
-	// Clear the state cookie
-	c.SetCookie("oauth_state", "", -1, "/", ac.authConfig.CookieDomain, ac.authConfig.CookieSecure, ac.authConfig.CookieHttpOnly)
+	// Clear the flow cookie, it is single use
+	c.SetCookie(oauthFlowCookie, "", -1, refreshCookiePath, ac.authConfig.CookieDomain, ac.authConfig.CookieSecure, true)
+
+	if errParam := c.Query("error"); errParam != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Authentication was cancelled or denied",
+			"message": errParam,
+		})
+		return
+	}
 
 	// Get authorization code
 	code := c.Query("code")
@@ -79,43 +116,172 @@ func (ac *AuthController) GoogleCallback(c *gin.Context) {
 		return
 	}
 
+	if flow.Link {
+		ac.linkIdentity(c, flow, code)
+		return
+	}
+
 	// Handle the callback
-	authResponse, err := ac.authService.HandleGoogleCallback(code)
+	authResponse, err := ac.authService.HandleCallback(c.Request.Context(), flow, code, clientInfo(c))
 	if err != nil {
+		switch err.Error() {
+		case "user account is disabled":
+			c.JSON(http.StatusForbidden, gin.H{"error": "User account is disabled"})
+			return
+		case "email not verified by provider":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "An account with this email already exists. Sign in to it first, then link this provider from your profile.",
+			})
+			return
+		case "account email not verified":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "An account with this email exists but its address has not been verified. Verify it from the sign-up email, then link this provider from your profile.",
+			})
+			return
+		}
+		// Provider and database errors can include internal details, so they are only logged
+		logging.FromContext(c.Request.Context()).Error("OIDC callback failed", "provider", flow.Provider, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Authentication failed",
-			"message": err.Error(),
 		})
 		return
 	}
 
-	// Set secure HTTP-only cookie with JWT token
-	ac.setAuthCookie(c, authResponse.Token)
+	// Set secure HTTP-only cookies with the access and refresh tokens
+	ac.setAuthCookies(c, authResponse)
 
 	// Redirect to frontend auth callback to complete the flow
-	c.Redirect(http.StatusTemporaryRedirect, "http://localhost:3000/auth-callback")
+	ac.postLoginRedirect(c, ac.authConfig.PostLoginRedirectURL, flow.ReturnTo)
+}
+
+// ListIdentities handles GET /api/auth/identities
+func (ac *AuthController) ListIdentities(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	identities, err := ac.authService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve linked accounts",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identities": identities,
+	})
+}
+
+// UnlinkIdentity handles DELETE /api/auth/identities/:id
+func (ac *AuthController) UnlinkIdentity(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	identityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid identity ID",
+		})
+		return
+	}
+
+	if err := ac.authService.UnlinkIdentity(c.Request.Context(), userID, uint(identityID)); err != nil {
+		switch err.Error() {
+		case "identity not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Linked account not found"})
+		case "cannot unlink last identity":
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot unlink your only sign-in method"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink account"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account unlinked successfully",
+	})
 }
 
-// Logout clears the authentication cookie
+// Logout revokes the current session and clears the authentication cookies
 func (ac *AuthController) Logout(c *gin.Context) {
-	// Clear the auth cookie
-	c.SetCookie(
-		"auth_token",
-		"",
-		-1, // Expire immediately
-		"/",
-		ac.authConfig.CookieDomain,
-		ac.authConfig.CookieSecure,
-		ac.authConfig.CookieHttpOnly,
-	)
+	refreshToken, _ := c.Cookie("refresh_token")
+	sessionID, _ := middleware.GetCurrentSessionID(c)
+	if err := ac.authService.Logout(c.Request.Context(), refreshToken, sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke session",
+		})
+		return
+	}
+
+	ac.clearAuthCookies(c)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logout successful",
 	})
 }
 
-// RefreshToken generates a new JWT token for the authenticated user
+// RefreshToken exchanges a refresh token for a new access token, rotating the refresh token.
+// Browsers send the refresh token cookie, other clients send it in the JSON body.
 func (ac *AuthController) RefreshToken(c *gin.Context) {
+	refreshToken, _ := c.Cookie("refresh_token")
+	fromBody := false
+	if refreshToken == "" && c.Request.ContentLength > 0 {
+		var req models.RefreshTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+		refreshToken = req.RefreshToken
+		fromBody = true
+	}
+
+	authResponse, err := ac.authService.RefreshSession(c.Request.Context(), refreshToken)
+	if err != nil {
+		ac.clearAuthCookies(c)
+		switch err.Error() {
+		case "invalid refresh token", "session revoked", "user account is disabled":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		case "refresh token reuse detected":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, session revoked"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		}
+		return
+	}
+
+	if fromBody {
+		c.JSON(http.StatusOK, gin.H{
+			"message":       "Token refreshed successfully",
+			"token":         authResponse.Token,
+			"refresh_token": authResponse.RefreshToken,
+			"expires_at":    authResponse.ExpiresAt,
+		})
+		return
+	}
+
+	ac.setAuthCookies(c, authResponse)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Token refreshed successfully",
+		"expires_at": authResponse.ExpiresAt,
+	})
+}
+
+// ListSessions handles GET /api/auth/sessions
+func (ac *AuthController) ListSessions(c *gin.Context) {
 	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -123,20 +289,85 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 		})
 		return
 	}
+	sessionID, _ := middleware.GetCurrentSessionID(c)
 
-	newToken, err := ac.authService.RefreshToken(userID)
+	sessions, err := ac.authService.ListSessions(c.Request.Context(), userID, sessionID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to refresh token",
+			"error": "Failed to retrieve sessions",
 		})
 		return
 	}
 
-	// Set the new token in cookie
-	ac.setAuthCookie(c, newToken)
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession handles DELETE /api/auth/sessions/:id
+func (ac *AuthController) RevokeSession(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid session ID",
+		})
+		return
+	}
+
+	if err := ac.authService.RevokeSession(c.Request.Context(), userID, uint(sessionID)); err != nil {
+		if err.Error() == "session not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	if currentSessionID, _ := middleware.GetCurrentSessionID(c); currentSessionID == uint(sessionID) {
+		ac.clearAuthCookies(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeAllSessions handles DELETE /api/auth/sessions, use ?keep_current=true to stay signed in
+func (ac *AuthController) RevokeAllSessions(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var keepSessionID uint
+	if c.Query("keep_current") == "true" {
+		keepSessionID, _ = middleware.GetCurrentSessionID(c)
+	}
+
+	if err := ac.authService.RevokeAllSessions(c.Request.Context(), userID, keepSessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revoke sessions",
+		})
+		return
+	}
+
+	if keepSessionID == 0 {
+		ac.clearAuthCookies(c)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Token refreshed successfully",
+		"message": "Sessions revoked successfully",
 	})
 }
 
@@ -165,42 +396,186 @@ func (ac *AuthController) CheckAuth(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"authenticated": true,
 		"user":          user,
-	})
+	}
+
+	// Browser sessions need a CSRF token for mutating requests
+	if sessionID, exists := middleware.GetCurrentSessionID(c); exists {
+		token, err := ac.csrfToken(c, sessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to issue CSRF token",
+			})
+			return
+		}
+		response["csrf_token"] = token
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// JWKS handles GET /.well-known/jwks.json
+func (ac *AuthController) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, ac.authService.JWKS())
 }
 
 // Private helper methods
 
-func (ac *AuthController) setAuthCookie(c *gin.Context, token string) {
-	sameSite := http.SameSiteLaxMode
+func (ac *AuthController) cookieSameSite() http.SameSite {
 	switch ac.authConfig.CookieSameSite {
 	case "Strict":
-		sameSite = http.SameSiteStrictMode
+		return http.SameSiteStrictMode
 	case "None":
-		sameSite = http.SameSiteNoneMode
+		return http.SameSiteNoneMode
 	default:
-		sameSite = http.SameSiteLaxMode
+		return http.SameSiteLaxMode
 	}
+}
 
-	c.SetSameSite(sameSite)
+func (ac *AuthController) setAuthCookies(c *gin.Context, authResponse *models.AuthResponse) {
+	c.SetSameSite(ac.cookieSameSite())
 	c.SetCookie(
 		"auth_token",
-		token,
-		int(ac.authConfig.JWTExpiration.Seconds()),
+		authResponse.Token,
+		int(ac.authConfig.AccessTokenExpiration.Seconds()),
 		"/",
 		ac.authConfig.CookieDomain,
 		ac.authConfig.CookieSecure,
 		ac.authConfig.CookieHttpOnly,
 	)
+	c.SetCookie(
+		"refresh_token",
+		authResponse.RefreshToken,
+		int(ac.authConfig.RefreshTokenExpiration.Seconds()),
+		refreshCookiePath,
+		ac.authConfig.CookieDomain,
+		ac.authConfig.CookieSecure,
+		true, // Never readable from JavaScript
+	)
 }
 
-func generateRandomState() (string, error) {
-	b := make([]byte, 32)
-	_, err := rand.Read(b)
+func (ac *AuthController) clearAuthCookies(c *gin.Context) {
+	c.SetCookie("auth_token", "", -1, "/", ac.authConfig.CookieDomain, ac.authConfig.CookieSecure, ac.authConfig.CookieHttpOnly)
+	c.SetCookie("refresh_token", "", -1, refreshCookiePath, ac.authConfig.CookieDomain, ac.authConfig.CookieSecure, true)
+	c.SetCookie(middleware.CSRFCookieName, "", -1, "/", ac.authConfig.CookieDomain, ac.authConfig.CookieSecure, false)
+}
+
+// csrfToken returns the session's CSRF token, reusing the cookie when it is still valid so
+// concurrent tabs keep working. A new token is stored in the cookie.
+func (ac *AuthController) csrfToken(c *gin.Context, sessionID uint) (string, error) {
+	if cookie, err := c.Request.Cookie(middleware.CSRFCookieName); err == nil && ac.authService.ValidateCSRFToken(sessionID, cookie.Value) {
+		return cookie.Value, nil
+	}
+
+	token, err := ac.authService.IssueCSRFToken(sessionID)
 	if err != nil {
 		return "", err
 	}
-	return base64.URLEncoding.EncodeToString(b), nil
-} 
\ No newline at end of file
+	c.SetSameSite(ac.cookieSameSite())
+	c.SetCookie(
+		middleware.CSRFCookieName,
+		token,
+		int(ac.authConfig.RefreshTokenExpiration.Seconds()),
+		"/",
+		ac.authConfig.CookieDomain,
+		ac.authConfig.CookieSecure,
+		false, // Double-submitted by the frontend
+	)
+	return token, nil
+}
+
+// linkIdentity finishes a link flow for the signed-in user
+func (ac *AuthController) linkIdentity(c *gin.Context, flow *models.OAuthFlow, code string) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required to link an account",
+		})
+		return
+	}
+
+	if _, err := ac.authService.LinkIdentity(c.Request.Context(), flow, code, userID); err != nil {
+		if err.Error() == "identity already linked to another account" {
+			c.JSON(http.StatusConflict, gin.H{"error": "This account is already linked to another user"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("Failed to link identity", "provider", flow.Provider, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to link account",
+		})
+		return
+	}
+
+	ac.postLoginRedirect(c, ac.authConfig.FrontendURL+"/profile", flow.ReturnTo)
+}
+
+// postLoginRedirect redirects to landingURL. A return_to on the frontend is passed along for the
+// frontend to open once it has loaded the session; other allowed return_to URLs are redirected to directly.
+func (ac *AuthController) postLoginRedirect(c *gin.Context, landingURL, returnTo string) {
+	target, err := url.Parse(landingURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid redirect configuration"})
+		return
+	}
+
+	// The allowed redirect URLs may have changed since the login started
+	if resolved, err := ac.authService.ResolveReturnTo(returnTo); err != nil {
+		returnTo = ""
+	} else {
+		returnTo = resolved
+	}
+
+	if returnTo != "" {
+		returnURL, err := url.Parse(returnTo)
+		frontendURL, frontendErr := url.Parse(ac.authConfig.FrontendURL)
+		if err != nil || frontendErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid redirect"})
+			return
+		}
+		if returnURL.Scheme != frontendURL.Scheme || returnURL.Host != frontendURL.Host {
+			c.Redirect(http.StatusTemporaryRedirect, returnURL.String())
+			return
+		}
+		query := target.Query()
+		query.Set("return_to", returnURL.RequestURI())
+		target.RawQuery = query.Encode()
+	}
+
+	c.Redirect(http.StatusTemporaryRedirect, target.String())
+}
+
+func (ac *AuthController) setOAuthFlowCookie(c *gin.Context, flow *models.OAuthFlow) error {
+	value, err := ac.authService.SealOAuthFlow(flow)
+	if err != nil {
+		return err
+	}
+	c.SetCookie(
+		oauthFlowCookie,
+		value,
+		300, // 5 minutes
+		refreshCookiePath,
+		ac.authConfig.CookieDomain,
+		ac.authConfig.CookieSecure,
+		true, // Holds the PKCE verifier, never readable from JavaScript
+	)
+	return nil
+}
+
+func (ac *AuthController) oauthFlowCookie(c *gin.Context) (*models.OAuthFlow, error) {
+	value, err := c.Cookie(oauthFlowCookie)
+	if err != nil {
+		return nil, err
+	}
+	return ac.authService.OpenOAuthFlow(value)
+}
+
+// clientInfo captures the device details stored with a new session
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
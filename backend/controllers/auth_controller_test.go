@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingProvider is an identity provider whose code exchange always fails
+type failingProvider struct {
+	exchanged bool
+}
+
+func (p *failingProvider) Info() models.IdentityProviderInfo {
+	return models.IdentityProviderInfo{Name: "test", DisplayName: "Test"}
+}
+
+func (p *failingProvider) AuthCodeURL(ctx context.Context, state, codeVerifier, nonce string) (string, error) {
+	return "https://issuer.example.com/authorize?state=" + state, nil
+}
+
+func (p *failingProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*models.OIDCUserInfo, error) {
+	p.exchanged = true
+	return nil, errors.New(`failed to exchange code for token: POST https://issuer.example.com/token: client_secret "s3cret" rejected`)
+}
+
+func newTestAuthController(t *testing.T) (*AuthController, *failingProvider) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	authConfig := &config.AuthConfig{
+		FrontendURL: "http://localhost:3000",
+		CSRFSecret:  []byte(strings.Repeat("k", 32)),
+	}
+	provider := &failingProvider{}
+	providers := services.NewProviderRegistry(nil)
+	providers.Register("test", provider)
+	authService := services.NewAuthService(nil, nil, nil, providers, authConfig, nil, nil)
+	return NewAuthController(authService, nil, authConfig), provider
+}
+
+// flowCookie returns the cookie the login endpoint sets for flow
+func flowCookie(t *testing.T, ac *AuthController, flow *models.OAuthFlow) *http.Cookie {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	if err := ac.setOAuthFlowCookie(c, flow); err != nil {
+		t.Fatalf("setOAuthFlowCookie: %v", err)
+	}
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == oauthFlowCookie {
+			return cookie
+		}
+	}
+	t.Fatal("no flow cookie set")
+	return nil
+}
+
+func callback(ac *AuthController, provider, query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/auth/"+provider+"/callback?"+query, nil)
+	if cookie != nil {
+		c.Request.AddCookie(cookie)
+	}
+	c.Params = gin.Params{{Key: "provider", Value: provider}}
+	ac.Callback(c)
+	return recorder
+}
+
+func TestCallbackRejectsStateMismatch(t *testing.T) {
+	ac, provider := newTestAuthController(t)
+	cookie := flowCookie(t, ac, &models.OAuthFlow{Provider: "test", State: "expected-state", CodeVerifier: "verifier", Nonce: "nonce"})
+
+	// A flow whose state was swapped for the one in a forged callback URL
+	payload, signature, _ := strings.Cut(cookie.Value, ".")
+	forged := flowCookie(t, ac, &models.OAuthFlow{Provider: "test", State: "forged-state", CodeVerifier: "verifier", Nonce: "nonce"})
+	forgedPayload, _, _ := strings.Cut(forged.Value, ".")
+	tampered := &http.Cookie{Name: oauthFlowCookie, Value: forgedPayload + "." + signature}
+	unsigned := &http.Cookie{Name: oauthFlowCookie, Value: payload}
+
+	tests := []struct {
+		name     string
+		provider string
+		query    string
+		cookie   *http.Cookie
+	}{
+		{name: "different state", provider: "test", query: "state=forged-state&code=abc", cookie: cookie},
+		{name: "missing state", provider: "test", query: "code=abc", cookie: cookie},
+		{name: "flow of another provider", provider: "other", query: "state=expected-state&code=abc", cookie: cookie},
+		{name: "no flow cookie", provider: "test", query: "state=expected-state&code=abc"},
+		{name: "tampered flow cookie", provider: "test", query: "state=forged-state&code=abc", cookie: tampered},
+		{name: "unsigned flow cookie", provider: "test", query: "state=expected-state&code=abc", cookie: unsigned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := callback(ac, tt.provider, tt.query, tt.cookie)
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+			}
+			if provider.exchanged {
+				t.Error("the authorization code was exchanged despite the state mismatch")
+			}
+		})
+	}
+}
+
+func TestCallbackHidesInternalErrors(t *testing.T) {
+	ac, provider := newTestAuthController(t)
+	cookie := flowCookie(t, ac, &models.OAuthFlow{Provider: "test", State: "expected-state", CodeVerifier: "verifier", Nonce: "nonce"})
+
+	recorder := callback(ac, "test", "state=expected-state&code=abc", cookie)
+	if !provider.exchanged {
+		t.Fatal("the authorization code was not exchanged")
+	}
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+	if body := recorder.Body.String(); strings.Contains(body, "s3cret") || strings.Contains(body, "issuer.example.com") {
+		t.Errorf("response exposes the internal error: %s", body)
+	}
+}
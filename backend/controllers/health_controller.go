@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"net/http"
+
+	"fithero-backend/models"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	healthService *services.HealthService
+}
+
+// NewHealthController creates a new health controller
+func NewHealthController(healthService *services.HealthService) *HealthController {
+	return &HealthController{
+		healthService: healthService,
+	}
+}
+
+// Livez handles GET /livez. It only reports that the process is serving requests, so a
+// database outage doesn't get every replica restarted.
+func (hc *HealthController) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": models.HealthStatusOK})
+}
+
+// Readyz handles GET /readyz, returning 503 while a required dependency is failing
+func (hc *HealthController) Readyz(c *gin.Context) {
+	report := hc.healthService.Readiness(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status == models.HealthStatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
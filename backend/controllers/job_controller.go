@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobController struct {
+	scheduler *services.Scheduler
+}
+
+// NewJobController creates a new job controller
+func NewJobController(scheduler *services.Scheduler) *JobController {
+	return &JobController{
+		scheduler: scheduler,
+	}
+}
+
+// ListJobs handles GET /api/admin/jobs
+func (jc *JobController) ListJobs(c *gin.Context) {
+	jobs, err := jc.scheduler.Jobs(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// ListRuns handles GET /api/admin/jobs/runs
+func (jc *JobController) ListRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	runs, err := jc.scheduler.ListRuns(c.Request.Context(), c.Query("job"), limit)
+	if err != nil {
+		switch err.Error() {
+		case "job not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve job runs"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
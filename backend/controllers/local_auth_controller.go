@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"net/http"
+
+	"fithero-backend/logging"
+	"fithero-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestMagicLink handles POST /api/auth/email/magic-link
+func (ac *AuthController) RequestMagicLink(c *gin.Context) {
+	var req models.EmailRequest
+	if !ac.bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.localAuthService.SendMagicLink(c.Request.Context(), req.Email); err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to send magic link", "error", err)
+	}
+
+	// Same response whether or not the address has an account
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address can sign in, a sign-in link has been sent",
+	})
+}
+
+// MagicLinkLogin handles POST /api/auth/email/magic-link/verify
+func (ac *AuthController) MagicLinkLogin(c *gin.Context) {
+	var req models.EmailTokenRequest
+	if !ac.bindJSON(c, &req) {
+		return
+	}
+
+	authResponse, err := ac.localAuthService.LoginWithMagicLink(c.Request.Context(), req.Token, clientInfo(c))
+	if err != nil {
+		ac.localLoginError(c, err)
+		return
+	}
+
+	ac.setAuthCookies(c, authResponse)
+
+	c.JSON(http.StatusOK, authResponse)
+}
+
+// Register handles POST /api/auth/register
+func (ac *AuthController) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if !ac.bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.localAuthService.Register(c.Request.Context(), &req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to register",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Check your email to verify your account",
+	})
+}
+
+// PasswordLogin handles POST /api/auth/login
+func (ac *AuthController) PasswordLogin(c *gin.Context) {
+	var req models.PasswordLoginRequest
+	if !ac.bindJSON(c, &req) {
+		return
+	}
+
+	authResponse, err := ac.localAuthService.LoginWithPassword(c.Request.Context(), req.Email, req.Password, clientInfo(c))
+	if err != nil {
+		ac.localLoginError(c, err)
+		return
+	}
+
+	ac.setAuthCookies(c, authResponse)
+
+	c.JSON(http.StatusOK, authResponse)
+}
+
+// VerifyEmail handles POST /api/auth/email/verify
+func (ac *AuthController) VerifyEmail(c *gin.Context) {
+	var req models.EmailTokenRequest
+	if !ac.bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.localAuthService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		if err.Error() == "invalid or expired token" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+	})
+}
+
+// ResendVerification handles POST /api/auth/email/resend-verification
+func (ac *AuthController) ResendVerification(c *gin.Context) {
+	var req models.EmailRequest
+	if !ac.bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.localAuthService.ResendVerification(c.Request.Context(), req.Email); err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to resend verification email", "error", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address has an unverified account, a verification link has been sent",
+	})
+}
+
+// ForgotPassword handles POST /api/auth/password/forgot
+func (ac *AuthController) ForgotPassword(c *gin.Context) {
+	var req models.EmailRequest
+	if !ac.bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.localAuthService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to send password reset email", "error", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address has an account, a password reset link has been sent",
+	})
+}
+
+// ResetPassword handles POST /api/auth/password/reset
+func (ac *AuthController) ResetPassword(c *gin.Context) {
+	var req models.PasswordResetRequest
+	if !ac.bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.localAuthService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		if err.Error() == "invalid or expired token" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset link"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	// Every session was signed out, including this browser's
+	ac.clearAuthCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password reset successfully, please sign in again",
+	})
+}
+
+// Private helper methods
+
+// bindJSON decodes and validates the request body, writing the error response on failure
+func (ac *AuthController) bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return false
+	}
+	if err := ac.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (ac *AuthController) localLoginError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "invalid email or password":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	case "invalid or expired token":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in link"})
+	case "email not verified":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address before signing in"})
+	case "user account is disabled":
+		c.JSON(http.StatusForbidden, gin.H{"error": "User account is disabled"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Authentication failed"})
+	}
+}
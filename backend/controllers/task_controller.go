@@ -1,14 +1,23 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
+	"time"
+	"fithero-backend/models"
 	"fithero-backend/services"
 	"fithero-backend/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
+// maxEvidencePhotoSize limits uploaded evidence photos to 5 MB
+const maxEvidencePhotoSize = 5 << 20
+
 type TaskController struct {
 	taskService *services.TaskService
 	validator   *validator.Validate
@@ -24,7 +33,7 @@ func NewTaskController(taskService *services.TaskService) *TaskController {
 
 // GetAllTasks handles GET /api/public/tasks
 func (tc *TaskController) GetAllTasks(c *gin.Context) {
-	tasks, err := tc.taskService.GetAllTasks()
+	tasks, err := tc.taskService.GetAllTasks(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
 		return
@@ -42,7 +51,7 @@ func (tc *TaskController) GenerateDailyTasks(c *gin.Context) {
 		return
 	}
 
-	dailyTasks, err := tc.taskService.GenerateDailyTasks(userID)
+	dailyTasks, err := tc.taskService.GenerateDailyTasks(c.Request.Context(), userID)
 	if err != nil {
 		switch err.Error() {
 		case "user not found":
@@ -77,24 +86,220 @@ func (tc *TaskController) CompleteTask(c *gin.Context) {
 		return
 	}
 
-	dailyTask, err := tc.taskService.CompleteTask(userID, uint(dailyTaskID))
+	evidence, err := tc.bindEvidence(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid evidence",
+			"details": err.Error(),
+		})
+		return
+	}
+	if closer, ok := evidence.Photo.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	dailyTask, err := tc.taskService.CompleteTask(c.Request.Context(), userID, uint(dailyTaskID), evidence)
 	if err != nil {
 		switch err.Error() {
 		case "daily task not found":
 			c.JSON(http.StatusNotFound, gin.H{"error": "Daily task not found"})
 		case "task already completed":
 			c.JSON(http.StatusConflict, gin.H{"error": "Task already completed"})
+		case "task pending verification":
+			c.JSON(http.StatusConflict, gin.H{"error": "Task is awaiting verification"})
+		case "task expired":
+			c.JSON(http.StatusConflict, gin.H{"error": "Task expired at the end of its day"})
 		case "access denied: you can only complete your own tasks":
 			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: You can only complete your own tasks"})
+		case "note evidence required", "numeric evidence required", "photo evidence required":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Evidence required", "details": err.Error()})
+		case services.ErrUnsupportedPhoto.Error():
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid evidence", "details": err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete task"})
 		}
 		return
 	}
 
+	if dailyTask.VerificationStatus == models.VerificationStatusPending {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":              "Task submitted for verification",
+			"daily_task":           dailyTask,
+			"points_earned":        0,
+			"points_pending":       dailyTask.Points,
+			"pending_verification": true,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Task completed successfully",
 		"daily_task":    dailyTask,
 		"points_earned": dailyTask.Points,
 	})
+}
+
+// GetVerificationQueue handles GET /api/verifications
+func (tc *TaskController) GetVerificationQueue(c *gin.Context) {
+	reviewer, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	dailyTasks, err := tc.taskService.GetVerificationQueue(c.Request.Context(), reviewer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve verification queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tasks": dailyTasks})
+}
+
+// ApproveCompletion handles POST /api/verifications/:id/approve
+func (tc *TaskController) ApproveCompletion(c *gin.Context) {
+	reviewer, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	dailyTaskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	dailyTask, err := tc.taskService.ApproveCompletion(c.Request.Context(), reviewer, uint(dailyTaskID))
+	if err != nil {
+		tc.handleVerificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Task completion approved",
+		"daily_task": dailyTask,
+	})
+}
+
+// RejectCompletion handles POST /api/verifications/:id/reject
+func (tc *TaskController) RejectCompletion(c *gin.Context) {
+	reviewer, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	dailyTaskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.RejectVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := tc.validator.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	dailyTask, err := tc.taskService.RejectCompletion(c.Request.Context(), reviewer, uint(dailyTaskID), req.Reason)
+	if err != nil {
+		tc.handleVerificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Task completion rejected",
+		"daily_task": dailyTask,
+	})
+}
+
+// GetEvidencePhoto handles GET /uploads/evidence/:name
+func (tc *TaskController) GetEvidencePhoto(c *gin.Context) {
+	viewer, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	photo, contentType, err := tc.taskService.GetEvidencePhoto(c.Request.Context(), viewer, c.Request.URL.Path)
+	if err != nil {
+		switch err.Error() {
+		case "evidence not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Evidence not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve evidence"})
+		}
+		return
+	}
+	defer photo.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", "inline; filename=\""+path.Base(c.Request.URL.Path)+"\"")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+	c.Header("Cache-Control", "private, max-age=3600")
+	http.ServeContent(c.Writer, c.Request, "", time.Time{}, photo)
+}
+
+// Private helper methods
+
+// bindEvidence reads completion evidence from a JSON or multipart body; an empty body means no evidence
+func (tc *TaskController) bindEvidence(c *gin.Context) (*models.TaskEvidence, error) {
+	evidence := &models.TaskEvidence{}
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxEvidencePhotoSize+1<<20)
+		if err := c.ShouldBind(evidence); err != nil {
+			return nil, err
+		}
+
+		if fileHeader, err := c.FormFile("photo"); err == nil {
+			if fileHeader.Size > maxEvidencePhotoSize {
+				return nil, errors.New("photo exceeds 5 MB")
+			}
+			if !strings.HasPrefix(fileHeader.Header.Get("Content-Type"), "image/") {
+				return nil, errors.New("photo must be an image")
+			}
+			file, err := fileHeader.Open()
+			if err != nil {
+				return nil, err
+			}
+			evidence.Photo = file
+		}
+	} else if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(evidence); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tc.validator.Struct(evidence); err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+func (tc *TaskController) handleVerificationError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "daily task not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Daily task not found"})
+	case "task is not pending verification":
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is not pending verification"})
+	case "access denied: you cannot verify your own tasks":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: You cannot verify your own tasks"})
+	case "access denied: insufficient permissions to verify this task":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied: Only moderators can verify this task"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update verification"})
+	}
 } 
\ No newline at end of file
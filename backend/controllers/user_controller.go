@@ -1,8 +1,13 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 	"fithero-backend/models"
 	"fithero-backend/services"
 	"fithero-backend/middleware"
@@ -10,16 +15,21 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// maxProfileUpdateSize limits the body of a profile update to 64 KB
+const maxProfileUpdateSize = 64 << 10
+
 type UserController struct {
-	userService *services.UserService
-	validator   *validator.Validate
+	userService      *services.UserService
+	antiCheatService *services.AntiCheatService
+	validator        *validator.Validate
 }
 
 // NewUserController creates a new user controller
-func NewUserController(userService *services.UserService) *UserController {
+func NewUserController(userService *services.UserService, antiCheatService *services.AntiCheatService) *UserController {
 	return &UserController{
-		userService: userService,
-		validator:   validator.New(),
+		userService:      userService,
+		antiCheatService: antiCheatService,
+		validator:        validator.New(),
 	}
 }
 
@@ -42,8 +52,11 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := uc.userService.CreateUser(&req)
+	user, err := uc.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
+		if uc.handleUsernameError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create user",
 			"details": err.Error(),
@@ -84,7 +97,7 @@ func (uc *UserController) GetUserByID(c *gin.Context) {
 		return
 	}
 
-	user, err := uc.userService.GetUserByID(uint(id))
+	user, err := uc.userService.GetUserByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "User not found",
@@ -100,7 +113,7 @@ func (uc *UserController) GetUserByID(c *gin.Context) {
 
 // GetCurrentUserProfile returns the current authenticated user's profile
 func (uc *UserController) GetCurrentUserProfile(c *gin.Context) {
-	user, exists := middleware.GetCurrentUser(c)
+	currentUserID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "Authentication required",
@@ -108,6 +121,16 @@ func (uc *UserController) GetCurrentUserProfile(c *gin.Context) {
 		return
 	}
 
+	// Reload through the service so the response includes the equipped loadout
+	user, err := uc.userService.GetUserByID(c.Request.Context(), currentUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "User not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"user": user,
 	})
@@ -140,8 +163,46 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	var req models.UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxProfileUpdateSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Reject and flag attempts to set game-managed fields such as points
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	var protected []string
+	for _, field := range models.ProtectedProfileFields {
+		if _, present := fields[field]; present {
+			protected = append(protected, field)
+		}
+	}
+	if len(protected) > 0 {
+		uc.antiCheatService.FlagDirectPointEdit(c.Request.Context(), currentUserID, protected)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "These fields cannot be changed through profile updates",
+			"fields": protected,
+		})
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := json.Unmarshal(body, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid request body",
 			"details": err.Error(),
@@ -157,7 +218,10 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	if err := uc.userService.UpdateUser(uint(id), &req); err != nil {
+	if err := uc.userService.UpdateUser(c.Request.Context(), uint(id), &req); err != nil {
+		if uc.handleUsernameError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update user",
 			"details": err.Error(),
@@ -166,7 +230,7 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 	}
 
 	// Return updated user data
-	updatedUser, err := uc.userService.GetUserByID(uint(id))
+	updatedUser, err := uc.userService.GetUserByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "User updated successfully",
@@ -180,6 +244,75 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 	})
 }
 
+// ChangeUsername handles PUT /api/profile/username
+func (uc *UserController) ChangeUsername(c *gin.Context) {
+	currentUserID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Authentication required",
+		})
+		return
+	}
+
+	var req models.ChangeUsernameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := uc.validator.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := uc.userService.ChangeUsername(c.Request.Context(), currentUserID, req.Username)
+	if err != nil {
+		if uc.handleUsernameError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to change username",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Username changed successfully",
+		"user":    user,
+	})
+}
+
+// GetPublicProfile handles GET /api/public/users/:username
+func (uc *UserController) GetPublicProfile(c *gin.Context) {
+	profile, currentUsername, err := uc.userService.GetPublicProfile(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profile"})
+		return
+	}
+
+	// Old usernames redirect to the current one. The redirect is temporary because the
+	// user may take the old username back later.
+	if currentUsername != "" {
+		c.Redirect(http.StatusFound, "/api/public/users/"+url.PathEscape(currentUsername))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profile": profile,
+	})
+}
+
 // DeleteUser soft deletes a user (only the user themselves)
 func (uc *UserController) DeleteUser(c *gin.Context) {
 	idStr := c.Param("id")
@@ -207,7 +340,7 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := uc.userService.DeleteUser(uint(id)); err != nil {
+	if err := uc.userService.DeleteUser(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to delete user",
 			"details": err.Error(),
@@ -231,7 +364,7 @@ func (uc *UserController) GetUserTasks(c *gin.Context) {
 	}
 
 	// Get user's daily tasks
-	tasks, err := uc.userService.GetUserDailyTasks(currentUserID)
+	tasks, err := uc.userService.GetUserDailyTasks(c.Request.Context(), currentUserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get user tasks",
@@ -256,7 +389,7 @@ func (uc *UserController) GetUserAchievements(c *gin.Context) {
 	}
 
 	// Get user's achievements
-	achievements, err := uc.userService.GetUserAchievements(currentUserID)
+	achievements, err := uc.userService.GetUserAchievements(c.Request.Context(), currentUserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get user achievements",
@@ -278,11 +411,40 @@ func (uc *UserController) GetLeaderboard(c *gin.Context) {
 		limit = 10
 	}
 
-	users, err := uc.userService.GetLeaderboard(limit)
+	users, err := uc.userService.GetLeaderboard(c.Request.Context(), limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve leaderboard"})
 		return
 	}
 
 	c.JSON(http.StatusOK, users)
-} 
\ No newline at end of file
+}
+
+// handleUsernameError writes the response for username policy errors and reports whether it did
+func (uc *UserController) handleUsernameError(c *gin.Context, err error) bool {
+	var cooldownErr *services.UsernameCooldownError
+	if errors.As(err, &cooldownErr) {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(cooldownErr.NextChangeAt).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":          "Username was changed recently",
+			"next_change_at": cooldownErr.NextChangeAt,
+		})
+		return true
+	}
+
+	switch err.Error() {
+	case "invalid username":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Usernames must be 3 to 30 letters, digits, dots, dashes or underscores and start with a letter or digit"})
+	case "username not allowed":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This username is not allowed"})
+	case "username taken":
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+	case "email already exists":
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+	case "invalid timezone":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Timezone must be an IANA name such as Europe/Berlin"})
+	default:
+		return false
+	}
+	return true
+}
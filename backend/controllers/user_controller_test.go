@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fithero-backend/middleware"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordingAntiCheatRepo records the anti-cheat flags raised
+type recordingAntiCheatRepo struct {
+	repositories.AntiCheatRepositoryInterface
+	flags []models.AntiCheatFlag
+}
+
+func (r *recordingAntiCheatRepo) CreateFlag(ctx context.Context, flag *models.AntiCheatFlag) error {
+	r.flags = append(r.flags, *flag)
+	return nil
+}
+
+// updateProfile sends a profile update for user 1, authenticated as user 1
+func updateProfile(uc *UserController, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.PUT("/api/users/:id", func(c *gin.Context) {
+		c.Set(middleware.UserIDContextKey, uint(1))
+		uc.UpdateUser(c)
+	})
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/api/users/1", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestUpdateUserFlagsProtectedFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &recordingAntiCheatRepo{}
+	uc := NewUserController(nil, services.NewAntiCheatService(repo, nil, nil))
+
+	recorder := updateProfile(uc, `{"first_name": "Ada", "points": 99999, "level": 50}`)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(recorder.Body.String(), `"fields":["points","level"]`) {
+		t.Errorf("body = %s, want the protected fields listed", recorder.Body.String())
+	}
+	if len(repo.flags) != 1 || repo.flags[0].UserID != 1 || repo.flags[0].Rule != models.AntiCheatRuleDirectPointEdit {
+		t.Errorf("flags = %+v, want one direct point edit flag", repo.flags)
+	}
+}
+
+func TestUpdateUserRejectsOversizedBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &recordingAntiCheatRepo{}
+	uc := NewUserController(nil, services.NewAntiCheatService(repo, nil, nil))
+
+	body := `{"first_name": "` + strings.Repeat("a", maxProfileUpdateSize) + `", "points": 1}`
+	recorder := updateProfile(uc, body)
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusRequestEntityTooLarge)
+	}
+	if len(repo.flags) != 0 {
+		t.Errorf("an oversized body raised flags: %+v", repo.flags)
+	}
+}
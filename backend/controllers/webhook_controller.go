@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"fithero-backend/middleware"
+	"fithero-backend/models"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type WebhookController struct {
+	webhookService *services.WebhookService
+	validator      *validator.Validate
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(webhookService *services.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+		validator:      validator.New(),
+	}
+}
+
+// CreateWebhook handles POST /api/admin/webhooks
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	adminID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := wc.validator.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	response, err := wc.webhookService.CreateWebhook(c.Request.Context(), adminID, &req)
+	if err != nil {
+		wc.handleError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListWebhooks handles GET /api/admin/webhooks
+func (wc *WebhookController) ListWebhooks(c *gin.Context) {
+	webhooks, err := wc.webhookService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+// GetWebhook handles GET /api/admin/webhooks/:id
+func (wc *WebhookController) GetWebhook(c *gin.Context) {
+	webhookID, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := wc.webhookService.GetWebhook(c.Request.Context(), webhookID)
+	if err != nil {
+		wc.handleError(c, err, "Failed to retrieve webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook handles PUT /api/admin/webhooks/:id
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	webhookID, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := wc.validator.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	webhook, err := wc.webhookService.UpdateWebhook(c.Request.Context(), webhookID, &req)
+	if err != nil {
+		wc.handleError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook handles DELETE /api/admin/webhooks/:id
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	webhookID, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := wc.webhookService.DeleteWebhook(c.Request.Context(), webhookID); err != nil {
+		wc.handleError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// ListDeliveries handles GET /api/admin/webhooks/:id/deliveries
+func (wc *WebhookController) ListDeliveries(c *gin.Context) {
+	webhookID, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	deliveries, err := wc.webhookService.ListDeliveries(c.Request.Context(), webhookID, c.Query("status"), limit)
+	if err != nil {
+		wc.handleError(c, err, "Failed to retrieve webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// Redeliver handles POST /api/admin/webhooks/deliveries/:id/redeliver
+func (wc *WebhookController) Redeliver(c *gin.Context) {
+	deliveryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	delivery, err := wc.webhookService.Redeliver(c.Request.Context(), uint(deliveryID))
+	if err != nil {
+		wc.handleError(c, err, "Failed to redeliver webhook")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func parseWebhookID(c *gin.Context) (uint, bool) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return 0, false
+	}
+	return uint(webhookID), true
+}
+
+// handleError maps webhook service errors to responses
+func (wc *WebhookController) handleError(c *gin.Context, err error, fallback string) {
+	switch err.Error() {
+	case "webhook not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+	case "delivery not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+	case "webhook is disabled":
+		c.JSON(http.StatusConflict, gin.H{"error": "Webhook is disabled, enable it before redelivering"})
+	case "invalid webhook url", "webhook url must use https", "webhook url must not point to a private network", "invalid delivery status":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
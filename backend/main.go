@@ -1,61 +1,234 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // User timezones must resolve in images without zoneinfo
 
 	"fithero-backend/config"
 	"fithero-backend/controllers"
+	"fithero-backend/logging"
+	"fithero-backend/metrics"
 	"fithero-backend/middleware"
+	"fithero-backend/models"
 	"fithero-backend/repositories"
 	"fithero-backend/services"
+	"fithero-backend/tracing"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
 	// Load environment variables from .env file
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: Could not load .env file: %v", err)
+	envErr := godotenv.Load()
+
+	// "config print" shows the effective settings with secrets redacted
+	args := os.Args[1:]
+	printConfig := len(args) >= 2 && args[0] == "config" && args[1] == "print"
+	if printConfig {
+		args = args[2:]
+	}
+
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		// Report each invalid setting on its own line
+		for _, line := range strings.Split(err.Error(), "\n") {
+			slog.Error("Invalid configuration", "error", line)
+		}
+		os.Exit(1)
+	}
+	if printConfig {
+		cfg.Print(os.Stdout)
+		return
+	}
+
+	// Structured logging; request handlers log through the request context's logger
+	logger := logging.New(os.Stdout, cfg.Logging.Level, cfg.Logging.Format)
+	slog.SetDefault(logger)
+	if envErr != nil {
+		slog.Warn("Could not load .env file", "error", envErr)
+	}
+	// Cancelled on SIGINT or SIGTERM, which starts the graceful shutdown
+	ctx, stop := signal.NotifyContext(logging.WithContext(context.Background(), logger), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Tracing; spans are only exported when TRACING_EXPORTER is set
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Exporter, cfg.Tracing.ServiceName, cfg.Tracing.SampleRatio)
+	if err != nil {
+		fatal("Failed to initialize tracing", err)
 	}
 
 	// Initialize database
-	db, err := config.InitDB()
+	db, err := config.InitDB(cfg.Database)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("Failed to connect to database", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("Failed to access database pool", err)
+	}
+	if err := metrics.RegisterDBStats(sqlDB); err != nil {
+		fatal("Failed to register database metrics", err)
 	}
 
-	// Initialize authentication configuration
-	authConfig := config.NewAuthConfig()
+	mailer, err := services.NewMailer(cfg.Mail)
+	if err != nil {
+		fatal("Failed to initialize mailer", err)
+	}
+	rateLimitStore, err := services.NewRateLimitStore(cfg.RateLimit)
+	if err != nil {
+		fatal("Failed to initialize rate limit store", err)
+	}
+	tokenSigner, err := services.NewTokenSigner(cfg.Auth)
+	if err != nil {
+		fatal("Failed to initialize token signing", err)
+	}
 
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(db)
 	taskRepo := repositories.NewTaskRepository(db)
 	achievementRepo := repositories.NewAchievementRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	antiCheatRepo := repositories.NewAntiCheatRepository(db)
+	identityRepo := repositories.NewIdentityRepository(db)
+	emailTokenRepo := repositories.NewEmailTokenRepository(db)
+	accessTokenRepo := repositories.NewAccessTokenRepository(db)
+	idempotencyRepo := repositories.NewIdempotencyRepository(db)
+	healthRepo := repositories.NewHealthRepository(db)
+	jobRepo := repositories.NewJobRepository(db)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	webhookRepo := repositories.NewWebhookRepository(db)
+	transactor := repositories.NewTransactor(db)
+
+	// Initialize evidence photo storage
+	evidenceStore, err := services.NewLocalEvidenceStore(cfg.Storage.EvidenceDir, "/uploads/evidence")
+	if err != nil {
+		fatal("Failed to initialize evidence storage", err)
+	}
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, authConfig)
-	userService := services.NewUserService(userRepo, taskRepo, achievementRepo)
-	taskService := services.NewTaskService(taskRepo, userRepo, achievementRepo)
-	achievementService := services.NewAchievementService(achievementRepo, userRepo)
+	identityProviders := services.NewProviderRegistry(cfg.Auth.OIDCProviders)
+	usernamePolicy := services.NewUsernamePolicy(userRepo, cfg.Username)
+	authService := services.NewAuthService(userRepo, sessionRepo, identityRepo, identityProviders, cfg.Auth, tokenSigner, usernamePolicy)
+	accessTokenService := services.NewAccessTokenService(accessTokenRepo, userRepo)
+	localAuthService := services.NewLocalAuthService(authService, userRepo, identityRepo, emailTokenRepo, sessionRepo, mailer, cfg.Auth)
+	userService := services.NewUserService(userRepo, taskRepo, achievementRepo, usernamePolicy, cfg.Username, localAuthService)
+	antiCheatService := services.NewAntiCheatService(antiCheatRepo, taskRepo, cfg.AntiCheat)
+	taskService := services.NewTaskService(taskRepo, userRepo, achievementRepo, outboxRepo, transactor, evidenceStore, antiCheatService)
+	achievementService := services.NewAchievementService(achievementRepo, userRepo, outboxRepo, transactor)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, cfg.Idempotency)
+	idempotencyService.StartCleanup(ctx)
+	healthService := services.NewHealthService(healthRepo, cfg.Health)
+	if checker, ok := mailer.(services.HealthChecker); ok {
+		healthService.AddCheck("mailer", false, checker.HealthCheck)
+	}
+
+	// Background workers and jobs are stopped after the HTTP server has drained, not as soon as
+	// the signal arrives, so events recorded by the last requests are still delivered
+	jobsCtx, stopJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopJobs()
+
+	// Delivers the domain events services record in the outbox; handlers subscribe here
+	outboxDispatcher := services.NewOutboxDispatcher(outboxRepo, cfg.Outbox)
+	webhookService := services.NewWebhookService(webhookRepo, cfg.Webhooks, nil)
+	outboxDispatcher.Subscribe("webhooks", "*", webhookService.HandleEvent)
+	outboxDispatcher.Start(jobsCtx)
+	webhookService.Start(jobsCtx)
+
+	// Background jobs
+	scheduler := services.NewScheduler(jobRepo, cfg.Jobs)
+	jobs := []struct {
+		name     string
+		schedule string
+		run      services.JobFunc
+	}{
+		{services.JobPregenerateDailyTasks, cfg.Jobs.PregenerateSchedule, func(ctx context.Context, now time.Time) error {
+			return taskService.PregenerateDailyTasks(ctx, now, cfg.Jobs.PregenerateActiveDays)
+		}},
+		{services.JobExpireDailyTasks, cfg.Jobs.ExpireSchedule, taskService.ExpireDailyTasks},
+		{services.JobEvaluateStreaks, cfg.Jobs.StreakSchedule, userService.EvaluateStreaks},
+		{services.JobLeaderboardSnapshot, cfg.Jobs.LeaderboardSchedule, func(ctx context.Context, now time.Time) error {
+			return userService.SnapshotLeaderboard(ctx, now, cfg.Jobs.LeaderboardSnapshotSize)
+		}},
+		{services.JobPurgeOutbox, cfg.Jobs.PurgeOutboxSchedule, outboxDispatcher.PurgeProcessed},
+		{services.JobPurgeWebhookDeliveries, cfg.Jobs.PurgeWebhooksSchedule, webhookService.PurgeDeliveries},
+	}
+	for _, job := range jobs {
+		if err := scheduler.Register(job.name, job.schedule, job.run); err != nil {
+			fatal("Failed to schedule job", err)
+		}
+	}
+	if cfg.Jobs.Enabled {
+		scheduler.Start(jobsCtx)
+	}
 
 	// Initialize controllers
-	authController := controllers.NewAuthController(authService, authConfig)
-	userController := controllers.NewUserController(userService)
+	authController := controllers.NewAuthController(authService, localAuthService, cfg.Auth)
+	userController := controllers.NewUserController(userService, antiCheatService)
 	taskController := controllers.NewTaskController(taskService)
 	achievementController := controllers.NewAchievementController(achievementService)
+	antiCheatController := controllers.NewAntiCheatController(antiCheatService)
+	accessTokenController := controllers.NewAccessTokenController(accessTokenService)
+	healthController := controllers.NewHealthController(healthService)
+	jobController := controllers.NewJobController(scheduler)
+	webhookController := controllers.NewWebhookController(webhookService)
+
+	// Session authentication; personal access tokens are rejected
+	requireAuth := middleware.AuthMiddleware(authService, accessTokenService)
+	// Session authentication, or a personal access token holding one of the scopes
+	requireScope := func(scopes ...string) gin.HandlerFunc {
+		return middleware.AuthMiddleware(authService, accessTokenService, scopes...)
+	}
+
+	// Token bucket rate limits; per-user limits must run after authentication
+	rateLimit := func(policy string, limit config.RateLimit, key middleware.RateLimitKeyFunc) gin.HandlerFunc {
+		if !cfg.RateLimit.Enabled {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return middleware.RateLimitMiddleware(rateLimitStore, policy, limit, key)
+	}
+	limitIP := rateLimit("ip", cfg.RateLimit.IP, middleware.RateLimitByIP)
+	limitUser := rateLimit("user", cfg.RateLimit.User, middleware.RateLimitByUser)
+	limitAuth := rateLimit("auth", cfg.RateLimit.Auth, middleware.RateLimitByIP)
+	limitTaskCompletion := rateLimit("task-completion", cfg.RateLimit.TaskCompletion, middleware.RateLimitByUser)
+	limitAchievementUnlock := rateLimit("achievement-unlock", cfg.RateLimit.AchievementUnlock, middleware.RateLimitByUser)
+
+	// Replays the stored response to retried requests carrying an Idempotency-Key. It runs after
+	// the rate limiters of a route, so a rate limited attempt is never replayed.
+	idempotent := middleware.IdempotencyMiddleware(idempotencyService)
 
 	// Initialize Gin router
-	router := gin.Default()
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("Failed to set trusted proxies", err)
+	}
+	router.Use(
+		middleware.TracingMiddleware(cfg.Tracing.ServiceName),
+		middleware.RequestIDMiddleware(logger),
+		middleware.TraceLogMiddleware(),
+		middleware.RequestLoggerMiddleware(),
+		middleware.RecoveryMiddleware(),
+		middleware.MetricsMiddleware(),
+	)
 
 	// Configure CORS
 	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{
-		"http://localhost:3000",
-		"http://127.0.0.1:3000",
-	}
+	corsConfig.AllowOrigins = cfg.CORS.AllowedOrigins
 	corsConfig.AllowCredentials = true
 	corsConfig.AllowHeaders = []string{
 		"Origin",
@@ -63,6 +236,18 @@ func main() {
 		"Accept",
 		"Authorization",
 		"X-Requested-With",
+		"Idempotency-Key",
+		middleware.RequestIDHeader,
+		middleware.CSRFHeaderName,
+	}
+	corsConfig.ExposeHeaders = []string{
+		"RateLimit-Limit",
+		"RateLimit-Remaining",
+		"RateLimit-Reset",
+		"RateLimit-Policy",
+		"Retry-After",
+		"Idempotent-Replayed",
+		middleware.RequestIDHeader,
 	}
 	corsConfig.AllowMethods = []string{
 		"GET",
@@ -72,124 +257,268 @@ func main() {
 		"OPTIONS",
 	}
 	router.Use(cors.New(corsConfig))
+	registerOperationalRoutes(router, healthController, authController)
+	router.Use(limitIP)
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "healthy",
-			"service": "fithero-backend",
-		})
-	})
+	// Uploaded task evidence photos, only shown to the users allowed to see each one
+	router.GET("/uploads/evidence/:name", middleware.TimeoutMiddleware(cfg.Timeouts.API), requireAuth, limitUser, taskController.GetEvidencePhoto)
 
 	// API routes
 	api := router.Group("/api")
 	{
 		// Authentication routes (public)
 		auth := api.Group("/auth")
+		auth.Use(middleware.TimeoutMiddleware(cfg.Timeouts.Auth))
 		{
-			auth.GET("/google", authController.GoogleLogin)
-			auth.GET("/google/callback", authController.GoogleCallback)
-			auth.POST("/logout", authController.Logout)
+			auth.GET("/providers", authController.ListProviders)
+			auth.GET("/oidc/:provider", limitAuth, middleware.OptionalAuthMiddleware(authService), authController.Login)
+			auth.GET("/oidc/:provider/callback", limitAuth, middleware.OptionalAuthMiddleware(authService), authController.Callback)
+
+			// Email login: magic links and optional passwords
+			auth.POST("/register", limitAuth, authController.Register)
+			auth.POST("/login", limitAuth, authController.PasswordLogin)
+			auth.POST("/email/magic-link", limitAuth, authController.RequestMagicLink)
+			auth.POST("/email/magic-link/verify", limitAuth, authController.MagicLinkLogin)
+			auth.POST("/email/verify", limitAuth, authController.VerifyEmail)
+			auth.POST("/email/resend-verification", limitAuth, authController.ResendVerification)
+			auth.POST("/password/forgot", limitAuth, authController.ForgotPassword)
+			auth.POST("/password/reset", limitAuth, authController.ResetPassword)
+
+			// Google keeps its original URLs so existing redirect URIs continue to work
+			withGoogle := func(handler gin.HandlerFunc) gin.HandlerFunc {
+				return func(c *gin.Context) {
+					c.Params = append(c.Params, gin.Param{Key: "provider", Value: "google"})
+					handler(c)
+				}
+			}
+			auth.GET("/google", limitAuth, middleware.OptionalAuthMiddleware(authService), withGoogle(authController.Login))
+			auth.GET("/google/callback", limitAuth, middleware.OptionalAuthMiddleware(authService), withGoogle(authController.Callback))
+			auth.POST("/logout", middleware.OptionalAuthMiddleware(authService), authController.Logout)
+			auth.POST("/refresh", authController.RefreshToken)
 			auth.GET("/check", middleware.OptionalAuthMiddleware(authService), authController.CheckAuth)
+
+			// Session management
+			sessions := auth.Group("/sessions")
+			sessions.Use(requireAuth, limitUser)
+			{
+				sessions.GET("", authController.ListSessions)
+				sessions.DELETE("", authController.RevokeAllSessions)
+				sessions.DELETE("/:id", authController.RevokeSession)
+			}
+
+			// Personal access tokens for scripts and integrations
+			tokens := auth.Group("/tokens")
+			tokens.Use(requireAuth, limitUser)
+			{
+				tokens.GET("", accessTokenController.ListTokens)
+				tokens.POST("", accessTokenController.CreateToken)
+				tokens.DELETE("/:id", accessTokenController.RevokeToken)
+			}
+
+			// Linked identity provider accounts
+			identities := auth.Group("/identities")
+			identities.Use(requireAuth, limitUser)
+			{
+				identities.GET("", authController.ListIdentities)
+				identities.DELETE("/:id", authController.UnlinkIdentity)
+			}
+		}
+
+		// Routes also available to personal access tokens with the listed scopes
+		scoped := api.Group("/")
+		scoped.Use(middleware.TimeoutMiddleware(cfg.Timeouts.API))
+		{
+			scoped.GET("/me", requireScope(models.ScopeReadProfile), limitUser, authController.Me)
+			scoped.GET("/profile", requireScope(models.ScopeReadProfile), limitUser, userController.GetCurrentUserProfile)
+			scoped.GET("/achievements/user", requireScope(models.ScopeReadProfile), limitUser, func(c *gin.Context) {
+				userID, _ := middleware.GetCurrentUserID(c)
+				userAchievements, err := achievementService.GetUserAchievements(c.Request.Context(), userID)
+				if err != nil {
+					c.JSON(500, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(200, gin.H{"achievements": userAchievements})
+			})
+			scoped.GET("/tasks/daily", requireScope(models.ScopeReadProfile, models.ScopeWriteTasks), limitUser, func(c *gin.Context) {
+				userID, _ := middleware.GetCurrentUserID(c)
+				dailyTasks, err := taskService.GetUserDailyTasks(c.Request.Context(), userID)
+				if err != nil {
+					c.JSON(500, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(200, gin.H{"tasks": dailyTasks})
+			})
+			scoped.POST("/tasks/daily/generate", requireScope(models.ScopeWriteTasks), limitUser, idempotent, func(c *gin.Context) {
+				userID, _ := middleware.GetCurrentUserID(c)
+				dailyTasks, err := taskService.GenerateDailyTasks(c.Request.Context(), userID)
+				if err != nil {
+					c.JSON(500, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(200, gin.H{"tasks": dailyTasks})
+			})
+			scoped.POST("/tasks/daily/:id/complete", requireScope(models.ScopeWriteTasks), limitUser, limitTaskCompletion, idempotent, taskController.CompleteTask)
+			scoped.GET("/leaderboard", requireScope(models.ScopeReadLeaderboard), limitUser, userController.GetLeaderboard)
 		}
 
 		// Protected routes requiring authentication
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware(authService))
+		protected.Use(middleware.TimeoutMiddleware(cfg.Timeouts.API), requireAuth, limitUser)
 		{
 			// User profile routes
-			protected.GET("/me", authController.Me)
-			protected.POST("/auth/refresh", authController.RefreshToken)
-			protected.GET("/profile", userController.GetCurrentUserProfile)
-			protected.PUT("/profile", func(c *gin.Context) {
+			protected.PUT("/profile", idempotent, func(c *gin.Context) {
 				// Get current user ID and forward to update method
 				userID, exists := middleware.GetCurrentUserID(c)
 				if !exists {
 					c.JSON(401, gin.H{"error": "Authentication required"})
 					return
 				}
-				c.Param("id")
-				c.Set("id", userID)
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: strconv.FormatUint(uint64(userID), 10)})
 				userController.UpdateUser(c)
 			})
+			protected.PUT("/profile/username", idempotent, userController.ChangeUsername)
+			protected.GET("/profile/loadout", achievementController.GetLoadout)
+			protected.PUT("/profile/loadout", idempotent, achievementController.UpdateLoadout)
 
 			// User-specific routes with ownership verification
 			users := protected.Group("/users")
 			{
 				users.GET("/:id", userController.GetUserByID)
-				users.PUT("/:id", userController.UpdateUser)
-				users.DELETE("/:id", userController.DeleteUser)
+				users.PUT("/:id", idempotent, userController.UpdateUser)
+				users.DELETE("/:id", idempotent, userController.DeleteUser)
 				users.GET("/tasks", userController.GetUserTasks)
 				users.GET("/achievements", userController.GetUserAchievements)
 			}
 
-			// Task routes
-			tasks := protected.Group("/tasks")
+			// Task verification routes
+			verifications := protected.Group("/verifications")
 			{
-				tasks.GET("/daily", func(c *gin.Context) {
-					userID, _ := middleware.GetCurrentUserID(c)
-					dailyTasks, err := taskService.GetUserDailyTasks(userID)
-					if err != nil {
-						c.JSON(500, gin.H{"error": err.Error()})
-						return
-					}
-					c.JSON(200, gin.H{"tasks": dailyTasks})
-				})
-				tasks.POST("/daily/generate", func(c *gin.Context) {
-					userID, _ := middleware.GetCurrentUserID(c)
-					dailyTasks, err := taskService.GenerateDailyTasks(userID)
-					if err != nil {
-						c.JSON(500, gin.H{"error": err.Error()})
-						return
-					}
-					c.JSON(200, gin.H{"tasks": dailyTasks})
-				})
-				tasks.POST("/daily/:id/complete", func(c *gin.Context) {
-					taskController.CompleteTask(c)
-				})
+				verifications.GET("", taskController.GetVerificationQueue)
+				verifications.POST("/:id/approve", idempotent, taskController.ApproveCompletion)
+				verifications.POST("/:id/reject", idempotent, taskController.RejectCompletion)
 			}
 
 			// Achievement routes
 			achievements := protected.Group("/achievements")
 			{
-				achievements.GET("/", achievementController.GetAllAchievements)
-				achievements.POST("/:id/unlock", achievementController.UnlockAchievement)
-				achievements.GET("/user", func(c *gin.Context) {
-					userID, _ := middleware.GetCurrentUserID(c)
-					userAchievements, err := achievementService.GetUserAchievements(userID)
-					if err != nil {
-						c.JSON(500, gin.H{"error": err.Error()})
-						return
-					}
-					c.JSON(200, gin.H{"achievements": userAchievements})
-				})
+				achievements.GET("/", achievementController.GetAchievementsForUser)
+				achievements.POST("/:id/unlock", limitAchievementUnlock, idempotent, achievementController.UnlockAchievement)
+				achievements.POST("/:id/refund", limitAchievementUnlock, idempotent, achievementController.RefundAchievement)
 			}
 		}
 
 		// Public routes (no authentication required)
 		public := api.Group("/public")
+		public.Use(middleware.TimeoutMiddleware(cfg.Timeouts.Public))
 		{
 			public.GET("/tasks", taskController.GetAllTasks)
 			public.GET("/achievements", achievementController.GetAllAchievements)
 			public.GET("/leaderboard", userController.GetLeaderboard)
+			public.GET("/users/:username", userController.GetPublicProfile)
 		}
 
 		// Admin routes (for user creation - could be expanded)
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(authService))
+		admin.Use(middleware.TimeoutMiddleware(cfg.Timeouts.Admin), requireAuth, middleware.RequireRole(models.RoleAdmin), limitUser)
 		{
 			admin.POST("/users", userController.CreateUser)
+			admin.POST("/users/:id/achievements/:achievement_id/revoke", achievementController.RevokeAchievement)
+			admin.GET("/achievements/audit", achievementController.GetAuditLog)
+			admin.GET("/anticheat/flags", antiCheatController.GetFlags)
+			admin.POST("/anticheat/flags/:id/review", antiCheatController.ReviewFlag)
+			admin.GET("/jobs", jobController.ListJobs)
+			admin.GET("/jobs/runs", jobController.ListRuns)
+			admin.POST("/webhooks", webhookController.CreateWebhook)
+			admin.GET("/webhooks", webhookController.ListWebhooks)
+			admin.GET("/webhooks/:id", webhookController.GetWebhook)
+			admin.PUT("/webhooks/:id", webhookController.UpdateWebhook)
+			admin.DELETE("/webhooks/:id", webhookController.DeleteWebhook)
+			admin.GET("/webhooks/:id/deliveries", webhookController.ListDeliveries)
+			admin.POST("/webhooks/deliveries/:id/redeliver", webhookController.Redeliver)
+		}
+	}
+
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Server.Port),
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "port", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
 		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal("Failed to start server", err)
+	case <-ctx.Done():
 	}
+	// A second signal stops the process immediately
+	stop()
 
-	// Set port from environment or default to 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Fail readiness first so load balancers stop routing new requests here, then let
+	// in-flight requests finish
+	slog.Info("Shutting down", "drain_delay", cfg.Server.DrainDelay.String())
+	healthService.SetDraining()
+	time.Sleep(cfg.Server.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Requests still running at shutdown timeout were cut off", "error", err)
+		server.Close()
 	}
 
-	log.Printf("Starting server on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	// Stop background workers, flush spans and close the database pool
+	stopJobs()
+	scheduler.Wait()
+	outboxDispatcher.Close()
+	webhookService.Close()
+	idempotencyService.Close()
+	rateLimitStore.Close()
+	flushCtx, cancelFlush := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		slog.Warn("Failed to close database connections", "error", err)
 	}
-} 
\ No newline at end of file
+	slog.Info("Server stopped")
+}
+
+// registerOperationalRoutes registers the health, metrics and key endpoints. Probes, scrapes and
+// JWKS fetches come from a few shared addresses, so these routes are registered before the IP
+// rate limiter is added to the router and are not limited by it.
+func registerOperationalRoutes(router *gin.Engine, healthController *controllers.HealthController, authController *controllers.AuthController) {
+	// Health check endpoint
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status":  "healthy",
+			"service": "fithero-backend",
+		})
+	})
+
+	// Liveness and readiness probes
+	router.GET("/livez", healthController.Livez)
+	router.GET("/readyz", healthController.Readyz)
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Public keys for services verifying FitHero access tokens
+	router.GET("/.well-known/jwks.json", authController.JWKS)
+}
+
+// fatal logs an error that prevents the server from starting and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
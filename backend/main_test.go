@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/controllers"
+	"fithero-backend/middleware"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type healthyRepo struct{}
+
+func (healthyRepo) Ping(ctx context.Context) error { return nil }
+
+func (healthyRepo) GetSchemaVersion(ctx context.Context) (int, error) {
+	return config.SchemaVersion, nil
+}
+
+func TestOperationalRoutesAreNotRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authConfig := &config.AuthConfig{JWTSecret: strings.Repeat("s", 32)}
+	signer, err := services.NewTokenSigner(authConfig)
+	if err != nil {
+		t.Fatalf("NewTokenSigner: %v", err)
+	}
+	healthService := services.NewHealthService(healthyRepo{}, &config.HealthConfig{CacheTTL: time.Second, CheckTimeout: time.Second})
+	authService := services.NewAuthService(nil, nil, nil, services.NewProviderRegistry(nil), authConfig, signer, nil)
+
+	store := services.NewMemoryRateLimitStore(time.Minute)
+	defer store.Close()
+	router := gin.New()
+	registerOperationalRoutes(router, controllers.NewHealthController(healthService), controllers.NewAuthController(authService, nil, authConfig))
+	router.Use(middleware.RateLimitMiddleware(store, "ip", config.RateLimit{Requests: 1, Period: time.Hour, Burst: 1}, middleware.RateLimitByIP))
+	router.GET("/api/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	get := func(path string) int {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.RemoteAddr = "10.0.0.5:4000"
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// Probes and scrapes from one address, far more often than the limit allows
+	for i := 0; i < 5; i++ {
+		for _, path := range []string{"/health", "/livez", "/readyz", "/metrics", "/.well-known/jwks.json"} {
+			if status := get(path); status != http.StatusOK {
+				t.Fatalf("GET %s #%d: status = %d, want %d", path, i+1, status, http.StatusOK)
+			}
+		}
+	}
+
+	// The address is still within its limit for the API, which is limited
+	if status := get("/api/ping"); status != http.StatusNoContent {
+		t.Fatalf("first API request: status = %d, want %d", status, http.StatusNoContent)
+	}
+	if status := get("/api/ping"); status != http.StatusTooManyRequests {
+		t.Errorf("second API request: status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+}
@@ -1,31 +1,52 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"fithero-backend/logging"
 	"fithero-backend/services"
 	"fithero-backend/models"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	UserContextKey = "user"
 	UserIDContextKey = "user_id"
+	SessionIDContextKey = "session_id"
+	AccessTokenContextKey = "access_token"
 )
 
-// AuthMiddleware validates JWT tokens and sets user context
-func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
+// CSRF tokens are issued by /api/auth/check in a cookie and in the response body. Mutating
+// requests authenticated by the auth cookie must echo the token in the header.
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// AuthMiddleware validates JWT tokens and sets user context. Personal access tokens sent in the
+// Authorization header are accepted only when the route lists scopes, and must hold one of them.
+func AuthMiddleware(authService *services.AuthService, accessTokenService *services.AccessTokenService, scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Try to get token from Authorization header
 		token := extractTokenFromHeader(c)
+
+		if strings.HasPrefix(token, models.AccessTokenPrefix) {
+			authenticateAccessToken(c, accessTokenService, token, scopes)
+			return
+		}
 		
 		// If not in header, try to get from cookie
+		fromCookie := false
 		if token == "" {
 			if cookie, err := c.Request.Cookie("auth_token"); err == nil {
 				token = cookie.Value
+				fromCookie = true
 			}
 		}
 
@@ -47,8 +68,26 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens whose session was revoked (logout, reuse detection, remote sign-out)
+		if !authService.IsSessionActive(c.Request.Context(), claims.SessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Session has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
+		// Browsers send cookies with cross-site requests; Bearer tokens are never sent implicitly
+		if fromCookie && !hasValidCSRFToken(c, authService, claims.SessionID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Invalid or missing CSRF token",
+			})
+			c.Abort()
+			return
+		}
+
 		// Get user details (optional - for additional validation)
-		user, err := authService.GetUserByID(claims.UserID)
+		user, err := authService.GetUserByID(c.Request.Context(), claims.UserID)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "User not found",
@@ -68,11 +107,57 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		// Set user context
 		c.Set(UserContextKey, user)
 		c.Set(UserIDContextKey, claims.UserID)
+		withUserLogger(c, claims.UserID)
+		c.Set(SessionIDContextKey, claims.SessionID)
 
 		c.Next()
 	}
 }
 
+// authenticateAccessToken authenticates a personal access token and checks its scopes
+func authenticateAccessToken(c *gin.Context, accessTokenService *services.AccessTokenService, token string, scopes []string) {
+	if len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Personal access tokens cannot access this endpoint",
+		})
+		c.Abort()
+		return
+	}
+
+	accessToken, user, err := accessTokenService.Authenticate(c.Request.Context(), token, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired token",
+		})
+		c.Abort()
+		return
+	}
+
+	granted := false
+	for _, scope := range scopes {
+		if accessToken.HasScope(scope) {
+			granted = true
+			break
+		}
+	}
+	if !granted {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":           "Insufficient token scope",
+			"required_scopes": scopes,
+		})
+		c.Abort()
+		return
+	}
+
+	// Set user context
+	c.Set(UserContextKey, user)
+	c.Set(UserIDContextKey, user.ID)
+	withUserLogger(c, user.ID)
+	c.Set(AccessTokenContextKey, accessToken)
+
+	c.Next()
+}
+
 // OptionalAuthMiddleware validates JWT tokens if present but doesn't require authentication
 func OptionalAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -80,20 +165,25 @@ func OptionalAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		token := extractTokenFromHeader(c)
 		
 		// If not in header, try to get from cookie
+		fromCookie := false
 		if token == "" {
 			if cookie, err := c.Request.Cookie("auth_token"); err == nil {
 				token = cookie.Value
+				fromCookie = true
 			}
 		}
 
 		if token != "" {
-			// Validate the token
-			if claims, err := authService.ValidateJWT(token); err == nil {
+			// Validate the token; cookie-authenticated mutating requests without a CSRF token stay anonymous
+			if claims, err := authService.ValidateJWT(token); err == nil && authService.IsSessionActive(c.Request.Context(), claims.SessionID) &&
+				(!fromCookie || hasValidCSRFToken(c, authService, claims.SessionID)) {
 				// Get user details
-				if user, err := authService.GetUserByID(claims.UserID); err == nil && user.IsActive {
+				if user, err := authService.GetUserByID(c.Request.Context(), claims.UserID); err == nil && user.IsActive {
 					// Set user context
 					c.Set(UserContextKey, user)
 					c.Set(UserIDContextKey, claims.UserID)
+					withUserLogger(c, claims.UserID)
+					c.Set(SessionIDContextKey, claims.SessionID)
 				}
 			}
 		}
@@ -102,6 +192,28 @@ func OptionalAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 	}
 }
 
+// withUserLogger adds the authenticated user's ID to the request context's logger and span
+func withUserLogger(c *gin.Context, userID uint) {
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.Int64("enduser.id", int64(userID)))
+	c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "user_id", userID))
+}
+
+// hasValidCSRFToken checks the double-submitted CSRF token of mutating requests. The header must
+// match the cookie and carry a signature for the current session. Safe methods need no token.
+func hasValidCSRFToken(c *gin.Context, authService *services.AuthService, sessionID uint) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	headerToken := c.GetHeader(CSRFHeaderName)
+	cookie, err := c.Request.Cookie(CSRFCookieName)
+	if err != nil || headerToken == "" || subtle.ConstantTimeCompare([]byte(headerToken), []byte(cookie.Value)) != 1 {
+		return false
+	}
+	return authService.ValidateCSRFToken(sessionID, headerToken)
+}
+
 // extractTokenFromHeader extracts JWT token from Authorization header
 func extractTokenFromHeader(c *gin.Context) string {
 	authHeader := c.GetHeader("Authorization")
@@ -140,6 +252,28 @@ func GetCurrentUserID(c *gin.Context) (uint, bool) {
 	return id, ok
 }
 
+// GetCurrentSessionID returns the session ID of the current access token
+func GetCurrentSessionID(c *gin.Context) (uint, bool) {
+	sessionID, exists := c.Get(SessionIDContextKey)
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := sessionID.(uint)
+	return id, ok
+}
+
+// GetCurrentAccessToken returns the personal access token the request was authenticated with, if any
+func GetCurrentAccessToken(c *gin.Context) (*models.PersonalAccessToken, bool) {
+	token, exists := c.Get(AccessTokenContextKey)
+	if !exists {
+		return nil, false
+	}
+
+	accessToken, ok := token.(*models.PersonalAccessToken)
+	return accessToken, ok
+}
+
 // RequireOwnership middleware ensures the user can only access their own resources
 func RequireOwnership() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -183,4 +317,30 @@ func RequireOwnership() gin.HandlerFunc {
 
 		c.Next()
 	}
-} 
\ No newline at end of file
+}
+
+// RequireRole middleware ensures the authenticated user has one of the given roles
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetCurrentUser(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authentication required",
+			})
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Access denied: insufficient permissions",
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// activeSessionRepo reports every session as active
+type activeSessionRepo struct {
+	repositories.SessionRepositoryInterface
+}
+
+func (r *activeSessionRepo) GetByID(ctx context.Context, id uint) (*models.Session, error) {
+	return &models.Session{ID: id, ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+// activeUserRepo returns an active user for every ID
+type activeUserRepo struct {
+	repositories.UserRepositoryInterface
+}
+
+func (r *activeUserRepo) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	return &models.User{ID: id, IsActive: true}, nil
+}
+
+// newCSRFTestRouter returns a router with an authenticated POST /api/profile, the auth service
+// and an access token for session 1
+func newCSRFTestRouter(t *testing.T) (*gin.Engine, *services.AuthService, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	authConfig := &config.AuthConfig{
+		JWTSecret:  strings.Repeat("s", 32),
+		CSRFSecret: []byte(strings.Repeat("k", 32)),
+	}
+	signer, err := services.NewTokenSigner(authConfig)
+	if err != nil {
+		t.Fatalf("NewTokenSigner: %v", err)
+	}
+	authService := services.NewAuthService(&activeUserRepo{}, &activeSessionRepo{}, nil, services.NewProviderRegistry(nil), authConfig, signer, nil)
+
+	token, err := signer.Sign(&models.JWTClaims{
+		UserID:    1,
+		SessionID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Issuer:    "fithero-backend",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/profile", AuthMiddleware(authService, nil), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router, authService, token
+}
+
+func postProfile(router *gin.Engine, configure func(request *http.Request)) int {
+	request := httptest.NewRequest(http.MethodPost, "/api/profile", nil)
+	configure(request)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func TestAuthMiddlewareRequiresCSRFTokenWithCookie(t *testing.T) {
+	router, authService, token := newCSRFTestRouter(t)
+	csrfToken, err := authService.IssueCSRFToken(1)
+	if err != nil {
+		t.Fatalf("IssueCSRFToken: %v", err)
+	}
+	otherSessionToken, err := authService.IssueCSRFToken(2)
+	if err != nil {
+		t.Fatalf("IssueCSRFToken: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		cookieToken string
+		headerToken string
+		want        int
+	}{
+		{"no token", "", "", http.StatusForbidden},
+		{"cookie without header", csrfToken, "", http.StatusForbidden},
+		{"header without cookie", "", csrfToken, http.StatusForbidden},
+		{"header differs from cookie", csrfToken, otherSessionToken, http.StatusForbidden},
+		{"token of another session", otherSessionToken, otherSessionToken, http.StatusForbidden},
+		{"forged token", "nonce.signature", "nonce.signature", http.StatusForbidden},
+		{"valid token", csrfToken, csrfToken, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := postProfile(router, func(request *http.Request) {
+				request.AddCookie(&http.Cookie{Name: "auth_token", Value: token})
+				if tt.cookieToken != "" {
+					request.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tt.cookieToken})
+				}
+				if tt.headerToken != "" {
+					request.Header.Set(CSRFHeaderName, tt.headerToken)
+				}
+			})
+			if status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareSkipsCSRFForBearerTokens(t *testing.T) {
+	router, _, token := newCSRFTestRouter(t)
+
+	status := postProfile(router, func(request *http.Request) {
+		request.Header.Set("Authorization", "Bearer "+token)
+	})
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", status, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"fithero-backend/logging"
+	"fithero-backend/models"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotentBodySize limits the request bodies buffered for fingerprinting. It leaves room
+// for evidence photo uploads, which are limited to 5 MB.
+const maxIdempotentBodySize = 8 << 20
+
+// idempotencyResponseWriter keeps a copy of the response body
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes authenticated POST, PUT, PATCH and DELETE requests carrying an Idempotency-Key header safe to
+// retry. The first response for a key is stored and replayed to retries of the same request;
+// reusing the key for a different request is rejected. Server errors, timeouts, conflicts and
+// rate limited responses are not stored, so the request can be retried with the same key once
+// the condition has passed. Requests without the header are not affected.
+func IdempotencyMiddleware(idempotencyService *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(models.IdempotencyKeyHeader)
+		userID, authenticated := GetCurrentUserID(c)
+		if key == "" || !authenticated || !isMutatingMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength),
+			})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+				c.Abort()
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, replay, err := idempotencyService.Begin(c.Request.Context(), userID, key, c.Request.Method, c.Request.URL.Path, requestFingerprint(c, body))
+		if err != nil {
+			switch err.Error() {
+			case "idempotency key reused with different request":
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case "request with this idempotency key in progress":
+				c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process Idempotency-Key"})
+			}
+			c.Abort()
+			return
+		}
+
+		if replay {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.StatusCode, record.ContentType, record.Response)
+			c.Abort()
+			return
+		}
+
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if !storableStatus(status) {
+			if err := idempotencyService.Release(c.Request.Context(), record); err != nil {
+				logging.FromContext(c.Request.Context()).Warn("Failed to release idempotency key", "idempotency_key_id", record.ID, "error", err)
+			}
+			return
+		}
+		if err := idempotencyService.Complete(c.Request.Context(), record, status, writer.Header().Get("Content-Type"), writer.body.Bytes()); err != nil {
+			logging.FromContext(c.Request.Context()).Warn("Failed to store idempotent response", "idempotency_key_id", record.ID, "error", err)
+		}
+	}
+}
+
+// requestFingerprint identifies a request by method, path, query and body
+func requestFingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// storableStatus reports whether a response is final for its request. Timeouts, conflicts and
+// rate limits are transient and the request may succeed when retried.
+func storableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return false
+	}
+	return status < http.StatusInternalServerError
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
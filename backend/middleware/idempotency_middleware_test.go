@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// memoryIdempotencyRepo keeps idempotency keys in memory
+type memoryIdempotencyRepo struct {
+	records map[string]*models.IdempotencyKey
+	nextID  uint
+}
+
+func (r *memoryIdempotencyRepo) Create(ctx context.Context, record *models.IdempotencyKey) (bool, error) {
+	if _, exists := r.records[record.Key]; exists {
+		return false, nil
+	}
+	r.nextID++
+	record.ID = r.nextID
+	record.CreatedAt = time.Now()
+	stored := *record
+	r.records[record.Key] = &stored
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepo) GetByUserKey(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error) {
+	record, ok := r.records[key]
+	if !ok || record.UserID != userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (r *memoryIdempotencyRepo) Complete(ctx context.Context, id uint, statusCode int, contentType string, response []byte, completedAt time.Time) error {
+	for _, record := range r.records {
+		if record.ID == id {
+			record.StatusCode = statusCode
+			record.ContentType = contentType
+			record.Response = response
+			record.CompletedAt = &completedAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) Delete(ctx context.Context, id uint) error {
+	for key, record := range r.records {
+		if record.ID == id {
+			delete(r.records, key)
+		}
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepo) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// newIdempotentRouter returns a router whose POST /orders creates an order per handled request
+// and answers with status, for user 1
+func newIdempotentRouter(t *testing.T, status *int) (*gin.Engine, *memoryIdempotencyRepo, *int) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := &memoryIdempotencyRepo{records: map[string]*models.IdempotencyKey{}}
+	service := services.NewIdempotencyService(repo, &config.IdempotencyConfig{KeyTTL: time.Hour, LockTimeout: time.Minute})
+
+	router := gin.New()
+	handled := 0
+	router.POST("/orders", func(c *gin.Context) {
+		c.Set(UserIDContextKey, uint(1))
+	}, IdempotencyMiddleware(service), func(c *gin.Context) {
+		handled++
+		c.JSON(*status, gin.H{"order": handled})
+	})
+	return router, repo, &handled
+}
+
+func postOrder(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		request.Header.Set(models.IdempotencyKeyHeader, key)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIdempotencyReplaysFirstResponse(t *testing.T) {
+	status := http.StatusCreated
+	router, _, handled := newIdempotentRouter(t, &status)
+
+	first := postOrder(router, "order-1", `{"item": "protein bar"}`)
+	retry := postOrder(router, "order-1", `{"item": "protein bar"}`)
+	if *handled != 1 {
+		t.Fatalf("handled %d requests, want the retry replayed", *handled)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry = %d %s, want the first response %d %s replayed", retry.Code, retry.Body.String(), first.Code, first.Body.String())
+	}
+
+	// Another key, or no key at all, is a new request
+	postOrder(router, "order-2", `{"item": "protein bar"}`)
+	postOrder(router, "", `{"item": "protein bar"}`)
+	if *handled != 3 {
+		t.Errorf("handled %d requests, want 3", *handled)
+	}
+}
+
+func TestIdempotencyRejectsKeyReuseForDifferentRequest(t *testing.T) {
+	status := http.StatusCreated
+	router, _, handled := newIdempotentRouter(t, &status)
+
+	postOrder(router, "order-1", `{"item": "protein bar"}`)
+	mismatched := postOrder(router, "order-1", `{"item": "dumbbells"}`)
+	if mismatched.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", mismatched.Code, http.StatusUnprocessableEntity)
+	}
+	if *handled != 1 {
+		t.Errorf("handled %d requests, want the mismatched request rejected", *handled)
+	}
+}
+
+func TestIdempotencyReleasesTransientResponses(t *testing.T) {
+	for _, transient := range []int{http.StatusConflict, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		status := transient
+		router, repo, handled := newIdempotentRouter(t, &status)
+
+		if recorder := postOrder(router, "order-1", `{}`); recorder.Code != transient {
+			t.Fatalf("status = %d, want %d", recorder.Code, transient)
+		}
+		if len(repo.records) != 0 {
+			t.Errorf("status %d: the key was kept, want it released", transient)
+		}
+
+		// The retry runs once the condition has passed
+		status = http.StatusCreated
+		if recorder := postOrder(router, "order-1", `{}`); recorder.Code != http.StatusCreated || *handled != 2 {
+			t.Errorf("status %d: retry = %d after %d handled requests, want it handled again", transient, recorder.Code, *handled)
+		}
+	}
+}
+
+func TestIdempotencyRejectsOversizedBody(t *testing.T) {
+	status := http.StatusCreated
+	router, repo, handled := newIdempotentRouter(t, &status)
+
+	recorder := postOrder(router, "upload-1", strings.Repeat("a", maxIdempotentBodySize+1))
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusRequestEntityTooLarge)
+	}
+	if *handled != 0 || len(repo.records) != 0 {
+		t.Errorf("oversized request was handled %d times and stored %d keys", *handled, len(repo.records))
+	}
+}
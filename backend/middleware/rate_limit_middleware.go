@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/logging"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const rateLimitContextKey = "rate_limit"
+
+// RateLimitKeyFunc returns the key requests are counted under
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimitByIP counts requests per client IP
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByUser counts requests per authenticated user, falling back to the client IP
+func RateLimitByUser(c *gin.Context) string {
+	if userID, exists := GetCurrentUserID(c); exists {
+		return "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimitMiddleware enforces a token bucket limit named policy. Requests over the limit get a
+// 429 response. The RateLimit-* headers describe the most restrictive limit applied to the request.
+// When the store fails, requests are let through.
+func RateLimitMiddleware(store services.RateLimitStore, policy string, limit config.RateLimit, key RateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := store.Take(c.Request.Context(), policy+":"+key(c), limit)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Warn("Rate limit store failed", "policy", policy, "error", err)
+			c.Next()
+			return
+		}
+
+		setRateLimitHeaders(c, limit, result)
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many requests",
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setRateLimitHeaders writes the RateLimit-* headers unless an earlier limit on the same request
+// has fewer requests remaining
+func setRateLimitHeaders(c *gin.Context, limit config.RateLimit, result services.RateLimitResult) {
+	if previous, exists := c.Get(rateLimitContextKey); exists {
+		if previous.(services.RateLimitResult).Remaining <= result.Remaining && result.Allowed {
+			return
+		}
+	}
+	c.Set(rateLimitContextKey, result)
+
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
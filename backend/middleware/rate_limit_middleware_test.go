@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubRateLimitStore returns fixed results and records the keys it was asked for
+type stubRateLimitStore struct {
+	results []services.RateLimitResult
+	err     error
+	keys    []string
+}
+
+func (s *stubRateLimitStore) Take(ctx context.Context, key string, limit config.RateLimit) (services.RateLimitResult, error) {
+	s.keys = append(s.keys, key)
+	if s.err != nil {
+		return services.RateLimitResult{}, s.err
+	}
+	result := s.results[0]
+	s.results = s.results[1:]
+	return result, nil
+}
+
+func (s *stubRateLimitStore) Close() {}
+
+func newRateLimitedRouter(t *testing.T, trustedProxies []string, middlewares ...gin.HandlerFunc) (*gin.Engine, *int) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	handled := 0
+	router.GET("/", append(middlewares, func(c *gin.Context) {
+		handled++
+		c.Status(http.StatusNoContent)
+	})...)
+	return router, &handled
+}
+
+func serve(router *gin.Engine, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRateLimitMiddlewareRejectsOverLimit(t *testing.T) {
+	store := services.NewMemoryRateLimitStore(time.Hour)
+	defer store.Close()
+	limit := config.RateLimit{Requests: 2, Period: time.Minute, Burst: 2}
+	router, handled := newRateLimitedRouter(t, nil, RateLimitMiddleware(store, "test", limit, RateLimitByIP))
+
+	for i := 1; i >= 0; i-- {
+		recorder := serve(router, "192.0.2.1:1234", "")
+		if recorder.Code != http.StatusNoContent {
+			t.Fatalf("request within the limit got %d", recorder.Code)
+		}
+		if got := recorder.Header().Get("RateLimit-Remaining"); got != strconv.Itoa(i) {
+			t.Errorf("RateLimit-Remaining = %q, want %d", got, i)
+		}
+		if got := recorder.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+			t.Errorf("RateLimit-Policy = %q, want 2;w=60", got)
+		}
+	}
+
+	recorder := serve(router, "192.0.2.1:1234", "")
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit got %d, want 429", recorder.Code)
+	}
+	// A token is refilled every 30 seconds; Retry-After rounds up to whole seconds
+	if got := recorder.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if recorder.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want 0", recorder.Header().Get("RateLimit-Remaining"))
+	}
+	if *handled != 2 {
+		t.Errorf("handler ran %d times, want 2", *handled)
+	}
+
+	if recorder := serve(router, "192.0.2.2:1234", ""); recorder.Code != http.StatusNoContent {
+		t.Errorf("another client got %d, want its own bucket", recorder.Code)
+	}
+}
+
+func TestRateLimitMiddlewareRetryAfterRoundsUp(t *testing.T) {
+	store := &stubRateLimitStore{results: []services.RateLimitResult{
+		{Allowed: false, Limit: 10, RetryAfter: 1500 * time.Millisecond, ResetAfter: 9 * time.Second},
+	}}
+	limit := config.RateLimit{Requests: 10, Period: 10 * time.Second, Burst: 10}
+	router, handled := newRateLimitedRouter(t, nil, RateLimitMiddleware(store, "test", limit, RateLimitByIP))
+
+	recorder := serve(router, "192.0.2.1:1234", "")
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "2" {
+		t.Errorf("got %d with Retry-After %q, want 429 with 2", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+	if recorder.Header().Get("RateLimit-Reset") != "9" {
+		t.Errorf("RateLimit-Reset = %q, want 9", recorder.Header().Get("RateLimit-Reset"))
+	}
+	if *handled != 0 {
+		t.Error("the handler ran for a rejected request")
+	}
+}
+
+func TestRateLimitMiddlewareReportsMostRestrictiveLimit(t *testing.T) {
+	loose := &stubRateLimitStore{results: []services.RateLimitResult{{Allowed: true, Limit: 300, Remaining: 250}}}
+	strict := &stubRateLimitStore{results: []services.RateLimitResult{{Allowed: true, Limit: 10, Remaining: 3}}}
+	router, _ := newRateLimitedRouter(t, nil,
+		RateLimitMiddleware(strict, "strict", config.RateLimit{Requests: 10, Period: time.Minute, Burst: 10}, RateLimitByIP),
+		RateLimitMiddleware(loose, "loose", config.RateLimit{Requests: 300, Period: time.Minute, Burst: 300}, RateLimitByIP),
+	)
+
+	recorder := serve(router, "192.0.2.1:1234", "")
+	if recorder.Header().Get("RateLimit-Limit") != "10" || recorder.Header().Get("RateLimit-Remaining") != "3" {
+		t.Errorf("headers describe limit %s with %s remaining, want 10 with 3",
+			recorder.Header().Get("RateLimit-Limit"), recorder.Header().Get("RateLimit-Remaining"))
+	}
+}
+
+func TestRateLimitMiddlewareFailsOpen(t *testing.T) {
+	store := &stubRateLimitStore{err: errors.New("store unavailable")}
+	router, handled := newRateLimitedRouter(t, nil,
+		RateLimitMiddleware(store, "test", config.RateLimit{Requests: 1, Period: time.Minute, Burst: 1}, RateLimitByIP))
+
+	if recorder := serve(router, "192.0.2.1:1234", ""); recorder.Code != http.StatusNoContent || *handled != 1 {
+		t.Errorf("got %d, want the request let through when the store fails", recorder.Code)
+	}
+}
+
+func TestRateLimitByIPTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		wantKey        string
+	}{
+		{name: "no trusted proxies", remoteAddr: "203.0.113.7:1234", wantKey: "test:ip:203.0.113.7"},
+		{name: "untrusted peer", trustedProxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.7:1234", wantKey: "test:ip:203.0.113.7"},
+		{name: "trusted proxy", trustedProxies: []string{"10.0.0.0/8"}, remoteAddr: "10.1.2.3:1234", wantKey: "test:ip:198.51.100.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &stubRateLimitStore{results: []services.RateLimitResult{{Allowed: true, Limit: 1}}}
+			router, _ := newRateLimitedRouter(t, tt.trustedProxies,
+				RateLimitMiddleware(store, "test", config.RateLimit{Requests: 1, Period: time.Minute, Burst: 1}, RateLimitByIP))
+
+			serve(router, tt.remoteAddr, "198.51.100.9")
+			if len(store.keys) != 1 || store.keys[0] != tt.wantKey {
+				t.Errorf("counted under %v, want %s", store.keys, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"fithero-backend/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware starts a server span for each request, continuing the caller's trace when
+// it sends a traceparent header. Probes and metric scrapes are not traced.
+func TracingMiddleware(serviceName string) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName, otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/health", "/livez", "/readyz", "/metrics":
+			return false
+		}
+		return true
+	}))
+}
+
+// TraceLogMiddleware correlates logs and traces: the request's logger gets the trace and span
+// IDs, and the span gets the request ID. It must run after RequestIDMiddleware.
+func TraceLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		span := trace.SpanFromContext(c.Request.Context())
+		if spanContext := span.SpanContext(); spanContext.IsValid() {
+			span.SetAttributes(attribute.String("request.id", c.GetString(RequestIDContextKey)))
+			ctx := logging.With(c.Request.Context(),
+				"trace_id", spanContext.TraceID().String(),
+				"span_id", spanContext.SpanID().String(),
+			)
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
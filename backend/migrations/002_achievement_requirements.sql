@@ -0,0 +1,35 @@
+-- Achievement unlock requirements
+ALTER TABLE achievements
+    ADD COLUMN min_level INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN available_from TIMESTAMP,
+    ADD COLUMN available_until TIMESTAMP,
+    ADD COLUMN stock_limit INTEGER;
+
+-- Achievement prerequisites (achievement requires prerequisite to be unlocked first)
+CREATE TABLE achievement_prerequisites (
+    achievement_id INTEGER REFERENCES achievements(id) ON DELETE CASCADE,
+    prerequisite_id INTEGER REFERENCES achievements(id) ON DELETE CASCADE,
+    PRIMARY KEY (achievement_id, prerequisite_id)
+);
+
+-- Level gates
+UPDATE achievements SET min_level = 2 WHERE title IN ('Health Guardian', 'Fitness Coach');
+UPDATE achievements SET min_level = 3 WHERE title IN ('Wellness Warrior', 'Wellness Expert');
+UPDATE achievements SET min_level = 4 WHERE title IN ('Fitness Champion', 'Fitness Director');
+UPDATE achievements SET min_level = 5 WHERE title IN ('Ultimate Hero', 'Health Guru');
+
+-- Career and character progression chains
+INSERT INTO achievement_prerequisites (achievement_id, prerequisite_id)
+SELECT a.id, p.id
+FROM (VALUES
+    ('Fitness Coach', 'Personal Trainer'),
+    ('Wellness Expert', 'Fitness Coach'),
+    ('Fitness Director', 'Wellness Expert'),
+    ('Health Guru', 'Fitness Director'),
+    ('Health Guardian', 'Fitness Apprentice'),
+    ('Wellness Warrior', 'Health Guardian'),
+    ('Fitness Champion', 'Wellness Warrior'),
+    ('Ultimate Hero', 'Fitness Champion')
+) AS chain(achievement_title, prerequisite_title)
+JOIN achievements a ON a.title = chain.achievement_title
+JOIN achievements p ON p.title = chain.prerequisite_title;
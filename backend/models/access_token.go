@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// Personal access token scopes
+const (
+	ScopeReadProfile     = "read:profile"
+	ScopeWriteTasks      = "write:tasks"
+	ScopeReadLeaderboard = "read:leaderboard"
+)
+
+// AccessTokenScopes lists every scope a personal access token can be granted
+var AccessTokenScopes = []string{ScopeReadProfile, ScopeWriteTasks, ScopeReadLeaderboard}
+
+// AccessTokenPrefix marks personal access tokens so they can be told apart from JWTs
+const AccessTokenPrefix = "fhp_"
+
+// PersonalAccessToken is a user-created credential for scripts and integrations.
+// Only the token hash is stored; the token itself is shown once on creation.
+type PersonalAccessToken struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Name        string     `json:"name" gorm:"not null"`
+	TokenHash   string     `json:"-" gorm:"not null;uniqueIndex"`
+	TokenPrefix string     `json:"token_prefix" gorm:"not null"` // First characters of the token, to help users identify it
+	Scopes      []string   `json:"scopes" gorm:"serializer:json;not null"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the token can still be used
+func (t *PersonalAccessToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+// HasScope reports whether the token was granted the scope
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAccessTokenRequest represents the request payload for creating a personal access token
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" validate:"required,min=1,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=read:profile write:tasks read:leaderboard"`
+	ExpiresInDays *int     `json:"expires_in_days" validate:"omitempty,min=1,max=365"` // Defaults to 90 days
+}
+
+// CreateAccessTokenResponse returns the new token; it cannot be retrieved again
+type CreateAccessTokenResponse struct {
+	Token       string              `json:"token"`
+	AccessToken PersonalAccessToken `json:"access_token"`
+}
@@ -5,6 +5,23 @@ import (
 	"gorm.io/gorm"
 )
 
+// Achievement availability states reported to a specific user
+const (
+	AchievementStatusLocked    = "locked"
+	AchievementStatusAvailable = "available"
+	AchievementStatusOwned     = "owned"
+)
+
+// Achievement audit actions
+const (
+	AchievementActionUnlock = "unlock"
+	AchievementActionRefund = "refund"
+	AchievementActionRevoke = "revoke"
+)
+
+// MaxShowcaseBadges is the number of badges a user can equip on their profile
+const MaxShowcaseBadges = 3
+
 type Achievement struct {
 	ID          uint   `json:"id" gorm:"primaryKey"`
 	Title       string `json:"title" gorm:"not null"`
@@ -12,12 +29,17 @@ type Achievement struct {
 	Icon        string `json:"icon" gorm:"not null"`
 	PointsCost  int    `json:"points_cost" gorm:"not null"`
 	Type        string `json:"type" gorm:"not null"` // character, upgrade, badge
+	MinLevel    int    `json:"min_level" gorm:"not null;default:1"` // Required user level
+	AvailableFrom  *time.Time `json:"available_from,omitempty"`  // Limited-time items start
+	AvailableUntil *time.Time `json:"available_until,omitempty"` // Limited-time items end
+	StockLimit     *int       `json:"stock_limit,omitempty"`     // Total unlocks allowed, nil means unlimited
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 	
 	// Relationships
 	UserAchievements []UserAchievement `json:"user_achievements,omitempty" gorm:"foreignKey:AchievementID"`
+	Prerequisites    []Achievement     `json:"prerequisites,omitempty" gorm:"many2many:achievement_prerequisites;joinForeignKey:AchievementID;joinReferences:PrerequisiteID"`
 }
 
 type UserAchievement struct {
@@ -25,6 +47,9 @@ type UserAchievement struct {
 	UserID        uint        `json:"user_id" gorm:"not null;index"`
 	AchievementID uint        `json:"achievement_id" gorm:"not null;index"`
 	UnlockedAt    time.Time   `json:"unlocked_at"`
+	PointsSpent   int         `json:"points_spent" gorm:"not null;default:0"`
+	IsEquipped    bool        `json:"is_equipped" gorm:"not null;default:false"`
+	SlotOrder     int         `json:"slot_order" gorm:"not null;default:0"` // Position in the badge showcase
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
@@ -38,8 +63,58 @@ type UserAchievement struct {
 	_ struct{} `gorm:"uniqueIndex:idx_user_achievement,composite:user_id,achievement_id"`
 }
 
+// AchievementAuditLog records every grant, refund and revocation of a user achievement
+type AchievementAuditLog struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"user_id" gorm:"not null;index"`  // User who owned the achievement
+	AchievementID uint      `json:"achievement_id" gorm:"not null;index"`
+	ActorID       uint      `json:"actor_id" gorm:"not null;index"` // User who performed the action
+	Action        string    `json:"action" gorm:"not null"`         // unlock, refund, revoke
+	Reason        string    `json:"reason"`
+	PointsDelta   int       `json:"points_delta" gorm:"not null;default:0"` // Points change applied to the user
+	CreatedAt     time.Time `json:"created_at"`
+
+	// Relationships
+	Achievement Achievement `json:"achievement,omitempty" gorm:"foreignKey:AchievementID"`
+}
+
+// AchievementWithStatus represents an achievement as seen by a specific user
+type AchievementWithStatus struct {
+	Achievement
+	Status         string `json:"status"` // locked, available, owned
+	Reason         string `json:"reason,omitempty"`
+	CanAfford      bool   `json:"can_afford"`
+	StockRemaining *int   `json:"stock_remaining,omitempty"`
+}
+
+// Loadout represents the achievements a user currently has equipped
+type Loadout struct {
+	Character *Achievement  `json:"character"`
+	JobTitle  *Achievement  `json:"job_title"`
+	Badges    []Achievement `json:"badges"`
+}
+
+// UpdateLoadoutRequest represents the request to change equipped achievements.
+// Omitted fields are left unchanged, a zero ID unequips the slot.
+type UpdateLoadoutRequest struct {
+	CharacterID *uint  `json:"character_id,omitempty"`
+	JobTitleID  *uint  `json:"job_title_id,omitempty"`
+	BadgeIDs    []uint `json:"badge_ids,omitempty"`
+}
+
+// RefundAchievementRequest represents a self-service refund of a store purchase
+type RefundAchievementRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// RevokeAchievementRequest represents an admin revocation of a user achievement
+type RevokeAchievementRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+	Refund bool   `json:"refund"`
+}
+
 // UnlockAchievementRequest represents the request to unlock an achievement
 type UnlockAchievementRequest struct {
 	UserID        uint `json:"user_id" validate:"required"`
 	AchievementID uint `json:"achievement_id" validate:"required"`
-} 
\ No newline at end of file
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Anti-cheat rules that can raise a flag
+const (
+	AntiCheatRuleCompletionSpeed = "completion_speed"
+	AntiCheatRuleDailyPointSpike = "daily_point_spike"
+	AntiCheatRuleDirectPointEdit = "direct_point_edit"
+)
+
+// Anti-cheat flag review states
+const (
+	FlagStatusOpen      = "open"
+	FlagStatusDismissed = "dismissed"
+	FlagStatusConfirmed = "confirmed"
+)
+
+// AntiCheatFlag records a suspicious pattern for admin review
+type AntiCheatFlag struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Rule        string     `json:"rule" gorm:"not null"`
+	Details     string     `json:"details" gorm:"not null"`
+	DailyTaskID *uint      `json:"daily_task_id,omitempty"`
+	Status      string     `json:"status" gorm:"not null;default:'open';index"` // open, dismissed, confirmed
+	ReviewedBy  *uint      `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote  string     `json:"review_note,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// ReviewFlagRequest represents an admin decision on an anti-cheat flag
+type ReviewFlagRequest struct {
+	Status string `json:"status" validate:"required,oneof=dismissed confirmed"`
+	Note   string `json:"note" validate:"max=1000"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// Health check statuses
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusDegraded    = "degraded"    // An optional dependency is failing
+	HealthStatusUnavailable = "unavailable" // A required dependency is failing
+	HealthStatusFail        = "fail"
+)
+
+// SchemaMigration records a database schema version applied by the backend
+type SchemaMigration struct {
+	Version   int       `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time `json:"applied_at" gorm:"not null"`
+}
+
+// HealthCheckResult is the outcome of checking one dependency
+type HealthCheckResult struct {
+	Status    string  `json:"status"`
+	Required  bool    `json:"required"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport is the readiness of the backend and each of its dependencies
+type HealthReport struct {
+	Status    string                       `json:"status"`
+	Checks    map[string]HealthCheckResult `json:"checks"`
+	CheckedAt time.Time                    `json:"checked_at"`
+}
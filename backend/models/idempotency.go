@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header clients use to make a mutating request safe to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyKey stores the outcome of a request made with an Idempotency-Key header, so a
+// retry with the same key gets the same response instead of repeating the work. Keys are
+// scoped to the user.
+type IdempotencyKey struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Key         string     `json:"key" gorm:"not null;size:255;uniqueIndex:idx_idempotency_user_key"`
+	Method      string     `json:"method" gorm:"not null"`
+	Path        string     `json:"path" gorm:"not null"`
+	Fingerprint string     `json:"-" gorm:"not null"` // SHA-256 of method, path and body
+	StatusCode  int        `json:"status_code"`
+	ContentType string     `json:"-"`
+	Response    []byte     `json:"-"`
+	CompletedAt *time.Time `json:"completed_at"` // Empty while the first request is still running
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null;index"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
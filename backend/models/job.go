@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Job run statuses
+const (
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+// JobRun records one run of a scheduled background job, including its retries
+type JobRun struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Job      string `json:"job" gorm:"not null;index"`
+	Status   string `json:"status" gorm:"not null"` // running, succeeded, failed
+	Attempts int    `json:"attempts" gorm:"not null;default:0"`
+	Error    string `json:"error,omitempty"` // Error of the last failed attempt
+	Instance string `json:"instance"`        // Host that ran the job
+
+	ScheduledFor time.Time  `json:"scheduled_for" gorm:"not null"` // Schedule tick the run belongs to
+	StartedAt    time.Time  `json:"started_at" gorm:"not null;index"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobInfo describes a registered background job
+type JobInfo struct {
+	Name      string    `json:"name"`
+	Schedule  string    `json:"schedule"`
+	NextRunAt time.Time `json:"next_run_at"`
+	LastRun   *JobRun   `json:"last_run,omitempty"`
+}
+
+// LeaderboardSnapshot is a user's leaderboard position at the end of a day (UTC)
+type LeaderboardSnapshot struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Date      string    `json:"date" gorm:"size:10;not null;uniqueIndex:idx_leaderboard_snapshot_date_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_leaderboard_snapshot_date_user;index"`
+	Rank      int       `json:"rank" gorm:"not null"`
+	Points    int       `json:"points" gorm:"not null"`
+	Level     int       `json:"level" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// LocalProvider is the identity provider name for email based logins (magic links and passwords)
+const LocalProvider = "email"
+
+// Email token purposes
+const (
+	EmailTokenMagicLink     = "magic_link"
+	EmailTokenVerifyEmail   = "verify_email"
+	EmailTokenPasswordReset = "password_reset"
+)
+
+// EmailToken is a single-use token sent by email. Only its hash is stored.
+type EmailToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    *uint      `json:"user_id" gorm:"index"` // Empty for magic links to addresses without an account
+	Email     string     `json:"email" gorm:"not null;index"`
+	Purpose   string     `json:"purpose" gorm:"not null"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// EmailRequest represents a request carrying only an email address (magic link, password reset, resend verification)
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// EmailTokenRequest represents a request to redeem an emailed token
+type EmailTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// RegisterRequest represents a sign up with email and password
+type RegisterRequest struct {
+	Email     string `json:"email" validate:"required,email"`
+	Password  string `json:"password" validate:"required,min=10,max=128"`
+	FirstName string `json:"first_name" validate:"max=100"`
+	LastName  string `json:"last_name" validate:"max=100"`
+}
+
+// PasswordLoginRequest represents a login with email and password
+type PasswordLoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// PasswordResetRequest represents setting a new password with a reset token
+type PasswordResetRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=10,max=128"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// Domain event types written to the outbox
+const (
+	EventTaskCompleted       = "task.completed"
+	EventUserLeveledUp       = "user.leveled_up"
+	EventAchievementUnlocked = "achievement.unlocked"
+)
+
+// OutboxEvent is a domain event recorded in the same transaction as the change it describes.
+// The outbox dispatcher delivers it to the registered handlers at least once.
+type OutboxEvent struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Type        string     `json:"type" gorm:"not null;index"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Payload     string     `json:"payload" gorm:"type:jsonb;not null"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	LastError   string     `json:"last_error,omitempty"`
+	AvailableAt time.Time  `json:"available_at" gorm:"not null;index"`  // Not delivered before this time
+	ProcessedAt *time.Time `json:"processed_at,omitempty" gorm:"index"` // Set once every handler succeeded
+	FailedAt    *time.Time `json:"failed_at,omitempty"`                 // Set when delivery was given up
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TaskCompletedEvent is the payload of task.completed, recorded when a completion earns points
+type TaskCompletedEvent struct {
+	UserID      uint      `json:"user_id"`
+	DailyTaskID uint      `json:"daily_task_id"`
+	TaskID      uint      `json:"task_id"`
+	Title       string    `json:"title"`
+	Category    string    `json:"category"`
+	Difficulty  string    `json:"difficulty"`
+	Points      int       `json:"points"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// UserLeveledUpEvent is the payload of user.leveled_up
+type UserLeveledUpEvent struct {
+	UserID        uint   `json:"user_id"`
+	PreviousLevel int    `json:"previous_level"`
+	Level         int    `json:"level"`
+	Points        int    `json:"points"`
+	Character     string `json:"character"`
+}
+
+// AchievementUnlockedEvent is the payload of achievement.unlocked
+type AchievementUnlockedEvent struct {
+	UserID        uint      `json:"user_id"`
+	AchievementID uint      `json:"achievement_id"`
+	Title         string    `json:"title"`
+	Type          string    `json:"type"`
+	PointsSpent   int       `json:"points_spent"`
+	UnlockedAt    time.Time `json:"unlocked_at"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// Session represents a signed-in device. Access tokens carry the session ID so
+// revoking the session invalidates them before they expire.
+type Session struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	UserAgent     string     `json:"user_agent"`
+	IPAddress     string     `json:"ip_address"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Current is set for the session making the request
+	Current bool `json:"current" gorm:"-"`
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken is a single-use token that rotates on every refresh. Presenting a
+// token that was already used means it leaked, and the whole session is revoked.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	SessionID uint       `json:"session_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relationships
+	Session Session `json:"-" gorm:"foreignKey:SessionID"`
+}
+
+// ClientInfo describes the device a session is created from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// RefreshTokenRequest represents a token refresh from non-browser clients
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Session revocation reasons
+const (
+	SessionRevokedLogout    = "logout"
+	SessionRevokedByUser    = "revoked_by_user"
+	SessionRevokedReuse     = "refresh_token_reuse"
+	SessionRevokedAllByUser = "revoked_all_by_user"
+	SessionRevokedPasswordReset = "password_reset"
+)
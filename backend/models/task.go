@@ -1,10 +1,33 @@
 package models
 
 import (
+	"io"
 	"time"
 	"gorm.io/gorm"
 )
 
+// Evidence a task can require when it is completed
+const (
+	EvidenceNone    = "none"
+	EvidenceNote    = "note"
+	EvidenceNumeric = "numeric"
+	EvidencePhoto   = "photo"
+)
+
+// Who reviews a completion before points are awarded
+const (
+	VerificationNone      = "none"
+	VerificationPeer      = "peer"
+	VerificationModerator = "moderator"
+)
+
+// Verification states of a completed daily task
+const (
+	VerificationStatusPending  = "pending"
+	VerificationStatusApproved = "approved"
+	VerificationStatusRejected = "rejected"
+)
+
 type Task struct {
 	ID          uint   `json:"id" gorm:"primaryKey"`
 	Title       string `json:"title" gorm:"not null"`
@@ -13,6 +36,9 @@ type Task struct {
 	Category    string `json:"category" gorm:"not null"` // cardio, strength, flexibility, wellness
 	Difficulty  string `json:"difficulty" gorm:"not null"` // easy, medium, hard
 	Level       int    `json:"level" gorm:"not null;default:1"` // Required user level
+	EvidenceType     string `json:"evidence_type" gorm:"not null;default:'none'"`     // none, note, numeric, photo
+	EvidenceUnit     string `json:"evidence_unit,omitempty"`                          // Unit for numeric evidence, e.g. reps or minutes
+	VerificationMode string `json:"verification_mode" gorm:"not null;default:'none'"` // none, peer, moderator
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
@@ -27,6 +53,21 @@ type DailyTask struct {
 	TaskID      uint      `json:"task_id" gorm:"not null;index"`
 	IsCompleted bool      `json:"is_completed" gorm:"not null;default:false"`
 	Points      int       `json:"points" gorm:"not null"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	AssignedDate string    `json:"assigned_date" gorm:"size:10;index"` // Local date in the user's timezone
+	ExpiredAt   *time.Time `json:"expired_at,omitempty"` // Set when the day ended without the task being completed
+
+	// Completion evidence
+	EvidenceNote     string   `json:"evidence_note,omitempty"`
+	EvidenceValue    *float64 `json:"evidence_value,omitempty"`
+	EvidencePhotoURL string   `json:"evidence_photo_url,omitempty" gorm:"index"`
+
+	// Verification ("" when the task needs none)
+	VerificationStatus string     `json:"verification_status,omitempty" gorm:"index"` // pending, approved, rejected
+	VerifiedBy         *uint      `json:"verified_by,omitempty"`
+	VerifiedAt         *time.Time `json:"verified_at,omitempty"`
+	RejectionReason    string     `json:"rejection_reason,omitempty"`
+
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
@@ -38,7 +79,29 @@ type DailyTask struct {
 
 // UpdateDailyTaskRequest represents the request to update a daily task
 type UpdateDailyTaskRequest struct {
-	IsCompleted *bool `json:"is_completed,omitempty"`
+	IsCompleted        *bool      `json:"is_completed,omitempty"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	EvidenceNote       *string    `json:"evidence_note,omitempty"`
+	EvidenceValue      *float64   `json:"evidence_value,omitempty"`
+	EvidencePhotoURL   *string    `json:"evidence_photo_url,omitempty"`
+	VerificationStatus *string    `json:"verification_status,omitempty"`
+	VerifiedBy         *uint      `json:"verified_by,omitempty"`
+	VerifiedAt         *time.Time `json:"verified_at,omitempty"`
+	RejectionReason    *string    `json:"rejection_reason,omitempty"`
+}
+
+// TaskEvidence represents the proof submitted when completing a task
+type TaskEvidence struct {
+	Note  string   `json:"note" form:"note" validate:"max=1000"`
+	Value *float64 `json:"value" form:"value" validate:"omitempty,gt=0"`
+
+	// Uploaded photo, set from multipart requests only
+	Photo io.Reader `json:"-" form:"-"`
+}
+
+// RejectVerificationRequest represents the request to reject a pending completion
+type RejectVerificationRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
 }
 
 // GenerateDailyTasksRequest represents the request to generate daily tasks
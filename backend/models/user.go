@@ -6,9 +6,16 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// User roles
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	ID           uint      `json:"id" gorm:"primaryKey"`
-	GoogleID     string    `json:"google_id" gorm:"unique;index"`
+	GoogleID     *string   `json:"-" gorm:"unique;index"` // Legacy Google account link, superseded by Identities
 	Email        string    `json:"email" gorm:"not null;unique" validate:"required,email"`
 	Username     string    `json:"username" gorm:"not null;unique" validate:"required,min=3,max=50"`
 	FirstName    string    `json:"first_name"`
@@ -18,6 +25,13 @@ type User struct {
 	Points       int       `json:"points" gorm:"not null;default:0"`
 	Character    string    `json:"character" gorm:"not null;default:'Rookie Hero'"`
 	JobTitle     string    `json:"job_title" gorm:"not null;default:'Fitness Novice'"`
+	Role         string    `json:"role" gorm:"not null;default:'user'"` // user, moderator, admin
+	Timezone     string    `json:"timezone" gorm:"not null;default:'UTC'"` // IANA name; daily tasks roll over at local midnight
+	CurrentStreak int      `json:"current_streak" gorm:"not null;default:0"` // Consecutive local days with a completed task
+	LongestStreak int      `json:"longest_streak" gorm:"not null;default:0"`
+	StreakEvaluatedOn string `json:"-" gorm:"size:10"` // Last local date counted towards the streak
+	PasswordHash string    `json:"-"` // argon2id, empty when the user has no password
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 	IsActive     bool      `json:"is_active" gorm:"default:true"`
 	LastLoginAt  *time.Time `json:"last_login_at"`
 	CreatedAt    time.Time `json:"created_at"`
@@ -27,6 +41,10 @@ type User struct {
 	// Relationships
 	DailyTasks      []DailyTask      `json:"daily_tasks,omitempty" gorm:"foreignKey:UserID"`
 	UserAchievements []UserAchievement `json:"user_achievements,omitempty" gorm:"foreignKey:UserID"`
+	Identities      []UserIdentity   `json:"identities,omitempty" gorm:"foreignKey:UserID"`
+
+	// Equipped achievements, populated by services for profile and leaderboard responses
+	Loadout *Loadout `json:"loadout,omitempty" gorm:"-"`
 }
 
 // CreateUserRequest represents the request payload for creating a user
@@ -35,7 +53,20 @@ type CreateUserRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 }
 
-// UpdateUserRequest represents the request payload for updating a user
+// UpdateProfileRequest represents the fields a user may change on their own profile.
+// Points, level, character and job title are managed by the game and cannot be set here.
+type UpdateProfileRequest struct {
+	Username  *string `json:"username,omitempty" validate:"omitempty,min=3,max=50"`
+	Email     *string `json:"email,omitempty" validate:"omitempty,email"`
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+	Timezone  *string `json:"timezone,omitempty"`
+}
+
+// ProtectedProfileFields lists request fields that users are not allowed to set directly
+var ProtectedProfileFields = []string{"points", "level", "character", "job_title"}
+
+// UpdateUserRequest represents an internal update of user fields by the services
 type UpdateUserRequest struct {
 	Username  *string `json:"username,omitempty" validate:"omitempty,min=3,max=50"`
 	Email     *string `json:"email,omitempty" validate:"omitempty,email"`
@@ -45,28 +76,23 @@ type UpdateUserRequest struct {
 	Points    *int    `json:"points,omitempty" validate:"omitempty,min=0"`
 	Character *string `json:"character,omitempty"`
 	JobTitle  *string `json:"job_title,omitempty"`
-}
-
-// GoogleUserInfo represents the user info returned from Google OAuth
-type GoogleUserInfo struct {
-	ID         string `json:"id"`
-	Email      string `json:"email"`
-	Name       string `json:"name"`
-	GivenName  string `json:"given_name"`
-	FamilyName string `json:"family_name"`
-	Picture    string `json:"picture"`
+	Timezone  *string `json:"timezone,omitempty"`
 }
 
 // AuthResponse represents the authentication response
 type AuthResponse struct {
-	User  User   `json:"user"`
-	Token string `json:"token"`
+	User         User      `json:"user"`
+	Token        string    `json:"token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"-"`
+	SessionID    uint      `json:"session_id"`
 }
 
 // JWTClaims represents the JWT token claims
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Email    string `json:"email"`
-	Username string `json:"username"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	SessionID uint   `json:"sid"`
 	jwt.RegisteredClaims
 } 
\ No newline at end of file
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// UsernameHistory records a username a user gave up. Previous usernames stay reserved for
+// their owner so public profile links keep redirecting to the current username.
+type UsernameHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Username  string    `json:"username" gorm:"not null;uniqueIndex"` // Lowercase
+	ChangedAt time.Time `json:"changed_at" gorm:"not null;index"`
+}
+
+// ChangeUsernameRequest represents the request payload for changing the current user's username
+type ChangeUsernameRequest struct {
+	Username string `json:"username" validate:"required"`
+}
+
+// PublicProfile is the part of a user's profile anyone can see
+type PublicProfile struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"`
+	Picture   string    `json:"picture"`
+	Level     int       `json:"level"`
+	Points    int       `json:"points"`
+	Character string    `json:"character"`
+	JobTitle  string    `json:"job_title"`
+	Loadout   *Loadout  `json:"loadout,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+)
+
+// WebhookSecretPrefix marks generated webhook signing secrets
+const WebhookSecretPrefix = "whsec_"
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSubscription sends the events it subscribes to as signed POST requests to a URL
+type WebhookSubscription struct {
+	ID                  uint       `json:"id" gorm:"primaryKey"`
+	URL                 string     `json:"url" gorm:"not null"`
+	Events              []string   `json:"events" gorm:"serializer:json;not null"`
+	Secret              string     `json:"-" gorm:"not null"` // HMAC-SHA256 signing key, shown once on creation
+	Description         string     `json:"description"`
+	IsActive            bool       `json:"is_active" gorm:"not null;default:true"`
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"not null;default:0"` // Failed attempts since the last success
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	CreatedBy           uint       `json:"created_by" gorm:"not null"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Subscribes reports whether the subscription wants events of the given type
+func (w *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, event := range w.Events {
+		if event == "*" || event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent to one subscription, retried until it succeeds or runs out
+// of attempts. Redeliveries are recorded as new deliveries of the same event.
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"not null;index;uniqueIndex:idx_webhook_delivery_event,where:redelivery_of IS NULL"`
+	EventID        uint       `json:"event_id" gorm:"not null;uniqueIndex:idx_webhook_delivery_event,where:redelivery_of IS NULL"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"type:jsonb;not null"` // Request body, identical for every attempt
+	RedeliveryOf   *uint      `json:"redelivery_of,omitempty"`
+	Status         string     `json:"status" gorm:"not null;index"` // pending, succeeded, failed
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"not null;index"`
+	ResponseStatus int        `json:"response_status,omitempty"` // HTTP status of the last attempt
+	ResponseBody   string     `json:"response_body,omitempty"`   // Start of the last response body
+	Error          string     `json:"error,omitempty"`           // Why the last attempt failed
+	DurationMs     int64      `json:"duration_ms"`               // Duration of the last attempt
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// WebhookPayload is the JSON body of every webhook request
+type WebhookPayload struct {
+	ID        uint        `json:"id"` // Outbox event ID, the same for redeliveries
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// CreateWebhookRequest represents the request payload for creating a webhook subscription
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url,max=2048"`
+	Events      []string `json:"events" validate:"required,min=1,dive,oneof=* task.completed user.leveled_up achievement.unlocked"`
+	Secret      string   `json:"secret" validate:"omitempty,min=16,max=255"` // Generated when empty
+	Description string   `json:"description" validate:"max=255"`
+}
+
+// CreateWebhookResponse returns the new subscription and its signing secret, which cannot be retrieved again
+type CreateWebhookResponse struct {
+	Secret  string              `json:"secret"`
+	Webhook WebhookSubscription `json:"webhook"`
+}
+
+// UpdateWebhookRequest represents the changes to a webhook subscription. Enabling a disabled
+// subscription resets its failure count.
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url,omitempty" validate:"omitempty,url,max=2048"`
+	Events      []string `json:"events,omitempty" validate:"omitempty,min=1,dive,oneof=* task.completed user.leveled_up achievement.unlocked"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=255"`
+	IsActive    *bool    `json:"is_active,omitempty"`
+}
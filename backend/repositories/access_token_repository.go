@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+)
+
+type AccessTokenRepositoryInterface interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken) error
+	GetByID(ctx context.Context, id uint) (*models.PersonalAccessToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error)
+	GetActiveByUserID(ctx context.Context, userID uint) ([]models.PersonalAccessToken, error)
+	Touch(ctx context.Context, id uint, lastUsedAt time.Time, ip string) error
+	Revoke(ctx context.Context, id uint) error
+}
+
+type AccessTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewAccessTokenRepository creates a new personal access token repository
+func NewAccessTokenRepository(db *gorm.DB) AccessTokenRepositoryInterface {
+	return &AccessTokenRepository{db: db}
+}
+
+// Create stores a new personal access token
+func (r *AccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	return dbFor(ctx, r.db).Create(token).Error
+}
+
+// GetByID retrieves a personal access token by ID
+func (r *AccessTokenRepository) GetByID(ctx context.Context, id uint) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := dbFor(ctx, r.db).First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetByHash retrieves a personal access token by token hash
+func (r *AccessTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := dbFor(ctx, r.db).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetActiveByUserID retrieves unrevoked, unexpired tokens for a user, newest first
+func (r *AccessTokenRepository) GetActiveByUserID(ctx context.Context, userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := dbFor(ctx, r.db).Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// Touch records that a token was used
+func (r *AccessTokenRepository) Touch(ctx context.Context, id uint, lastUsedAt time.Time, ip string) error {
+	return dbFor(ctx, r.db).Model(&models.PersonalAccessToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": lastUsedAt,
+		"last_used_ip": ip,
+	}).Error
+}
+
+// Revoke revokes a token
+func (r *AccessTokenRepository) Revoke(ctx context.Context, id uint) error {
+	return dbFor(ctx, r.db).Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
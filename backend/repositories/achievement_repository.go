@@ -1,19 +1,34 @@
 package repositories
 
 import (
+	"context"
 	"fithero-backend/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AchievementRepositoryInterface interface {
-	GetAll() ([]models.Achievement, error)
-	GetByID(id uint) (*models.Achievement, error)
+	GetAll(ctx context.Context) ([]models.Achievement, error)
+	GetByID(ctx context.Context, id uint) (*models.Achievement, error)
+	LockForUpdate(ctx context.Context, id uint) error
 	
 	// User Achievements
-	CreateUserAchievement(userAchievement *models.UserAchievement) (*models.UserAchievement, error)
-	GetUserAchievements(userID uint) ([]models.UserAchievement, error)
-	GetUserAchievementByUserAndAchievement(userID, achievementID uint) (*models.UserAchievement, error)
-	IsAchievementUnlocked(userID, achievementID uint) (bool, error)
+	CreateUserAchievement(ctx context.Context, userAchievement *models.UserAchievement) (*models.UserAchievement, error)
+	GetUserAchievements(ctx context.Context, userID uint) ([]models.UserAchievement, error)
+	GetUserAchievementByUserAndAchievement(ctx context.Context, userID, achievementID uint) (*models.UserAchievement, error)
+	IsAchievementUnlocked(ctx context.Context, userID, achievementID uint) (bool, error)
+	GetOwnedDependents(ctx context.Context, userID, achievementID uint) ([]models.UserAchievement, error)
+	DeleteUserAchievement(ctx context.Context, id uint) error
+	CountUnlocks(ctx context.Context, achievementID uint) (int64, error)
+	CountUnlocksByAchievement(ctx context.Context) (map[uint]int64, error)
+
+	// Loadout
+	GetEquippedAchievements(ctx context.Context, userIDs []uint) ([]models.UserAchievement, error)
+	ReplaceEquipped(ctx context.Context, userID uint, achievementType string, achievementIDs []uint) error
+
+	// Audit trail
+	CreateAuditLog(ctx context.Context, entry *models.AchievementAuditLog) error
+	GetAuditLogs(ctx context.Context, userID uint) ([]models.AchievementAuditLog, error)
 }
 
 type AchievementRepository struct {
@@ -26,47 +41,53 @@ func NewAchievementRepository(db *gorm.DB) AchievementRepositoryInterface {
 }
 
 // GetAll retrieves all achievements
-func (r *AchievementRepository) GetAll() ([]models.Achievement, error) {
+func (r *AchievementRepository) GetAll(ctx context.Context) ([]models.Achievement, error) {
 	var achievements []models.Achievement
-	err := r.db.Find(&achievements).Error
+	err := dbFor(ctx, r.db).Preload("Prerequisites").Find(&achievements).Error
 	return achievements, err
 }
 
 // GetByID retrieves an achievement by ID
-func (r *AchievementRepository) GetByID(id uint) (*models.Achievement, error) {
+func (r *AchievementRepository) GetByID(ctx context.Context, id uint) (*models.Achievement, error) {
 	var achievement models.Achievement
-	err := r.db.First(&achievement, id).Error
+	err := dbFor(ctx, r.db).Preload("Prerequisites").First(&achievement, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &achievement, nil
 }
 
+// LockForUpdate locks an achievement row until the surrounding transaction ends
+func (r *AchievementRepository) LockForUpdate(ctx context.Context, id uint) error {
+	var achievement models.Achievement
+	return dbFor(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&achievement, id).Error
+}
+
 // CreateUserAchievement creates a new user achievement
-func (r *AchievementRepository) CreateUserAchievement(userAchievement *models.UserAchievement) (*models.UserAchievement, error) {
-	if err := r.db.Create(userAchievement).Error; err != nil {
+func (r *AchievementRepository) CreateUserAchievement(ctx context.Context, userAchievement *models.UserAchievement) (*models.UserAchievement, error) {
+	if err := dbFor(ctx, r.db).Create(userAchievement).Error; err != nil {
 		return nil, err
 	}
 	// Load the achievement relationship
-	if err := r.db.Preload("Achievement").First(userAchievement, userAchievement.ID).Error; err != nil {
+	if err := dbFor(ctx, r.db).Preload("Achievement").First(userAchievement, userAchievement.ID).Error; err != nil {
 		return nil, err
 	}
 	return userAchievement, nil
 }
 
 // GetUserAchievements retrieves all achievements for a user
-func (r *AchievementRepository) GetUserAchievements(userID uint) ([]models.UserAchievement, error) {
+func (r *AchievementRepository) GetUserAchievements(ctx context.Context, userID uint) ([]models.UserAchievement, error) {
 	var userAchievements []models.UserAchievement
-	err := r.db.Preload("Achievement").
+	err := dbFor(ctx, r.db).Preload("Achievement").
 		Where("user_id = ?", userID).
 		Find(&userAchievements).Error
 	return userAchievements, err
 }
 
 // GetUserAchievementByUserAndAchievement retrieves a specific user achievement
-func (r *AchievementRepository) GetUserAchievementByUserAndAchievement(userID, achievementID uint) (*models.UserAchievement, error) {
+func (r *AchievementRepository) GetUserAchievementByUserAndAchievement(ctx context.Context, userID, achievementID uint) (*models.UserAchievement, error) {
 	var userAchievement models.UserAchievement
-	err := r.db.Preload("Achievement").
+	err := dbFor(ctx, r.db).Preload("Achievement").
 		Where("user_id = ? AND achievement_id = ?", userID, achievementID).
 		First(&userAchievement).Error
 	if err != nil {
@@ -76,10 +97,115 @@ func (r *AchievementRepository) GetUserAchievementByUserAndAchievement(userID, a
 }
 
 // IsAchievementUnlocked checks if a user has unlocked a specific achievement
-func (r *AchievementRepository) IsAchievementUnlocked(userID, achievementID uint) (bool, error) {
+func (r *AchievementRepository) IsAchievementUnlocked(ctx context.Context, userID, achievementID uint) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.UserAchievement{}).
+	err := dbFor(ctx, r.db).Model(&models.UserAchievement{}).
 		Where("user_id = ? AND achievement_id = ?", userID, achievementID).
 		Count(&count).Error
 	return count > 0, err
-} 
\ No newline at end of file
+}
+
+// GetOwnedDependents retrieves the user's achievements that have the given achievement as a prerequisite
+func (r *AchievementRepository) GetOwnedDependents(ctx context.Context, userID, achievementID uint) ([]models.UserAchievement, error) {
+	var userAchievements []models.UserAchievement
+	db := dbFor(ctx, r.db)
+	dependents := db.Table("achievement_prerequisites").Select("achievement_id").Where("prerequisite_id = ?", achievementID)
+	err := db.Preload("Achievement").
+		Where("user_id = ? AND achievement_id IN (?)", userID, dependents).
+		Order("id").
+		Find(&userAchievements).Error
+	return userAchievements, err
+}
+
+// DeleteUserAchievement permanently removes a user achievement so it can be unlocked again.
+// It returns gorm.ErrRecordNotFound when the achievement was already removed.
+func (r *AchievementRepository) DeleteUserAchievement(ctx context.Context, id uint) error {
+	result := dbFor(ctx, r.db).Unscoped().Delete(&models.UserAchievement{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountUnlocks counts how many users have unlocked a specific achievement
+func (r *AchievementRepository) CountUnlocks(ctx context.Context, achievementID uint) (int64, error) {
+	var count int64
+	err := dbFor(ctx, r.db).Model(&models.UserAchievement{}).
+		Where("achievement_id = ?", achievementID).
+		Count(&count).Error
+	return count, err
+}
+
+// CountUnlocksByAchievement counts unlocks for every achievement keyed by achievement ID
+func (r *AchievementRepository) CountUnlocksByAchievement(ctx context.Context) (map[uint]int64, error) {
+	var rows []struct {
+		AchievementID uint
+		Count         int64
+	}
+	err := dbFor(ctx, r.db).Model(&models.UserAchievement{}).
+		Select("achievement_id, COUNT(*) AS count").
+		Group("achievement_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.AchievementID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetEquippedAchievements retrieves equipped achievements for the given users ordered by slot
+func (r *AchievementRepository) GetEquippedAchievements(ctx context.Context, userIDs []uint) ([]models.UserAchievement, error) {
+	var userAchievements []models.UserAchievement
+	if len(userIDs) == 0 {
+		return userAchievements, nil
+	}
+	err := dbFor(ctx, r.db).Preload("Achievement").
+		Where("user_id IN ? AND is_equipped = ?", userIDs, true).
+		Order("slot_order ASC").
+		Find(&userAchievements).Error
+	return userAchievements, err
+}
+
+// ReplaceEquipped unequips every owned achievement of a type and equips the given ones in order
+func (r *AchievementRepository) ReplaceEquipped(ctx context.Context, userID uint, achievementType string, achievementIDs []uint) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		ofType := tx.Model(&models.Achievement{}).Select("id").Where("type = ?", achievementType)
+		if err := tx.Model(&models.UserAchievement{}).
+			Where("user_id = ? AND achievement_id IN (?)", userID, ofType).
+			Updates(map[string]interface{}{"is_equipped": false, "slot_order": 0}).Error; err != nil {
+			return err
+		}
+
+		for i, achievementID := range achievementIDs {
+			if err := tx.Model(&models.UserAchievement{}).
+				Where("user_id = ? AND achievement_id = ?", userID, achievementID).
+				Updates(map[string]interface{}{"is_equipped": true, "slot_order": i}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateAuditLog records an achievement audit entry
+func (r *AchievementRepository) CreateAuditLog(ctx context.Context, entry *models.AchievementAuditLog) error {
+	return dbFor(ctx, r.db).Create(entry).Error
+}
+
+// GetAuditLogs retrieves audit entries newest first, for a single user when userID is non-zero
+func (r *AchievementRepository) GetAuditLogs(ctx context.Context, userID uint) ([]models.AchievementAuditLog, error) {
+	var entries []models.AchievementAuditLog
+	query := dbFor(ctx, r.db).Preload("Achievement").Order("created_at DESC")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	err := query.Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+)
+
+type AntiCheatRepositoryInterface interface {
+	CreateFlag(ctx context.Context, flag *models.AntiCheatFlag) error
+	GetFlags(ctx context.Context, status string) ([]models.AntiCheatFlag, error)
+	GetFlagByID(ctx context.Context, id uint) (*models.AntiCheatFlag, error)
+	ReviewFlag(ctx context.Context, flag *models.AntiCheatFlag) error
+	HasFlagSince(ctx context.Context, userID uint, rule string, since time.Time) (bool, error)
+}
+
+type AntiCheatRepository struct {
+	db *gorm.DB
+}
+
+// NewAntiCheatRepository creates a new anti-cheat repository
+func NewAntiCheatRepository(db *gorm.DB) AntiCheatRepositoryInterface {
+	return &AntiCheatRepository{db: db}
+}
+
+// CreateFlag stores a new anti-cheat flag
+func (r *AntiCheatRepository) CreateFlag(ctx context.Context, flag *models.AntiCheatFlag) error {
+	return dbFor(ctx, r.db).Create(flag).Error
+}
+
+// GetFlags retrieves flags oldest first, filtered by status when non-empty
+func (r *AntiCheatRepository) GetFlags(ctx context.Context, status string) ([]models.AntiCheatFlag, error) {
+	var flags []models.AntiCheatFlag
+	query := dbFor(ctx, r.db).Preload("User").Order("created_at ASC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&flags).Error
+	return flags, err
+}
+
+// GetFlagByID retrieves a flag by ID
+func (r *AntiCheatRepository) GetFlagByID(ctx context.Context, id uint) (*models.AntiCheatFlag, error) {
+	var flag models.AntiCheatFlag
+	if err := dbFor(ctx, r.db).Preload("User").First(&flag, id).Error; err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// ReviewFlag saves the review fields of a flag that is still open. It returns
+// gorm.ErrRecordNotFound when the flag was already reviewed, so a flag is decided at most once.
+func (r *AntiCheatRepository) ReviewFlag(ctx context.Context, flag *models.AntiCheatFlag) error {
+	result := dbFor(ctx, r.db).Model(&models.AntiCheatFlag{}).
+		Where("id = ? AND status = ?", flag.ID, models.FlagStatusOpen).
+		Updates(map[string]interface{}{
+			"status":      flag.Status,
+			"reviewed_by": flag.ReviewedBy,
+			"reviewed_at": flag.ReviewedAt,
+			"review_note": flag.ReviewNote,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// HasFlagSince checks if a user was already flagged for a rule since the given time
+func (r *AntiCheatRepository) HasFlagSince(ctx context.Context, userID uint, rule string, since time.Time) (bool, error) {
+	var count int64
+	err := dbFor(ctx, r.db).Model(&models.AntiCheatFlag{}).
+		Where("user_id = ? AND rule = ? AND created_at >= ?", userID, rule, since).
+		Count(&count).Error
+	return count > 0, err
+}
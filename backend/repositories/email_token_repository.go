@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+)
+
+type EmailTokenRepositoryInterface interface {
+	Create(ctx context.Context, token *models.EmailToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.EmailToken, error)
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	InvalidateForEmail(ctx context.Context, email, purpose string, usedAt time.Time) error
+}
+
+type EmailTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailTokenRepository creates a new email token repository
+func NewEmailTokenRepository(db *gorm.DB) EmailTokenRepositoryInterface {
+	return &EmailTokenRepository{db: db}
+}
+
+// Create stores a new email token
+func (r *EmailTokenRepository) Create(ctx context.Context, token *models.EmailToken) error {
+	return dbFor(ctx, r.db).Create(token).Error
+}
+
+// GetByHash retrieves an email token by token hash
+func (r *EmailTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.EmailToken, error) {
+	var token models.EmailToken
+	if err := dbFor(ctx, r.db).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed atomically marks a token as used. It returns false when the token had already been used.
+func (r *EmailTokenRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := dbFor(ctx, r.db).Model(&models.EmailToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateForEmail marks all unused tokens of a purpose for an address as used,
+// so only the most recently sent link works
+func (r *EmailTokenRepository) InvalidateForEmail(ctx context.Context, email, purpose string, usedAt time.Time) error {
+	return dbFor(ctx, r.db).Model(&models.EmailToken{}).
+		Where("email = ? AND purpose = ? AND used_at IS NULL", email, purpose).
+		Update("used_at", usedAt).Error
+}
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+)
+
+type HealthRepositoryInterface interface {
+	Ping(ctx context.Context) error
+	GetSchemaVersion(ctx context.Context) (int, error)
+}
+
+type HealthRepository struct {
+	db *gorm.DB
+}
+
+// NewHealthRepository creates a new health repository
+func NewHealthRepository(db *gorm.DB) HealthRepositoryInterface {
+	return &HealthRepository{db: db}
+}
+
+// Ping checks that a database connection can be used
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// GetSchemaVersion returns the latest applied schema version, or 0 if none is recorded
+func (r *HealthRepository) GetSchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := dbFor(ctx, r.db).Model(&models.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
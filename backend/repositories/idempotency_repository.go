@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepositoryInterface interface {
+	Create(ctx context.Context, record *models.IdempotencyKey) (bool, error)
+	GetByUserKey(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, id uint, statusCode int, contentType string, response []byte, completedAt time.Time) error
+	Delete(ctx context.Context, id uint) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency key repository
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepositoryInterface {
+	return &IdempotencyRepository{db: db}
+}
+
+// Create stores a new idempotency key. It returns false when the user already has a record for the key.
+func (r *IdempotencyRepository) Create(ctx context.Context, record *models.IdempotencyKey) (bool, error) {
+	result := dbFor(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetByUserKey retrieves a user's record for an idempotency key
+func (r *IdempotencyRepository) GetByUserKey(ctx context.Context, userID uint, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := dbFor(ctx, r.db).Where("user_id = ? AND key = ?", userID, key).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete stores the response of the request that created the record
+func (r *IdempotencyRepository) Complete(ctx context.Context, id uint, statusCode int, contentType string, response []byte, completedAt time.Time) error {
+	return dbFor(ctx, r.db).Model(&models.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"response":     response,
+		"completed_at": completedAt,
+	}).Error
+}
+
+// Delete removes a record so the key can be used again
+func (r *IdempotencyRepository) Delete(ctx context.Context, id uint) error {
+	return dbFor(ctx, r.db).Delete(&models.IdempotencyKey{}, id).Error
+}
+
+// DeleteExpired removes expired records and returns how many were removed
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := dbFor(ctx, r.db).Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+)
+
+type IdentityRepositoryInterface interface {
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) (*models.User, error)
+	GetByID(ctx context.Context, id uint) (*models.UserIdentity, error)
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	GetByUserID(ctx context.Context, userID uint) ([]models.UserIdentity, error)
+	Touch(ctx context.Context, id uint, email string, lastLoginAt time.Time) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository creates a new linked identity repository
+func NewIdentityRepository(db *gorm.DB) IdentityRepositoryInterface {
+	return &IdentityRepository{db: db}
+}
+
+// Create links an identity to an existing user
+func (r *IdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return dbFor(ctx, r.db).Create(identity).Error
+}
+
+// CreateUserWithIdentity creates a new user together with their first linked identity
+func (r *IdentityRepository) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) (*models.User, error) {
+	err := dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetByID retrieves a linked identity by ID
+func (r *IdentityRepository) GetByID(ctx context.Context, id uint) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := dbFor(ctx, r.db).First(&identity, id).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetByProviderSubject retrieves the identity for a provider account
+func (r *IdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := dbFor(ctx, r.db).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetByUserID retrieves all identities linked to a user
+func (r *IdentityRepository) GetByUserID(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	if err := dbFor(ctx, r.db).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// Touch records a login through the identity and the email the provider reported
+func (r *IdentityRepository) Touch(ctx context.Context, id uint, email string, lastLoginAt time.Time) error {
+	return dbFor(ctx, r.db).Model(&models.UserIdentity{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":         email,
+		"last_login_at": lastLoginAt,
+	}).Error
+}
+
+// Delete unlinks an identity
+func (r *IdentityRepository) Delete(ctx context.Context, id uint) error {
+	return dbFor(ctx, r.db).Delete(&models.UserIdentity{}, id).Error
+}
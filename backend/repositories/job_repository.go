@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"hash/fnv"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+)
+
+type JobRepositoryInterface interface {
+	CreateRun(ctx context.Context, run *models.JobRun) error
+	UpdateRun(ctx context.Context, run *models.JobRun) error
+	GetLastRun(ctx context.Context, job string) (*models.JobRun, error)
+	ListRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error)
+
+	// TryLock takes the cluster-wide lock for a job without waiting. When the lock is taken the
+	// returned unlock function must be called to release it.
+	TryLock(ctx context.Context, job string) (unlock func(), locked bool, err error)
+}
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+// NewJobRepository creates a new job repository
+func NewJobRepository(db *gorm.DB) JobRepositoryInterface {
+	return &JobRepository{db: db}
+}
+
+// CreateRun records the start of a job run
+func (r *JobRepository) CreateRun(ctx context.Context, run *models.JobRun) error {
+	return dbFor(ctx, r.db).Create(run).Error
+}
+
+// UpdateRun saves the outcome of a job run
+func (r *JobRepository) UpdateRun(ctx context.Context, run *models.JobRun) error {
+	return dbFor(ctx, r.db).Save(run).Error
+}
+
+// GetLastRun returns the most recent run of a job
+func (r *JobRepository) GetLastRun(ctx context.Context, job string) (*models.JobRun, error) {
+	var run models.JobRun
+	if err := dbFor(ctx, r.db).Where("job = ?", job).Order("started_at DESC").First(&run).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns returns the most recent runs, newest first, of one job or of all jobs when job is empty
+func (r *JobRepository) ListRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	var runs []models.JobRun
+	query := dbFor(ctx, r.db).Order("started_at DESC").Limit(limit)
+	if job != "" {
+		query = query.Where("job = ?", job)
+	}
+	err := query.Find(&runs).Error
+	return runs, err
+}
+
+// TryLock takes a Postgres session-level advisory lock on a dedicated connection. The lock is
+// released when the unlock function runs or, should the process die, when the connection drops.
+func (r *JobRepository) TryLock(ctx context.Context, job string) (func(), bool, error) {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := jobLockKey(job)
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil || !locked {
+		conn.Close()
+		return nil, false, err
+	}
+
+	unlock := func() {
+		// The job's context may already be cancelled, but the lock must still be released
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			// Discard the connection instead of returning it to the pool, which ends the
+			// session and with it the lock
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+// jobLockKey maps a job name to an advisory lock key
+func jobLockKey(job string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("fithero:job:" + job))
+	return int64(h.Sum64())
+}
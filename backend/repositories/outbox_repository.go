@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepositoryInterface interface {
+	Create(ctx context.Context, event *models.OutboxEvent) error
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error)
+	MarkProcessed(ctx context.Context, id uint, processedAt time.Time) error
+	MarkFailed(ctx context.Context, id uint, lastError string, retryAt time.Time) error
+	MarkGivenUp(ctx context.Context, id uint, lastError string, failedAt time.Time) error
+	DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB) OutboxRepositoryInterface {
+	return &OutboxRepository{db: db}
+}
+
+// Create records an event. Called with a transaction's context, the event is only stored if
+// the transaction commits.
+func (r *OutboxRepository) Create(ctx context.Context, event *models.OutboxEvent) error {
+	return dbFor(ctx, r.db).Create(event).Error
+}
+
+// ClaimDue leases up to limit undelivered events that are due, oldest first, by moving their
+// availability to leaseUntil and counting the attempt. Events claimed by another dispatcher
+// are skipped; events whose dispatcher died become due again when the lease runs out.
+func (r *OutboxRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	db := dbFor(ctx, r.db)
+	due := db.Model(&models.OutboxEvent{}).Select("id").
+		Where("processed_at IS NULL AND failed_at IS NULL AND available_at <= ?", now).
+		Order("id").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+	err := db.Model(&events).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Updates(map[string]interface{}{
+			"available_at": leaseUntil,
+			"attempts":     gorm.Expr("attempts + 1"),
+		}).Error
+	return events, err
+}
+
+// MarkProcessed records that every handler received an event
+func (r *OutboxRepository) MarkProcessed(ctx context.Context, id uint, processedAt time.Time) error {
+	return dbFor(ctx, r.db).Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"processed_at": processedAt,
+		"last_error":   "",
+	}).Error
+}
+
+// MarkFailed records a failed delivery and schedules the next attempt
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uint, lastError string, retryAt time.Time) error {
+	return dbFor(ctx, r.db).Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_error":   lastError,
+		"available_at": retryAt,
+	}).Error
+}
+
+// MarkGivenUp records that an event will not be delivered again
+func (r *OutboxRepository) MarkGivenUp(ctx context.Context, id uint, lastError string, failedAt time.Time) error {
+	return dbFor(ctx, r.db).Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_error": lastError,
+		"failed_at":  failedAt,
+	}).Error
+}
+
+// DeleteProcessedBefore deletes events delivered before the given time
+func (r *OutboxRepository) DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := dbFor(ctx, r.db).Where("processed_at < ?", before).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepositoryInterface interface {
+	Create(ctx context.Context, session *models.Session) (*models.Session, error)
+	GetByID(ctx context.Context, id uint) (*models.Session, error)
+	GetActiveByUserID(ctx context.Context, userID uint) ([]models.Session, error)
+	Touch(ctx context.Context, id uint, lastUsedAt time.Time) error
+	Revoke(ctx context.Context, id uint, reason string) error
+	RevokeAllForUser(ctx context.Context, userID uint, exceptID uint, reason string) error
+
+	// Refresh tokens
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+}
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *gorm.DB) SessionRepositoryInterface {
+	return &SessionRepository{db: db}
+}
+
+// Create creates a new session
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) (*models.Session, error) {
+	if err := dbFor(ctx, r.db).Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetByID retrieves a session by ID
+func (r *SessionRepository) GetByID(ctx context.Context, id uint) (*models.Session, error) {
+	var session models.Session
+	if err := dbFor(ctx, r.db).First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveByUserID retrieves unrevoked, unexpired sessions for a user, most recently used first
+func (r *SessionRepository) GetActiveByUserID(ctx context.Context, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := dbFor(ctx, r.db).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch records that a session was used
+func (r *SessionRepository) Touch(ctx context.Context, id uint, lastUsedAt time.Time) error {
+	return dbFor(ctx, r.db).Model(&models.Session{}).Where("id = ?", id).Update("last_used_at", lastUsedAt).Error
+}
+
+// Revoke revokes a single session
+func (r *SessionRepository) Revoke(ctx context.Context, id uint, reason string) error {
+	return dbFor(ctx, r.db).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// RevokeAllForUser revokes every active session of a user, except exceptID when non-zero
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID uint, exceptID uint, reason string) error {
+	query := dbFor(ctx, r.db).Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptID != 0 {
+		query = query.Where("id <> ?", exceptID)
+	}
+	return query.Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *SessionRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return dbFor(ctx, r.db).Create(token).Error
+}
+
+// GetRefreshTokenByHash retrieves a refresh token and its session by token hash
+func (r *SessionRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := dbFor(ctx, r.db).Preload("Session").Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed atomically marks a token as used. It returns false when the
+// token had already been used, which indicates reuse.
+func (r *SessionRepository) MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := dbFor(ctx, r.db).Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -1,20 +1,33 @@
 package repositories
 
 import (
+	"context"
+	"time"
+
 	"fithero-backend/models"
 	"gorm.io/gorm"
 )
 
 type TaskRepositoryInterface interface {
-	GetAll() ([]models.Task, error)
-	GetByID(id uint) (*models.Task, error)
-	GetTasksByLevel(level int) ([]models.Task, error)
+	GetAll(ctx context.Context) ([]models.Task, error)
+	GetByID(ctx context.Context, id uint) (*models.Task, error)
+	GetTasksByLevel(ctx context.Context, level int) ([]models.Task, error)
 	
 	// Daily Tasks
-	CreateDailyTask(dailyTask *models.DailyTask) (*models.DailyTask, error)
-	GetDailyTasksByUserID(userID uint) ([]models.DailyTask, error)
-	GetDailyTaskByID(id uint) (*models.DailyTask, error)
-	UpdateDailyTask(id uint, updates *models.UpdateDailyTaskRequest) error
+	CreateDailyTask(ctx context.Context, dailyTask *models.DailyTask) (*models.DailyTask, error)
+	GetDailyTasksByUserID(ctx context.Context, userID uint) ([]models.DailyTask, error)
+	GetDailyTasksForDate(ctx context.Context, userID uint, date string) ([]models.DailyTask, error)
+	GetDailyTaskByID(ctx context.Context, id uint) (*models.DailyTask, error)
+	GetDailyTaskByEvidencePhoto(ctx context.Context, photoURL string) (*models.DailyTask, error)
+	UpdateDailyTask(ctx context.Context, id uint, updates *models.UpdateDailyTaskRequest) error
+	CompleteDailyTask(ctx context.Context, id uint, updates *models.UpdateDailyTaskRequest) error
+	ReviewDailyTask(ctx context.Context, id uint, updates *models.UpdateDailyTaskRequest) error
+	GetCompletedSince(ctx context.Context, userID uint, since time.Time) ([]models.DailyTask, error)
+	GetCompletedDates(ctx context.Context, userID uint, after, through string) ([]string, error)
+	ExpireDailyTasks(ctx context.Context, timezone, before string, expiredAt time.Time) (int64, error)
+
+	// Verification
+	GetPendingVerifications(ctx context.Context, excludeUserID uint, modes []string) ([]models.DailyTask, error)
 }
 
 type TaskRepository struct {
@@ -27,16 +40,16 @@ func NewTaskRepository(db *gorm.DB) TaskRepositoryInterface {
 }
 
 // GetAll retrieves all tasks
-func (r *TaskRepository) GetAll() ([]models.Task, error) {
+func (r *TaskRepository) GetAll(ctx context.Context) ([]models.Task, error) {
 	var tasks []models.Task
-	err := r.db.Find(&tasks).Error
+	err := dbFor(ctx, r.db).Find(&tasks).Error
 	return tasks, err
 }
 
 // GetByID retrieves a task by ID
-func (r *TaskRepository) GetByID(id uint) (*models.Task, error) {
+func (r *TaskRepository) GetByID(ctx context.Context, id uint) (*models.Task, error) {
 	var task models.Task
-	err := r.db.First(&task, id).Error
+	err := dbFor(ctx, r.db).First(&task, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -44,38 +57,57 @@ func (r *TaskRepository) GetByID(id uint) (*models.Task, error) {
 }
 
 // GetTasksByLevel retrieves tasks suitable for a specific user level
-func (r *TaskRepository) GetTasksByLevel(level int) ([]models.Task, error) {
+func (r *TaskRepository) GetTasksByLevel(ctx context.Context, level int) ([]models.Task, error) {
 	var tasks []models.Task
 	// Get tasks where required level is less than or equal to user level
-	err := r.db.Where("level <= ?", level).Find(&tasks).Error
+	err := dbFor(ctx, r.db).Where("level <= ?", level).Find(&tasks).Error
 	return tasks, err
 }
 
 // CreateDailyTask creates a new daily task
-func (r *TaskRepository) CreateDailyTask(dailyTask *models.DailyTask) (*models.DailyTask, error) {
-	if err := r.db.Create(dailyTask).Error; err != nil {
+func (r *TaskRepository) CreateDailyTask(ctx context.Context, dailyTask *models.DailyTask) (*models.DailyTask, error) {
+	if err := dbFor(ctx, r.db).Create(dailyTask).Error; err != nil {
 		return nil, err
 	}
 	// Load the task relationship
-	if err := r.db.Preload("Task").First(dailyTask, dailyTask.ID).Error; err != nil {
+	if err := dbFor(ctx, r.db).Preload("Task").First(dailyTask, dailyTask.ID).Error; err != nil {
 		return nil, err
 	}
 	return dailyTask, nil
 }
 
 // GetDailyTasksByUserID retrieves daily tasks for a user
-func (r *TaskRepository) GetDailyTasksByUserID(userID uint) ([]models.DailyTask, error) {
+func (r *TaskRepository) GetDailyTasksByUserID(ctx context.Context, userID uint) ([]models.DailyTask, error) {
 	var dailyTasks []models.DailyTask
-	err := r.db.Preload("Task").
+	err := dbFor(ctx, r.db).Preload("Task").
 		Where("user_id = ?", userID).
 		Find(&dailyTasks).Error
 	return dailyTasks, err
 }
 
+// GetDailyTasksForDate retrieves a user's daily tasks assigned on a local date
+func (r *TaskRepository) GetDailyTasksForDate(ctx context.Context, userID uint, date string) ([]models.DailyTask, error) {
+	var dailyTasks []models.DailyTask
+	err := dbFor(ctx, r.db).Preload("Task").
+		Where("user_id = ? AND assigned_date = ?", userID, date).
+		Find(&dailyTasks).Error
+	return dailyTasks, err
+}
+
 // GetDailyTaskByID retrieves a daily task by ID
-func (r *TaskRepository) GetDailyTaskByID(id uint) (*models.DailyTask, error) {
+func (r *TaskRepository) GetDailyTaskByID(ctx context.Context, id uint) (*models.DailyTask, error) {
+	var dailyTask models.DailyTask
+	err := dbFor(ctx, r.db).Preload("Task").First(&dailyTask, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &dailyTask, nil
+}
+
+// GetDailyTaskByEvidencePhoto retrieves the daily task an evidence photo was uploaded for
+func (r *TaskRepository) GetDailyTaskByEvidencePhoto(ctx context.Context, photoURL string) (*models.DailyTask, error) {
 	var dailyTask models.DailyTask
-	err := r.db.Preload("Task").First(&dailyTask, id).Error
+	err := dbFor(ctx, r.db).Preload("Task").Where("evidence_photo_url = ?", photoURL).First(&dailyTask).Error
 	if err != nil {
 		return nil, err
 	}
@@ -83,21 +115,127 @@ func (r *TaskRepository) GetDailyTaskByID(id uint) (*models.DailyTask, error) {
 }
 
 // UpdateDailyTask updates a daily task
-func (r *TaskRepository) UpdateDailyTask(id uint, updates *models.UpdateDailyTaskRequest) error {
+func (r *TaskRepository) UpdateDailyTask(ctx context.Context, id uint, updates *models.UpdateDailyTaskRequest) error {
 	dailyTask := &models.DailyTask{}
-	if err := r.db.First(dailyTask, id).Error; err != nil {
+	if err := dbFor(ctx, r.db).First(dailyTask, id).Error; err != nil {
 		return err
 	}
 
+	updateData := dailyTaskUpdates(updates)
+	if len(updateData) > 0 {
+		return dbFor(ctx, r.db).Model(dailyTask).Updates(updateData).Error
+	}
+	
+	return nil
+}
+
+// CompleteDailyTask applies a completion to a daily task that is neither completed nor expired.
+// It returns gorm.ErrRecordNotFound when no such task matched, so a concurrent completion
+// of the same task fails instead of being applied twice.
+func (r *TaskRepository) CompleteDailyTask(ctx context.Context, id uint, updates *models.UpdateDailyTaskRequest) error {
+	result := dbFor(ctx, r.db).Model(&models.DailyTask{}).
+		Where("id = ? AND is_completed = ? AND expired_at IS NULL", id, false).
+		Updates(dailyTaskUpdates(updates))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReviewDailyTask applies a reviewer's decision to a daily task pending verification.
+// It returns gorm.ErrRecordNotFound when the task is no longer pending, so a completion
+// is approved or rejected at most once.
+func (r *TaskRepository) ReviewDailyTask(ctx context.Context, id uint, updates *models.UpdateDailyTaskRequest) error {
+	result := dbFor(ctx, r.db).Model(&models.DailyTask{}).
+		Where("id = ? AND verification_status = ?", id, models.VerificationStatusPending).
+		Updates(dailyTaskUpdates(updates))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// dailyTaskUpdates returns the columns set in a daily task update request
+func dailyTaskUpdates(updates *models.UpdateDailyTaskRequest) map[string]interface{} {
 	updateData := make(map[string]interface{})
 	
 	if updates.IsCompleted != nil {
 		updateData["is_completed"] = *updates.IsCompleted
 	}
-
-	if len(updateData) > 0 {
-		return r.db.Model(dailyTask).Updates(updateData).Error
+	if updates.CompletedAt != nil {
+		updateData["completed_at"] = *updates.CompletedAt
 	}
-	
-	return nil
-} 
\ No newline at end of file
+	if updates.EvidenceNote != nil {
+		updateData["evidence_note"] = *updates.EvidenceNote
+	}
+	if updates.EvidenceValue != nil {
+		updateData["evidence_value"] = *updates.EvidenceValue
+	}
+	if updates.EvidencePhotoURL != nil {
+		updateData["evidence_photo_url"] = *updates.EvidencePhotoURL
+	}
+	if updates.VerificationStatus != nil {
+		updateData["verification_status"] = *updates.VerificationStatus
+	}
+	if updates.VerifiedBy != nil {
+		updateData["verified_by"] = *updates.VerifiedBy
+	}
+	if updates.VerifiedAt != nil {
+		updateData["verified_at"] = *updates.VerifiedAt
+	}
+	if updates.RejectionReason != nil {
+		updateData["rejection_reason"] = *updates.RejectionReason
+	}
+	return updateData
+}
+
+// GetCompletedSince retrieves a user's completed daily tasks since the given time, newest first
+func (r *TaskRepository) GetCompletedSince(ctx context.Context, userID uint, since time.Time) ([]models.DailyTask, error) {
+	var dailyTasks []models.DailyTask
+	err := dbFor(ctx, r.db).Where("user_id = ? AND is_completed = ? AND completed_at >= ?", userID, true, since).
+		Order("completed_at DESC").
+		Find(&dailyTasks).Error
+	return dailyTasks, err
+}
+
+// GetPendingVerifications retrieves completions awaiting review for the given verification modes,
+// excluding the reviewer's own tasks
+func (r *TaskRepository) GetPendingVerifications(ctx context.Context, excludeUserID uint, modes []string) ([]models.DailyTask, error) {
+	var dailyTasks []models.DailyTask
+	err := dbFor(ctx, r.db).Preload("Task").
+		Joins("JOIN tasks ON tasks.id = daily_tasks.task_id").
+		Where("daily_tasks.verification_status = ?", models.VerificationStatusPending).
+		Where("daily_tasks.user_id <> ?", excludeUserID).
+		Where("tasks.verification_mode IN ?", modes).
+		Order("daily_tasks.completed_at ASC").
+		Find(&dailyTasks).Error
+	return dailyTasks, err
+}
+
+// GetCompletedDates returns the distinct local dates after after and up to through on which a
+// user completed a daily task, including completions awaiting verification
+func (r *TaskRepository) GetCompletedDates(ctx context.Context, userID uint, after, through string) ([]string, error) {
+	var dates []string
+	err := dbFor(ctx, r.db).Model(&models.DailyTask{}).
+		Where("user_id = ? AND is_completed = ? AND assigned_date > ? AND assigned_date <= ?", userID, true, after, through).
+		Distinct("assigned_date").
+		Pluck("assigned_date", &dates).Error
+	return dates, err
+}
+
+// ExpireDailyTasks marks the uncompleted daily tasks of users in a timezone that were assigned
+// before the given local date as expired. Tasks from before dates were recorded are included.
+func (r *TaskRepository) ExpireDailyTasks(ctx context.Context, timezone, before string, expiredAt time.Time) (int64, error) {
+	db := dbFor(ctx, r.db)
+	result := db.Model(&models.DailyTask{}).
+		Where("is_completed = ? AND expired_at IS NULL AND assigned_date < ?", false, before).
+		Where("user_id IN (?)", db.Model(&models.User{}).Select("id").Where("timezone = ?", timezone)).
+		Update("expired_at", expiredAt)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+type TransactorInterface interface {
+	// WithinTransaction runs fn in a database transaction, committed when fn returns nil and
+	// rolled back otherwise. Repository calls made with the context passed to fn join it.
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type Transactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor creates a transactor for the repositories sharing db
+func NewTransactor(db *gorm.DB) TransactorInterface {
+	return &Transactor{db: db}
+}
+
+// WithinTransaction runs fn in a transaction, or in a savepoint of the transaction ctx already carries
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return dbFor(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// dbFor returns the transaction carried by ctx, or db when there is none, bound to ctx
+func dbFor(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
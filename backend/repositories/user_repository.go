@@ -1,19 +1,44 @@
 package repositories
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"fithero-backend/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepositoryInterface interface {
-	Create(user *models.User) (*models.User, error)
-	GetByID(id uint) (*models.User, error)
-	GetByEmail(email string) (*models.User, error)
-	GetByGoogleID(googleID string) (*models.User, error)
-	GetAll() ([]models.User, error)
-	GetTopUsersByPoints(limit int) ([]models.User, error)
-	Update(id uint, updates *models.UpdateUserRequest) error
-	Delete(id uint) error
+	Create(ctx context.Context, user *models.User) (*models.User, error)
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByIDForUpdate(ctx context.Context, id uint) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByGoogleID(ctx context.Context, googleID string) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetAll(ctx context.Context) ([]models.User, error)
+	GetTopUsersByPoints(ctx context.Context, limit int) ([]models.User, error)
+	Update(ctx context.Context, id uint, updates *models.UpdateUserRequest) error
+	UpdateRole(ctx context.Context, id uint, role string) error
+	SetPasswordHash(ctx context.Context, id uint, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, id uint, verifiedAt time.Time) error
+	ChangeEmail(ctx context.Context, id uint, email string) error
+	AddPoints(ctx context.Context, id uint, points int) error
+
+	// Usernames
+	IsUsernameTaken(ctx context.Context, username string, exceptUserID uint) (bool, error)
+	GetUsernameHistory(ctx context.Context, username string) (*models.UsernameHistory, error)
+	GetLastUsernameChange(ctx context.Context, userID uint) (*models.UsernameHistory, error)
+	ChangeUsername(ctx context.Context, userID uint, oldUsername, newUsername string, changedAt time.Time) error
+	Delete(ctx context.Context, id uint) error
+
+	// Scheduled jobs
+	GetTimezones(ctx context.Context) ([]string, error)
+	GetUsersWithoutDailyTasks(ctx context.Context, timezone, date string, activeSince time.Time) ([]models.User, error)
+	GetUsersForStreakEvaluation(ctx context.Context, timezone, date string) ([]models.User, error)
+	UpdateStreak(ctx context.Context, id uint, current, longest int, evaluatedOn string) error
+	CreateLeaderboardSnapshot(ctx context.Context, entries []models.LeaderboardSnapshot) error
 }
 
 type UserRepository struct {
@@ -24,48 +49,66 @@ func NewUserRepository(db *gorm.DB) UserRepositoryInterface {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) Create(user *models.User) (*models.User, error) {
-	if err := r.db.Create(user).Error; err != nil {
+func (r *UserRepository) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	if err := dbFor(ctx, r.db).Create(user).Error; err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (r *UserRepository) GetByID(id uint) (*models.User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := dbFor(ctx, r.db).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByIDForUpdate retrieves a user and locks the row until the surrounding transaction ends
+func (r *UserRepository) GetByIDForUpdate(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := dbFor(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	if err := r.db.First(&user, id).Error; err != nil {
+	if err := dbFor(ctx, r.db).Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+func (r *UserRepository) GetByGoogleID(ctx context.Context, googleID string) (*models.User, error) {
 	var user models.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := dbFor(ctx, r.db).Where("google_id = ?", googleID).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepository) GetByGoogleID(googleID string) (*models.User, error) {
+// GetByUsername retrieves a user by username, ignoring case
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	if err := r.db.Where("google_id = ?", googleID).First(&user).Error; err != nil {
+	if err := dbFor(ctx, r.db).Where("LOWER(username) = LOWER(?)", username).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepository) GetAll() ([]models.User, error) {
+func (r *UserRepository) GetAll(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	if err := r.db.Find(&users).Error; err != nil {
+	if err := dbFor(ctx, r.db).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-func (r *UserRepository) GetTopUsersByPoints(limit int) ([]models.User, error) {
+func (r *UserRepository) GetTopUsersByPoints(ctx context.Context, limit int) ([]models.User, error) {
 	var users []models.User
-	if err := r.db.Where("is_active = ?", true).
+	if err := dbFor(ctx, r.db).Where("is_active = ?", true).
 		Order("points DESC").
 		Limit(limit).
 		Find(&users).Error; err != nil {
@@ -74,9 +117,9 @@ func (r *UserRepository) GetTopUsersByPoints(limit int) ([]models.User, error) {
 	return users, nil
 }
 
-func (r *UserRepository) Update(id uint, updates *models.UpdateUserRequest) error {
+func (r *UserRepository) Update(ctx context.Context, id uint, updates *models.UpdateUserRequest) error {
 	user := &models.User{}
-	if err := r.db.First(user, id).Error; err != nil {
+	if err := dbFor(ctx, r.db).First(user, id).Error; err != nil {
 		return err
 	}
 
@@ -106,14 +149,158 @@ func (r *UserRepository) Update(id uint, updates *models.UpdateUserRequest) erro
 	if updates.JobTitle != nil {
 		updateData["job_title"] = *updates.JobTitle
 	}
+	if updates.Timezone != nil {
+		updateData["timezone"] = *updates.Timezone
+	}
 
 	if len(updateData) > 0 {
-		return r.db.Model(user).Updates(updateData).Error
+		return dbFor(ctx, r.db).Model(user).Updates(updateData).Error
 	}
 	
 	return nil
 }
 
-func (r *UserRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
-} 
\ No newline at end of file
+func (r *UserRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	return dbFor(ctx, r.db).Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+// SetPasswordHash replaces the user's password hash, an empty hash removes password login
+func (r *UserRepository) SetPasswordHash(ctx context.Context, id uint, passwordHash string) error {
+	return dbFor(ctx, r.db).Model(&models.User{}).Where("id = ?", id).Update("password_hash", passwordHash).Error
+}
+
+// MarkEmailVerified records that the user proved ownership of their email address
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id uint, verifiedAt time.Time) error {
+	return dbFor(ctx, r.db).Model(&models.User{}).
+		Where("id = ? AND email_verified_at IS NULL", id).
+		Update("email_verified_at", verifiedAt).Error
+}
+
+// ChangeEmail sets a new email address, which is unverified until the user confirms it
+func (r *UserRepository) ChangeEmail(ctx context.Context, id uint, email string) error {
+	return dbFor(ctx, r.db).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"email":             email,
+			"email_verified_at": nil,
+		}).Error
+}
+
+// AddPoints adds to the user's points in a single statement, so concurrent changes are not lost
+func (r *UserRepository) AddPoints(ctx context.Context, id uint, points int) error {
+	return dbFor(ctx, r.db).Model(&models.User{}).Where("id = ?", id).
+		Update("points", gorm.Expr("points + ?", points)).Error
+}
+
+// IsUsernameTaken reports whether another user, including deleted users, holds or previously held the username
+func (r *UserRepository) IsUsernameTaken(ctx context.Context, username string, exceptUserID uint) (bool, error) {
+	var count int64
+	if err := dbFor(ctx, r.db).Unscoped().Model(&models.User{}).
+		Where("LOWER(username) = LOWER(?) AND id <> ?", username, exceptUserID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	if err := dbFor(ctx, r.db).Model(&models.UsernameHistory{}).
+		Where("username = LOWER(?) AND user_id <> ?", username, exceptUserID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetUsernameHistory retrieves the history entry for a previous username
+func (r *UserRepository) GetUsernameHistory(ctx context.Context, username string) (*models.UsernameHistory, error) {
+	var history models.UsernameHistory
+	if err := dbFor(ctx, r.db).Where("username = LOWER(?)", username).First(&history).Error; err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// GetLastUsernameChange retrieves the user's most recent username change
+func (r *UserRepository) GetLastUsernameChange(ctx context.Context, userID uint) (*models.UsernameHistory, error) {
+	var history models.UsernameHistory
+	if err := dbFor(ctx, r.db).Where("user_id = ?", userID).Order("changed_at DESC").First(&history).Error; err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// ChangeUsername renames a user and keeps the old username reserved for them. Reclaiming one
+// of the user's own previous usernames removes it from their history.
+func (r *UserRepository) ChangeUsername(ctx context.Context, userID uint, oldUsername, newUsername string, changedAt time.Time) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND username = LOWER(?)", userID, newUsername).
+			Delete(&models.UsernameHistory{}).Error; err != nil {
+			return err
+		}
+		if !strings.EqualFold(oldUsername, newUsername) {
+			if err := tx.Create(&models.UsernameHistory{
+				UserID:    userID,
+				Username:  strings.ToLower(oldUsername),
+				ChangedAt: changedAt,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("username", newUsername).Error
+	})
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
+	return dbFor(ctx, r.db).Delete(&models.User{}, id).Error
+} 
+
+// GetTimezones returns the distinct timezones of active users
+func (r *UserRepository) GetTimezones(ctx context.Context) ([]string, error) {
+	var timezones []string
+	err := dbFor(ctx, r.db).Model(&models.User{}).
+		Where("is_active = ?", true).
+		Distinct("timezone").
+		Pluck("timezone", &timezones).Error
+	return timezones, err
+}
+
+// GetUsersWithoutDailyTasks returns active users in a timezone who logged in since activeSince
+// and have no daily tasks for the given local date
+func (r *UserRepository) GetUsersWithoutDailyTasks(ctx context.Context, timezone, date string, activeSince time.Time) ([]models.User, error) {
+	var users []models.User
+	db := dbFor(ctx, r.db)
+	err := db.
+		Where("timezone = ? AND is_active = ? AND last_login_at >= ?", timezone, true, activeSince).
+		Where("NOT EXISTS (?)", db.Model(&models.DailyTask{}).Select("1").
+			Where("daily_tasks.user_id = users.id AND daily_tasks.assigned_date = ?", date)).
+		Find(&users).Error
+	return users, err
+}
+
+// GetUsersForStreakEvaluation returns active users in a timezone whose streak has not been
+// evaluated up to the given local date
+func (r *UserRepository) GetUsersForStreakEvaluation(ctx context.Context, timezone, date string) ([]models.User, error) {
+	var users []models.User
+	err := dbFor(ctx, r.db).
+		Where("timezone = ? AND is_active = ?", timezone, true).
+		Where("COALESCE(streak_evaluated_on, '') < ?", date).
+		Find(&users).Error
+	return users, err
+}
+
+// UpdateStreak stores a user's streak as evaluated up to and including evaluatedOn
+func (r *UserRepository) UpdateStreak(ctx context.Context, id uint, current, longest int, evaluatedOn string) error {
+	return dbFor(ctx, r.db).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"current_streak":      current,
+		"longest_streak":      longest,
+		"streak_evaluated_on": evaluatedOn,
+	}).Error
+}
+
+// CreateLeaderboardSnapshot stores leaderboard positions, keeping any already stored for the same date
+func (r *UserRepository) CreateLeaderboardSnapshot(ctx context.Context, entries []models.LeaderboardSnapshot) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return dbFor(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(&entries).Error
+}
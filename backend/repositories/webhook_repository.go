@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"fithero-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepositoryInterface interface {
+	// Subscriptions
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetActiveSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription, fields ...string) error
+	DeleteSubscription(ctx context.Context, id uint) error
+	RecordSuccess(ctx context.Context, subscriptionID uint) error
+	RecordFailure(ctx context.Context, subscriptionID uint, disableAfter int, reason string, now time.Time) (bool, error)
+
+	// Deliveries
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, subscriptionID uint, status string, limit int) ([]models.WebhookDelivery, error)
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	DeleteFinishedDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *gorm.DB) WebhookRepositoryInterface {
+	return &WebhookRepository{db: db}
+}
+
+// CreateSubscription creates a webhook subscription
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return dbFor(ctx, r.db).Create(subscription).Error
+}
+
+// GetSubscription retrieves a webhook subscription by ID
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := dbFor(ctx, r.db).First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// ListSubscriptions retrieves all webhook subscriptions, oldest first
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := dbFor(ctx, r.db).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetActiveSubscriptions retrieves the subscriptions that receive events
+func (r *WebhookRepository) GetActiveSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := dbFor(ctx, r.db).Where("is_active = ?", true).Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// UpdateSubscription saves the given fields of a webhook subscription and leaves the others
+// as they are in the database. It returns gorm.ErrRecordNotFound when the subscription no
+// longer exists.
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription, fields ...string) error {
+	result := dbFor(ctx, r.db).Model(subscription).Select(fields).Updates(subscription)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteSubscription deletes a webhook subscription and its delivery log
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id uint) error {
+	return dbFor(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.WebhookSubscription{}, id).Error
+	})
+}
+
+// RecordSuccess resets a subscription's failure count after a successful delivery
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, subscriptionID uint) error {
+	return dbFor(ctx, r.db).Model(&models.WebhookSubscription{}).
+		Where("id = ? AND consecutive_failures <> 0", subscriptionID).
+		Update("consecutive_failures", 0).Error
+}
+
+// RecordFailure counts a failed delivery attempt and disables the subscription once
+// disableAfter attempts in a row have failed. It reports whether this failure disabled it.
+func (r *WebhookRepository) RecordFailure(ctx context.Context, subscriptionID uint, disableAfter int, reason string, now time.Time) (bool, error) {
+	err := dbFor(ctx, r.db).Model(&models.WebhookSubscription{}).
+		Where("id = ?", subscriptionID).
+		Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+	if err != nil {
+		return false, err
+	}
+
+	result := dbFor(ctx, r.db).Model(&models.WebhookSubscription{}).
+		Where("id = ? AND is_active = ? AND consecutive_failures >= ?", subscriptionID, true, disableAfter).
+		Updates(map[string]interface{}{
+			"is_active":       false,
+			"disabled_at":     now,
+			"disabled_reason": reason,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateDelivery queues a delivery. An event already queued for the subscription is not
+// queued again, so a repeated outbox delivery does not send it twice.
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return dbFor(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(delivery).Error
+}
+
+// GetDelivery retrieves a webhook delivery by ID
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := dbFor(ctx, r.db).First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries retrieves a subscription's most recent deliveries, newest first, optionally
+// only those with the given status
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uint, status string, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := dbFor(ctx, r.db).Where("subscription_id = ?", subscriptionID).Order("id DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDueDeliveries leases up to limit pending deliveries of active subscriptions that are
+// due, oldest first, by moving their next attempt to leaseUntil and counting the attempt
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	db := dbFor(ctx, r.db)
+	active := db.Model(&models.WebhookSubscription{}).Select("id").Where("is_active = ?", true)
+	due := db.Model(&models.WebhookDelivery{}).Select("id").
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Where("subscription_id IN (?)", active).
+		Order("id").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+	err := db.Model(&deliveries).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Updates(map[string]interface{}{
+			"next_attempt_at": leaseUntil,
+			"attempts":        gorm.Expr("attempts + 1"),
+		}).Error
+	return deliveries, err
+}
+
+// UpdateDelivery saves the outcome of a delivery attempt
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return dbFor(ctx, r.db).Save(delivery).Error
+}
+
+// DeleteFinishedDeliveriesBefore deletes succeeded and failed deliveries created before the given time
+func (r *WebhookRepository) DeleteFinishedDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := dbFor(ctx, r.db).
+		Where("status <> ? AND created_at < ?", models.WebhookDeliveryPending, before).
+		Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"fithero-backend/logging"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+	"fithero-backend/tracing"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAccessTokenLifetime = 90 * 24 * time.Hour
+	maxAccessTokensPerUser     = 25
+	// accessTokenTouchInterval limits last-used writes for tokens used in quick succession
+	accessTokenTouchInterval = time.Minute
+)
+
+type AccessTokenService struct {
+	accessTokenRepo repositories.AccessTokenRepositoryInterface
+	userRepo        repositories.UserRepositoryInterface
+}
+
+func NewAccessTokenService(accessTokenRepo repositories.AccessTokenRepositoryInterface, userRepo repositories.UserRepositoryInterface) *AccessTokenService {
+	return &AccessTokenService{
+		accessTokenRepo: accessTokenRepo,
+		userRepo:        userRepo,
+	}
+}
+
+// CreateToken creates a personal access token. The returned token string is not stored.
+func (s *AccessTokenService) CreateToken(ctx context.Context, userID uint, req *models.CreateAccessTokenRequest) (*models.CreateAccessTokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "AccessTokenService.CreateToken")
+	defer span.End()
+
+	existing, err := s.accessTokenRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxAccessTokensPerUser {
+		return nil, errors.New("too many access tokens")
+	}
+
+	secret, err := generateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	token := models.AccessTokenPrefix + secret
+
+	lifetime := defaultAccessTokenLifetime
+	if req.ExpiresInDays != nil {
+		lifetime = time.Duration(*req.ExpiresInDays) * 24 * time.Hour
+	}
+	expiresAt := time.Now().Add(lifetime)
+
+	accessToken := &models.PersonalAccessToken{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		TokenHash:   hashToken(token),
+		TokenPrefix: token[:len(models.AccessTokenPrefix)+6],
+		Scopes:      uniqueScopes(req.Scopes),
+		ExpiresAt:   &expiresAt,
+	}
+	if err := s.accessTokenRepo.Create(ctx, accessToken); err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAccessTokenResponse{
+		Token:       token,
+		AccessToken: *accessToken,
+	}, nil
+}
+
+// ListTokens returns the user's active tokens
+func (s *AccessTokenService) ListTokens(ctx context.Context, userID uint) ([]models.PersonalAccessToken, error) {
+	ctx, span := tracing.Start(ctx, "AccessTokenService.ListTokens")
+	defer span.End()
+
+	return s.accessTokenRepo.GetActiveByUserID(ctx, userID)
+}
+
+// RevokeToken revokes one of the user's tokens
+func (s *AccessTokenService) RevokeToken(ctx context.Context, userID, tokenID uint) error {
+	ctx, span := tracing.Start(ctx, "AccessTokenService.RevokeToken")
+	defer span.End()
+
+	token, err := s.accessTokenRepo.GetByID(ctx, tokenID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("access token not found")
+		}
+		return err
+	}
+	if token.UserID != userID {
+		return errors.New("access token not found")
+	}
+	return s.accessTokenRepo.Revoke(ctx, tokenID)
+}
+
+// Authenticate resolves a personal access token to its token record and active user
+func (s *AccessTokenService) Authenticate(ctx context.Context, rawToken, ip string) (*models.PersonalAccessToken, *models.User, error) {
+	ctx, span := tracing.Start(ctx, "AccessTokenService.Authenticate")
+	defer span.End()
+
+	token, err := s.accessTokenRepo.GetByHash(ctx, hashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("invalid access token")
+		}
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if !token.IsActive(now) {
+		return nil, nil, errors.New("invalid access token")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, errors.New("invalid access token")
+	}
+	if !user.IsActive {
+		return nil, nil, errors.New("user account is disabled")
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= accessTokenTouchInterval || token.LastUsedIP != ip {
+		if err := s.accessTokenRepo.Touch(ctx, token.ID, now, ip); err != nil {
+			logging.FromContext(ctx).Warn("Failed to update last use of access token", "token_id", token.ID, "error", err)
+		}
+	}
+
+	return token, user, nil
+}
+
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result
+}
@@ -1,35 +1,123 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
+	"fithero-backend/metrics"
 	"fithero-backend/models"
 	"fithero-backend/repositories"
+	"fithero-backend/tracing"
 	"gorm.io/gorm"
 )
 
+// refundGracePeriod is how long after a purchase a user may refund it themselves
+const refundGracePeriod = 24 * time.Hour
+
+// RequirementError describes an unmet achievement unlock requirement
+type RequirementError struct {
+	Reason string
+}
+
+func (e *RequirementError) Error() string {
+	return e.Reason
+}
+
 type AchievementService struct {
 	achievementRepo repositories.AchievementRepositoryInterface
 	userRepo        repositories.UserRepositoryInterface
+	outboxRepo      repositories.OutboxRepositoryInterface
+	transactor      repositories.TransactorInterface
 }
 
 // NewAchievementService creates a new achievement service
-func NewAchievementService(achievementRepo repositories.AchievementRepositoryInterface, userRepo repositories.UserRepositoryInterface) *AchievementService {
+func NewAchievementService(achievementRepo repositories.AchievementRepositoryInterface, userRepo repositories.UserRepositoryInterface, outboxRepo repositories.OutboxRepositoryInterface, transactor repositories.TransactorInterface) *AchievementService {
 	return &AchievementService{
 		achievementRepo: achievementRepo,
 		userRepo:        userRepo,
+		outboxRepo:      outboxRepo,
+		transactor:      transactor,
 	}
 }
 
 // GetAllAchievements returns all available achievements
-func (s *AchievementService) GetAllAchievements() ([]models.Achievement, error) {
-	return s.achievementRepo.GetAll()
+func (s *AchievementService) GetAllAchievements(ctx context.Context) ([]models.Achievement, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.GetAllAchievements")
+	defer span.End()
+
+	return s.achievementRepo.GetAll(ctx)
+}
+
+// GetAchievementsForUser returns all achievements with their lock status for a user
+func (s *AchievementService) GetAchievementsForUser(ctx context.Context, userID uint) ([]models.AchievementWithStatus, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.GetAchievementsForUser")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	achievements, err := s.achievementRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userAchievements, err := s.achievementRepo.GetUserAchievements(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	owned := make(map[uint]bool, len(userAchievements))
+	for _, ua := range userAchievements {
+		owned[ua.AchievementID] = true
+	}
+
+	unlockCounts, err := s.achievementRepo.CountUnlocksByAchievement(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]models.AchievementWithStatus, 0, len(achievements))
+	for _, achievement := range achievements {
+		status := models.AchievementWithStatus{
+			Achievement: achievement,
+			CanAfford:   user.Points >= achievement.PointsCost,
+		}
+		if achievement.StockLimit != nil {
+			remaining := *achievement.StockLimit - int(unlockCounts[achievement.ID])
+			if remaining < 0 {
+				remaining = 0
+			}
+			status.StockRemaining = &remaining
+		}
+
+		if owned[achievement.ID] {
+			status.Status = models.AchievementStatusOwned
+		} else if err := s.checkRequirements(user, &achievement, owned, unlockCounts[achievement.ID], now); err != nil {
+			status.Status = models.AchievementStatusLocked
+			status.Reason = err.Error()
+		} else {
+			status.Status = models.AchievementStatusAvailable
+		}
+
+		result = append(result, status)
+	}
+
+	return result, nil
 }
 
 // GetUserAchievements retrieves all achievements unlocked by a user
-func (s *AchievementService) GetUserAchievements(userID uint) ([]models.UserAchievement, error) {
+func (s *AchievementService) GetUserAchievements(ctx context.Context, userID uint) ([]models.UserAchievement, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.GetUserAchievements")
+	defer span.End()
+
 	// Validate user exists
-	_, err := s.userRepo.GetByID(userID)
+	_, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -37,13 +125,16 @@ func (s *AchievementService) GetUserAchievements(userID uint) ([]models.UserAchi
 		return nil, err
 	}
 
-	return s.achievementRepo.GetUserAchievements(userID)
+	return s.achievementRepo.GetUserAchievements(ctx, userID)
 }
 
 // UnlockAchievement unlocks an achievement for a user with business logic validation
-func (s *AchievementService) UnlockAchievement(userID, achievementID uint) (*models.UserAchievement, error) {
+func (s *AchievementService) UnlockAchievement(ctx context.Context, userID, achievementID uint) (*models.UserAchievement, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.UnlockAchievement")
+	defer span.End()
+
 	// Validate user exists
-	user, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -52,7 +143,7 @@ func (s *AchievementService) UnlockAchievement(userID, achievementID uint) (*mod
 	}
 
 	// Validate achievement exists
-	achievement, err := s.achievementRepo.GetByID(achievementID)
+	achievement, err := s.achievementRepo.GetByID(ctx, achievementID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("achievement not found")
@@ -61,7 +152,7 @@ func (s *AchievementService) UnlockAchievement(userID, achievementID uint) (*mod
 	}
 
 	// Check if achievement is already unlocked
-	isUnlocked, err := s.achievementRepo.IsAchievementUnlocked(userID, achievementID)
+	isUnlocked, err := s.achievementRepo.IsAchievementUnlocked(ctx, userID, achievementID)
 	if err != nil {
 		return nil, err
 	}
@@ -69,62 +160,439 @@ func (s *AchievementService) UnlockAchievement(userID, achievementID uint) (*mod
 		return nil, errors.New("achievement already unlocked")
 	}
 
+	// Check unlock requirements (level, prerequisites, availability window, stock)
+	if err := s.validateRequirements(ctx, userID, user, achievement); err != nil {
+		return nil, err
+	}
+
 	// Check if user has enough points
 	if user.Points < achievement.PointsCost {
 		return nil, errors.New("insufficient points to unlock achievement")
 	}
 
-	// Deduct points from user
-	newPoints := user.Points - achievement.PointsCost
-	err = s.userRepo.Update(userID, &models.UpdateUserRequest{
-		Points: &newPoints,
+	// The purchase, its audit entry and its event are stored together or not at all
+	var createdAchievement *models.UserAchievement
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Count the unlocks again under a lock on the achievement, so concurrent purchases
+		// cannot exceed its stock
+		if achievement.StockLimit != nil {
+			if err := s.achievementRepo.LockForUpdate(ctx, achievementID); err != nil {
+				return err
+			}
+			count, err := s.achievementRepo.CountUnlocks(ctx, achievementID)
+			if err != nil {
+				return err
+			}
+			if count >= int64(*achievement.StockLimit) {
+				return &RequirementError{Reason: "Sold out"}
+			}
+		}
+
+		// Check the balance again under a lock on the user, so concurrent purchases cannot
+		// spend the same points twice
+		buyer, err := s.userRepo.GetByIDForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if buyer.Points < achievement.PointsCost {
+			return errors.New("insufficient points to unlock achievement")
+		}
+
+		// Deduct points from user
+		if err := s.userRepo.AddPoints(ctx, userID, -achievement.PointsCost); err != nil {
+			return errors.New("failed to deduct points from user")
+		}
+
+		// Create user achievement
+		userAchievement := &models.UserAchievement{
+			UserID:        userID,
+			AchievementID: achievementID,
+			UnlockedAt:    time.Now(),
+			PointsSpent:   achievement.PointsCost,
+		}
+
+		createdAchievement, err = s.achievementRepo.CreateUserAchievement(ctx, userAchievement)
+		if err != nil {
+			return errors.New("failed to unlock achievement")
+		}
+
+		// Update user job title or character if achievement affects them
+		if err := s.updateUserBasedOnAchievement(ctx, userID, achievement); err != nil {
+			return err
+		}
+
+		err = s.achievementRepo.CreateAuditLog(ctx, &models.AchievementAuditLog{
+			UserID:        userID,
+			AchievementID: achievementID,
+			ActorID:       userID,
+			Action:        models.AchievementActionUnlock,
+			PointsDelta:   -achievement.PointsCost,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, s.outboxRepo, models.EventAchievementUnlocked, userID, &models.AchievementUnlockedEvent{
+			UserID:        userID,
+			AchievementID: achievementID,
+			Title:         achievement.Title,
+			Type:          achievement.Type,
+			PointsSpent:   achievement.PointsCost,
+			UnlockedAt:    userAchievement.UnlockedAt,
+		})
 	})
 	if err != nil {
-		return nil, errors.New("failed to deduct points from user")
+		return nil, err
+	}
+	metrics.AchievementsUnlocked.WithLabelValues(achievement.Type).Inc()
+
+	return createdAchievement, nil
+}
+
+// RefundAchievement lets a user return a store purchase within the grace period
+func (s *AchievementService) RefundAchievement(ctx context.Context, userID, achievementID uint, reason string) (*models.AchievementAuditLog, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.RefundAchievement")
+	defer span.End()
+
+	userAchievement, err := s.achievementRepo.GetUserAchievementByUserAndAchievement(ctx, userID, achievementID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("achievement not owned")
+		}
+		return nil, err
 	}
 
-	// Create user achievement
-	userAchievement := &models.UserAchievement{
-		UserID:        userID,
-		AchievementID: achievementID,
-		UnlockedAt:    time.Now(),
+	if userAchievement.PointsSpent <= 0 {
+		return nil, errors.New("achievement was not purchased")
+	}
+	if time.Since(userAchievement.UnlockedAt) > refundGracePeriod {
+		return nil, errors.New("refund period has expired")
 	}
 
-	createdAchievement, err := s.achievementRepo.CreateUserAchievement(userAchievement)
+	// Achievements that require this one have to be refunded first
+	entries, err := s.removeUserAchievement(ctx, userID, userAchievement, models.AchievementActionRefund, reason, true, false)
 	if err != nil {
-		// Rollback points if achievement creation fails
-		rollbackPoints := user.Points
-		s.userRepo.Update(userID, &models.UpdateUserRequest{
-			Points: &rollbackPoints,
-		})
-		return nil, errors.New("failed to unlock achievement")
+		return nil, err
 	}
+	return &entries[len(entries)-1], nil
+}
 
-	// Update user job title or character if achievement affects them
-	s.updateUserBasedOnAchievement(userID, achievement)
+// RevokeAchievement removes an achievement from a user on behalf of an admin, together with
+// the user's achievements that require it, optionally refunding the points spent on them.
+// It returns the audit entries of every removal, the requested achievement's last.
+func (s *AchievementService) RevokeAchievement(ctx context.Context, actorID, userID, achievementID uint, reason string, refund bool) ([]models.AchievementAuditLog, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.RevokeAchievement")
+	defer span.End()
 
-	return createdAchievement, nil
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	userAchievement, err := s.achievementRepo.GetUserAchievementByUserAndAchievement(ctx, userID, achievementID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("achievement not owned")
+		}
+		return nil, err
+	}
+
+	return s.removeUserAchievement(ctx, actorID, userAchievement, models.AchievementActionRevoke, reason, refund, true)
 }
 
-// updateUserBasedOnAchievement updates user character or job title based on achievement type
-func (s *AchievementService) updateUserBasedOnAchievement(userID uint, achievement *models.Achievement) {
-	updateReq := &models.UpdateUserRequest{}
-	
+// GetAuditLog returns achievement audit entries, for a single user when userID is non-zero
+func (s *AchievementService) GetAuditLog(ctx context.Context, userID uint) ([]models.AchievementAuditLog, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.GetAuditLog")
+	defer span.End()
+
+	return s.achievementRepo.GetAuditLogs(ctx, userID)
+}
+
+// removeUserAchievement deletes a user achievement, reverts any equipped profile field,
+// returns the points spent on it when refund is set and records the action. Owned
+// achievements that require it are removed the same way first when cascade is set;
+// otherwise it is not removed while any are owned. It returns an audit entry for every
+// removal, the one for userAchievement last.
+func (s *AchievementService) removeUserAchievement(ctx context.Context, actorID uint, userAchievement *models.UserAchievement, action, reason string, refund, cascade bool) ([]models.AchievementAuditLog, error) {
+	var entries []models.AchievementAuditLog
+	removing := make(map[uint]bool)
+
+	var remove func(ctx context.Context, userAchievement *models.UserAchievement, reason string) error
+	remove = func(ctx context.Context, userAchievement *models.UserAchievement, reason string) error {
+		userID := userAchievement.UserID
+		achievement := userAchievement.Achievement
+		removing[achievement.ID] = true
+
+		dependents, err := s.achievementRepo.GetOwnedDependents(ctx, userID, achievement.ID)
+		if err != nil {
+			return err
+		}
+		for i := range dependents {
+			if removing[dependents[i].AchievementID] {
+				continue
+			}
+			if !cascade {
+				return errors.New("achievement is required by other owned achievements")
+			}
+			dependentReason := fmt.Sprintf("Requires \"%s\", which was removed", achievement.Title)
+			if reason != "" {
+				dependentReason += ": " + reason
+			}
+			if err := remove(ctx, &dependents[i], dependentReason); err != nil {
+				return err
+			}
+		}
+
+		// Only the request that actually removes the achievement refunds it
+		if err := s.achievementRepo.DeleteUserAchievement(ctx, userAchievement.ID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("achievement not owned")
+			}
+			return errors.New("failed to remove achievement")
+		}
+
+		// Revert character or job title to the default if this achievement was equipped
+		if userAchievement.IsEquipped && (achievement.Type == "character" || achievement.Type == "upgrade") {
+			if err := s.equip(ctx, userID, achievement.Type, nil); err != nil {
+				return err
+			}
+		}
+
+		refundPoints := 0
+		if refund {
+			refundPoints = userAchievement.PointsSpent
+		}
+		if refundPoints > 0 {
+			if err := s.userRepo.AddPoints(ctx, userID, refundPoints); err != nil {
+				return errors.New("failed to refund points to user")
+			}
+		}
+
+		entry := models.AchievementAuditLog{
+			UserID:        userID,
+			AchievementID: achievement.ID,
+			ActorID:       actorID,
+			Action:        action,
+			Reason:        reason,
+			PointsDelta:   refundPoints,
+		}
+		if err := s.achievementRepo.CreateAuditLog(ctx, &entry); err != nil {
+			return err
+		}
+		entry.Achievement = achievement
+		entries = append(entries, entry)
+		return nil
+	}
+
+	// The removals, their refunds and their audit entries are stored together or not at all
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return remove(ctx, userAchievement, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// validateRequirements loads the data needed to check an achievement's unlock requirements
+func (s *AchievementService) validateRequirements(ctx context.Context, userID uint, user *models.User, achievement *models.Achievement) error {
+	owned := make(map[uint]bool)
+	if len(achievement.Prerequisites) > 0 {
+		userAchievements, err := s.achievementRepo.GetUserAchievements(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, ua := range userAchievements {
+			owned[ua.AchievementID] = true
+		}
+	}
+
+	var unlockCount int64
+	if achievement.StockLimit != nil {
+		count, err := s.achievementRepo.CountUnlocks(ctx, achievement.ID)
+		if err != nil {
+			return err
+		}
+		unlockCount = count
+	}
+
+	return s.checkRequirements(user, achievement, owned, unlockCount, time.Now())
+}
+
+// checkRequirements returns an error describing the first unmet unlock requirement
+func (s *AchievementService) checkRequirements(user *models.User, achievement *models.Achievement, owned map[uint]bool, unlockCount int64, now time.Time) error {
+	if achievement.AvailableFrom != nil && now.Before(*achievement.AvailableFrom) {
+		return &RequirementError{Reason: fmt.Sprintf("Available from %s", achievement.AvailableFrom.Format(time.RFC3339))}
+	}
+	if achievement.AvailableUntil != nil && !now.Before(*achievement.AvailableUntil) {
+		return &RequirementError{Reason: fmt.Sprintf("No longer available since %s", achievement.AvailableUntil.Format(time.RFC3339))}
+	}
+	if achievement.StockLimit != nil && unlockCount >= int64(*achievement.StockLimit) {
+		return &RequirementError{Reason: "Sold out"}
+	}
+	if user.Level < achievement.MinLevel {
+		return &RequirementError{Reason: fmt.Sprintf("Requires level %d", achievement.MinLevel)}
+	}
+	for _, prerequisite := range achievement.Prerequisites {
+		if !owned[prerequisite.ID] {
+			return &RequirementError{Reason: fmt.Sprintf("Requires \"%s\"", prerequisite.Title)}
+		}
+	}
+	return nil
+}
+
+// updateUserBasedOnAchievement equips a newly unlocked character or job title
+func (s *AchievementService) updateUserBasedOnAchievement(ctx context.Context, userID uint, achievement *models.Achievement) error {
 	switch achievement.Type {
+	case "character", "upgrade":
+		return s.equip(ctx, userID, achievement.Type, achievement)
+	}
+	return nil
+}
+
+// GetLoadout returns the achievements currently equipped by a user
+func (s *AchievementService) GetLoadout(ctx context.Context, userID uint) (*models.Loadout, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.GetLoadout")
+	defer span.End()
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	equipped, err := s.achievementRepo.GetEquippedAchievements(ctx, []uint{userID})
+	if err != nil {
+		return nil, err
+	}
+
+	if loadout, exists := buildLoadouts(equipped)[userID]; exists {
+		return loadout, nil
+	}
+	return &models.Loadout{Badges: []models.Achievement{}}, nil
+}
+
+// UpdateLoadout equips owned achievements into the character, job title and badge slots
+func (s *AchievementService) UpdateLoadout(ctx context.Context, userID uint, req *models.UpdateLoadoutRequest) (*models.Loadout, error) {
+	ctx, span := tracing.Start(ctx, "AchievementService.UpdateLoadout")
+	defer span.End()
+
+	if req.BadgeIDs != nil && len(req.BadgeIDs) > models.MaxShowcaseBadges {
+		return nil, errors.New("too many badges in loadout")
+	}
+
+	// Every slot is changed or none is. The lock on the user makes concurrent loadout
+	// changes apply one after the other instead of interleaving.
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.userRepo.GetByIDForUpdate(ctx, userID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user not found")
+			}
+			return err
+		}
+
+		// Validate every requested slot before changing anything
+		var character, jobTitle *models.Achievement
+		var err error
+		if req.CharacterID != nil && *req.CharacterID != 0 {
+			if character, err = s.ownedAchievementOfType(ctx, userID, *req.CharacterID, "character"); err != nil {
+				return err
+			}
+		}
+		if req.JobTitleID != nil && *req.JobTitleID != 0 {
+			if jobTitle, err = s.ownedAchievementOfType(ctx, userID, *req.JobTitleID, "upgrade"); err != nil {
+				return err
+			}
+		}
+		seen := make(map[uint]bool, len(req.BadgeIDs))
+		for _, badgeID := range req.BadgeIDs {
+			if seen[badgeID] {
+				return errors.New("duplicate badge in loadout")
+			}
+			seen[badgeID] = true
+			if _, err := s.ownedAchievementOfType(ctx, userID, badgeID, "badge"); err != nil {
+				return err
+			}
+		}
+
+		if req.CharacterID != nil {
+			if err := s.equip(ctx, userID, "character", character); err != nil {
+				return err
+			}
+		}
+		if req.JobTitleID != nil {
+			if err := s.equip(ctx, userID, "upgrade", jobTitle); err != nil {
+				return err
+			}
+		}
+		if req.BadgeIDs != nil {
+			if err := s.achievementRepo.ReplaceEquipped(ctx, userID, "badge", req.BadgeIDs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetLoadout(ctx, userID)
+}
+
+// equip puts an achievement into the character or job title slot and updates the
+// displayed profile field. A nil achievement unequips the slot and restores the default.
+func (s *AchievementService) equip(ctx context.Context, userID uint, achievementType string, achievement *models.Achievement) error {
+	var achievementIDs []uint
+	if achievement != nil {
+		achievementIDs = []uint{achievement.ID}
+	}
+	if err := s.achievementRepo.ReplaceEquipped(ctx, userID, achievementType, achievementIDs); err != nil {
+		return err
+	}
+
+	updateReq := &models.UpdateUserRequest{}
+	switch achievementType {
 	case "character":
-		updateReq.Character = &achievement.Title
+		character := ""
+		if achievement != nil {
+			character = achievement.Title
+		} else {
+			user, err := s.userRepo.GetByID(ctx, userID)
+			if err != nil {
+				return err
+			}
+			character = characterForLevel(user.Level)
+		}
+		updateReq.Character = &character
 	case "upgrade":
-		// Map achievement titles to job titles
-		jobTitle := s.mapAchievementToJobTitle(achievement.Title)
-		if jobTitle != "" {
-			updateReq.JobTitle = &jobTitle
+		jobTitle := defaultJobTitle
+		if achievement != nil {
+			if mapped := s.mapAchievementToJobTitle(achievement.Title); mapped != "" {
+				jobTitle = mapped
+			}
 		}
+		updateReq.JobTitle = &jobTitle
 	}
 
-	// Only update if there are changes
-	if updateReq.Character != nil || updateReq.JobTitle != nil {
-		s.userRepo.Update(userID, updateReq)
+	return s.userRepo.Update(ctx, userID, updateReq)
+}
+
+// ownedAchievementOfType verifies that a user owns an achievement of the expected type
+func (s *AchievementService) ownedAchievementOfType(ctx context.Context, userID, achievementID uint, achievementType string) (*models.Achievement, error) {
+	userAchievement, err := s.achievementRepo.GetUserAchievementByUserAndAchievement(ctx, userID, achievementID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("achievement not owned")
+		}
+		return nil, err
+	}
+	if userAchievement.Achievement.Type != achievementType {
+		return nil, errors.New("achievement cannot be equipped in this slot")
 	}
+	return &userAchievement.Achievement, nil
 }
 
 // mapAchievementToJobTitle maps achievement titles to job titles
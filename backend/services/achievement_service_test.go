@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"fithero-backend/models"
+)
+
+func newTestAchievementService() (*AchievementService, *memoryStore) {
+	store := newMemoryStore()
+	service := NewAchievementService(&storeAchievementRepo{store: store}, &storeUserRepo{store: store}, &storeOutboxRepo{store: store}, &memoryTransactor{store: store})
+	return service, store
+}
+
+// achievementChain adds three job title upgrades, each requiring the one before
+func achievementChain(store *memoryStore) (trainer, coach, director *models.Achievement) {
+	trainer = store.addAchievement(&models.Achievement{Title: "Personal Trainer", Type: "upgrade", PointsCost: 100})
+	coach = store.addAchievement(&models.Achievement{Title: "Fitness Coach", Type: "upgrade", PointsCost: 200, Prerequisites: []models.Achievement{*trainer}})
+	director = store.addAchievement(&models.Achievement{Title: "Fitness Director", Type: "upgrade", PointsCost: 300, Prerequisites: []models.Achievement{*coach}})
+	return trainer, coach, director
+}
+
+func TestRefundRejectedWhileDependentsOwned(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{Points: 0, JobTitle: "Fitness Coach"})
+	trainer, coach, _ := achievementChain(store)
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: trainer.ID, PointsSpent: 100, UnlockedAt: time.Now()})
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: coach.ID, PointsSpent: 200, UnlockedAt: time.Now(), IsEquipped: true})
+
+	_, err := service.RefundAchievement(context.Background(), user.ID, trainer.ID, "")
+	if err == nil || err.Error() != "achievement is required by other owned achievements" {
+		t.Fatalf("refund of a prerequisite: err = %v", err)
+	}
+	if owned := store.owned(user.ID); !owned[trainer.ID] || !owned[coach.ID] {
+		t.Errorf("owned after the rejected refund = %v", owned)
+	}
+	if store.users[user.ID].Points != 0 || len(store.auditLogs) != 0 {
+		t.Errorf("rejected refund changed points to %d and wrote %d audit entries", store.users[user.ID].Points, len(store.auditLogs))
+	}
+
+	// Refunding the dependent first frees the prerequisite
+	if _, err := service.RefundAchievement(context.Background(), user.ID, coach.ID, ""); err != nil {
+		t.Fatalf("refund of the dependent: %v", err)
+	}
+	if _, err := service.RefundAchievement(context.Background(), user.ID, trainer.ID, ""); err != nil {
+		t.Fatalf("refund of the prerequisite: %v", err)
+	}
+	if got := store.users[user.ID].Points; got != 300 {
+		t.Errorf("points = %d, want 300", got)
+	}
+	if got := store.users[user.ID].JobTitle; got != defaultJobTitle {
+		t.Errorf("job title = %q, want %q after refunding the equipped upgrade", got, defaultJobTitle)
+	}
+}
+
+func TestRevokeCascadesToDependents(t *testing.T) {
+	service, store := newTestAchievementService()
+	admin := store.addUser(&models.User{Role: "admin"})
+	user := store.addUser(&models.User{JobTitle: "Fitness Director"})
+	trainer, coach, director := achievementChain(store)
+	badge := store.addAchievement(&models.Achievement{Title: "Early Bird", Type: "badge", PointsCost: 50})
+	for _, achievement := range []*models.Achievement{trainer, coach, director, badge} {
+		store.addUserAchievement(&models.UserAchievement{
+			UserID:        user.ID,
+			AchievementID: achievement.ID,
+			PointsSpent:   achievement.PointsCost,
+			UnlockedAt:    time.Now().Add(-30 * 24 * time.Hour),
+			IsEquipped:    achievement == director,
+		})
+	}
+
+	entries, err := service.RevokeAchievement(context.Background(), admin.ID, user.ID, trainer.ID, "Bought with exploited points", true)
+	if err != nil {
+		t.Fatalf("RevokeAchievement: %v", err)
+	}
+
+	// Dependents are removed before what they depend on, each with its own audit entry
+	var removed []uint
+	for _, entry := range entries {
+		removed = append(removed, entry.AchievementID)
+		if entry.Action != models.AchievementActionRevoke || entry.ActorID != admin.ID || entry.PointsDelta != entry.Achievement.PointsCost {
+			t.Errorf("audit entry for %q: %+v", entry.Achievement.Title, entry)
+		}
+	}
+	if len(removed) != 3 || removed[0] != director.ID || removed[1] != coach.ID || removed[2] != trainer.ID {
+		t.Fatalf("removed %v, want %v", removed, []uint{director.ID, coach.ID, trainer.ID})
+	}
+	if entries[2].Reason != "Bought with exploited points" {
+		t.Errorf("reason = %q", entries[2].Reason)
+	}
+	if !strings.Contains(entries[1].Reason, `Requires "Personal Trainer"`) || !strings.Contains(entries[0].Reason, `Requires "Fitness Coach"`) {
+		t.Errorf("dependent reasons = %q, %q", entries[0].Reason, entries[1].Reason)
+	}
+	if len(store.auditLogs) != 3 {
+		t.Errorf("wrote %d audit entries, want 3", len(store.auditLogs))
+	}
+
+	if owned := store.owned(user.ID); len(owned) != 1 || !owned[badge.ID] {
+		t.Errorf("owned after revoking = %v, want only the badge", owned)
+	}
+	if got := store.users[user.ID].Points; got != 600 {
+		t.Errorf("points = %d, want the 600 spent on the revoked upgrades", got)
+	}
+	if got := store.users[user.ID].JobTitle; got != defaultJobTitle {
+		t.Errorf("job title = %q, want %q", got, defaultJobTitle)
+	}
+}
+
+func TestRevokeRollsBackEveryRemovalOnFailure(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{})
+	trainer, coach, _ := achievementChain(store)
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: trainer.ID, PointsSpent: 100})
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: coach.ID, PointsSpent: 200})
+	store.fail["AddPoints"] = errTest
+
+	if _, err := service.RevokeAchievement(context.Background(), 99, user.ID, trainer.ID, "", true); err == nil {
+		t.Fatal("RevokeAchievement succeeded although the refund failed")
+	}
+	if owned := store.owned(user.ID); len(owned) != 2 || len(store.auditLogs) != 0 {
+		t.Errorf("failed revocation left owned = %v and %d audit entries", owned, len(store.auditLogs))
+	}
+}
+
+func TestUnlockChecksRequirements(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{Points: 1000, Level: 3})
+	trainer, coach, _ := achievementChain(store)
+	veteran := store.addAchievement(&models.Achievement{Title: "Veteran", Type: "badge", PointsCost: 10, MinLevel: 5})
+	ended := time.Now().Add(-time.Hour)
+	seasonal := store.addAchievement(&models.Achievement{Title: "Summer Sprint", Type: "badge", PointsCost: 10, AvailableUntil: &ended})
+
+	tests := []struct {
+		name        string
+		achievement *models.Achievement
+		reason      string
+	}{
+		{"missing prerequisite", coach, `Requires "Personal Trainer"`},
+		{"level too low", veteran, "Requires level 5"},
+		{"no longer available", seasonal, "No longer available since " + ended.Format(time.RFC3339)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.UnlockAchievement(context.Background(), user.ID, tt.achievement.ID)
+			var requirementErr *RequirementError
+			if !errors.As(err, &requirementErr) || requirementErr.Reason != tt.reason {
+				t.Fatalf("err = %v, want requirement %q", err, tt.reason)
+			}
+		})
+	}
+	if store.users[user.ID].Points != 1000 || len(store.owned(user.ID)) != 0 {
+		t.Fatalf("rejected unlocks spent points or granted achievements")
+	}
+
+	// Owning the prerequisite makes the next upgrade available
+	if _, err := service.UnlockAchievement(context.Background(), user.ID, trainer.ID); err != nil {
+		t.Fatalf("unlock of the prerequisite: %v", err)
+	}
+	if _, err := service.UnlockAchievement(context.Background(), user.ID, coach.ID); err != nil {
+		t.Fatalf("unlock of the dependent: %v", err)
+	}
+	if got := store.users[user.ID].Points; got != 700 {
+		t.Errorf("points = %d, want 700", got)
+	}
+}
+
+func TestUnlockRespectsStockLimit(t *testing.T) {
+	service, store := newTestAchievementService()
+	stock := 1
+	limited := store.addAchievement(&models.Achievement{Title: "Founder", Type: "badge", PointsCost: 50, StockLimit: &stock})
+	first := store.addUser(&models.User{Points: 100})
+	second := store.addUser(&models.User{Points: 100})
+
+	if _, err := service.UnlockAchievement(context.Background(), first.ID, limited.ID); err != nil {
+		t.Fatalf("first unlock: %v", err)
+	}
+	_, err := service.UnlockAchievement(context.Background(), second.ID, limited.ID)
+	var requirementErr *RequirementError
+	if !errors.As(err, &requirementErr) || requirementErr.Reason != "Sold out" {
+		t.Fatalf("second unlock: err = %v, want Sold out", err)
+	}
+	if got := store.users[second.ID].Points; got != 100 {
+		t.Errorf("points after the sold out unlock = %d, want 100", got)
+	}
+}
+
+func TestUnlockRecordsAuditAndEvent(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{Points: 120})
+	badge := store.addAchievement(&models.Achievement{Title: "Early Bird", Type: "badge", PointsCost: 100})
+
+	if _, err := service.UnlockAchievement(context.Background(), user.ID, badge.ID); err != nil {
+		t.Fatalf("UnlockAchievement: %v", err)
+	}
+	if got := store.users[user.ID].Points; got != 20 {
+		t.Errorf("points = %d, want 20", got)
+	}
+	if len(store.auditLogs) != 1 || store.auditLogs[0].Action != models.AchievementActionUnlock || store.auditLogs[0].PointsDelta != -100 {
+		t.Errorf("audit log = %+v", store.auditLogs)
+	}
+	if len(store.events) != 1 || store.events[0].Type != models.EventAchievementUnlocked {
+		t.Errorf("events = %+v", store.events)
+	}
+	if store.locked[len(store.locked)-1] != user.ID {
+		t.Errorf("the balance was not checked under a lock on the user")
+	}
+
+	// A second purchase the user cannot afford changes nothing
+	other := store.addAchievement(&models.Achievement{Title: "Night Owl", Type: "badge", PointsCost: 50})
+	if _, err := service.UnlockAchievement(context.Background(), user.ID, other.ID); err == nil || err.Error() != "insufficient points to unlock achievement" {
+		t.Fatalf("unaffordable unlock: err = %v", err)
+	}
+	if len(store.auditLogs) != 1 || len(store.events) != 1 {
+		t.Errorf("unaffordable unlock wrote audit entries or events")
+	}
+}
+
+func TestUnlockRollsBackWhenEventFails(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{Points: 100})
+	badge := store.addAchievement(&models.Achievement{Title: "Early Bird", Type: "badge", PointsCost: 100})
+	store.fail["CreateEvent"] = errTest
+
+	if _, err := service.UnlockAchievement(context.Background(), user.ID, badge.ID); err == nil {
+		t.Fatal("UnlockAchievement succeeded although the event could not be recorded")
+	}
+	if store.users[user.ID].Points != 100 || len(store.owned(user.ID)) != 0 || len(store.auditLogs) != 0 {
+		t.Errorf("failed unlock left points = %d, owned = %v, audit = %d", store.users[user.ID].Points, store.owned(user.ID), len(store.auditLogs))
+	}
+}
+
+func TestUpdateLoadoutEquipsEverySlot(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{})
+	trainer, _, _ := achievementChain(store)
+	knight := store.addAchievement(&models.Achievement{Title: "Knight", Type: "character"})
+	earlyBird := store.addAchievement(&models.Achievement{Title: "Early Bird", Type: "badge"})
+	nightOwl := store.addAchievement(&models.Achievement{Title: "Night Owl", Type: "badge"})
+	for _, achievement := range []*models.Achievement{trainer, knight, earlyBird, nightOwl} {
+		store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: achievement.ID})
+	}
+
+	loadout, err := service.UpdateLoadout(context.Background(), user.ID, &models.UpdateLoadoutRequest{
+		CharacterID: &knight.ID,
+		JobTitleID:  &trainer.ID,
+		BadgeIDs:    []uint{nightOwl.ID, earlyBird.ID},
+	})
+	if err != nil {
+		t.Fatalf("UpdateLoadout: %v", err)
+	}
+	if loadout.Character == nil || loadout.Character.ID != knight.ID || loadout.JobTitle == nil || loadout.JobTitle.ID != trainer.ID {
+		t.Errorf("loadout = %+v", loadout)
+	}
+	if len(loadout.Badges) != 2 || loadout.Badges[0].ID != nightOwl.ID || loadout.Badges[1].ID != earlyBird.ID {
+		t.Errorf("badges = %+v, want Night Owl then Early Bird", loadout.Badges)
+	}
+	if got := store.users[user.ID]; got.Character != "Knight" || got.JobTitle != "Personal Trainer" {
+		t.Errorf("profile shows %q and %q", got.Character, got.JobTitle)
+	}
+	if len(store.locked) != 1 || store.locked[0] != user.ID {
+		t.Errorf("locked = %v, want the user locked once", store.locked)
+	}
+
+	// A zero ID unequips the slot and restores the default
+	zero := uint(0)
+	if _, err := service.UpdateLoadout(context.Background(), user.ID, &models.UpdateLoadoutRequest{JobTitleID: &zero}); err != nil {
+		t.Fatalf("unequip: %v", err)
+	}
+	if got := store.users[user.ID].JobTitle; got != defaultJobTitle {
+		t.Errorf("job title = %q, want %q", got, defaultJobTitle)
+	}
+}
+
+func TestUpdateLoadoutRejectsInvalidSlots(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{})
+	knight := store.addAchievement(&models.Achievement{Title: "Knight", Type: "character"})
+	badge := store.addAchievement(&models.Achievement{Title: "Early Bird", Type: "badge"})
+	unowned := store.addAchievement(&models.Achievement{Title: "Night Owl", Type: "badge"})
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: knight.ID})
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: badge.ID})
+
+	tests := []struct {
+		name string
+		req  *models.UpdateLoadoutRequest
+		want string
+	}{
+		{"too many badges", &models.UpdateLoadoutRequest{BadgeIDs: []uint{1, 2, 3, 4}}, "too many badges in loadout"},
+		{"duplicate badge", &models.UpdateLoadoutRequest{BadgeIDs: []uint{badge.ID, badge.ID}}, "duplicate badge in loadout"},
+		{"badge not owned", &models.UpdateLoadoutRequest{BadgeIDs: []uint{unowned.ID}}, "achievement not owned"},
+		{"wrong slot", &models.UpdateLoadoutRequest{JobTitleID: &knight.ID}, "achievement cannot be equipped in this slot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.UpdateLoadout(context.Background(), user.ID, tt.req); err == nil || err.Error() != tt.want {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateLoadoutRollsBackEverySlotOnFailure(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{Character: "Recruit"})
+	knight := store.addAchievement(&models.Achievement{Title: "Knight", Type: "character"})
+	badge := store.addAchievement(&models.Achievement{Title: "Early Bird", Type: "badge"})
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: knight.ID})
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: badge.ID})
+	store.fail["ReplaceEquipped:badge"] = errTest
+
+	_, err := service.UpdateLoadout(context.Background(), user.ID, &models.UpdateLoadoutRequest{
+		CharacterID: &knight.ID,
+		BadgeIDs:    []uint{badge.ID},
+	})
+	if err == nil {
+		t.Fatal("UpdateLoadout succeeded although the badges could not be equipped")
+	}
+	if got := store.users[user.ID].Character; got != "Recruit" {
+		t.Errorf("character = %q, want the change rolled back", got)
+	}
+	for _, userAchievement := range store.userAchievements {
+		if userAchievement.IsEquipped {
+			t.Errorf("%d is still equipped after the rollback", userAchievement.AchievementID)
+		}
+	}
+}
+
+func TestRefundWithinGracePeriod(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{Points: 10})
+	badge := store.addAchievement(&models.Achievement{Title: "Early Bird", Type: "badge", PointsCost: 100})
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: badge.ID, PointsSpent: 100, UnlockedAt: time.Now().Add(-23 * time.Hour)})
+
+	entry, err := service.RefundAchievement(context.Background(), user.ID, badge.ID, "Bought by mistake")
+	if err != nil {
+		t.Fatalf("RefundAchievement: %v", err)
+	}
+	if entry.Action != models.AchievementActionRefund || entry.ActorID != user.ID || entry.PointsDelta != 100 || entry.Reason != "Bought by mistake" {
+		t.Errorf("audit entry = %+v", entry)
+	}
+	if got := store.users[user.ID].Points; got != 110 {
+		t.Errorf("points = %d, want 110", got)
+	}
+	if store.owned(user.ID)[badge.ID] {
+		t.Error("the refunded badge is still owned")
+	}
+}
+
+func TestRefundRejected(t *testing.T) {
+	service, store := newTestAchievementService()
+	user := store.addUser(&models.User{})
+	expired := store.addAchievement(&models.Achievement{Title: "Early Bird", Type: "badge", PointsCost: 100})
+	earned := store.addAchievement(&models.Achievement{Title: "First Steps", Type: "badge"})
+	unowned := store.addAchievement(&models.Achievement{Title: "Night Owl", Type: "badge", PointsCost: 100})
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: expired.ID, PointsSpent: 100, UnlockedAt: time.Now().Add(-25 * time.Hour)})
+	store.addUserAchievement(&models.UserAchievement{UserID: user.ID, AchievementID: earned.ID, UnlockedAt: time.Now()})
+
+	tests := []struct {
+		name        string
+		achievement *models.Achievement
+		want        string
+	}{
+		{"outside the grace period", expired, "refund period has expired"},
+		{"not purchased", earned, "achievement was not purchased"},
+		{"not owned", unowned, "achievement not owned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.RefundAchievement(context.Background(), user.ID, tt.achievement.ID, ""); err == nil || err.Error() != tt.want {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if store.users[user.ID].Points != 0 || len(store.owned(user.ID)) != 2 || len(store.auditLogs) != 0 {
+		t.Errorf("rejected refunds changed the user's points or achievements")
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/logging"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+	"fithero-backend/tracing"
+	"gorm.io/gorm"
+)
+
+type AntiCheatService struct {
+	antiCheatRepo repositories.AntiCheatRepositoryInterface
+	taskRepo      repositories.TaskRepositoryInterface
+	config        *config.AntiCheatConfig
+}
+
+// NewAntiCheatService creates a new anti-cheat service
+func NewAntiCheatService(antiCheatRepo repositories.AntiCheatRepositoryInterface, taskRepo repositories.TaskRepositoryInterface, antiCheatConfig *config.AntiCheatConfig) *AntiCheatService {
+	return &AntiCheatService{
+		antiCheatRepo: antiCheatRepo,
+		taskRepo:      taskRepo,
+		config:        antiCheatConfig,
+	}
+}
+
+// EvaluateCompletion runs the completion heuristics for a task that was just completed
+// and records a flag for every rule it trips. Flags never block the completion itself.
+func (s *AntiCheatService) EvaluateCompletion(ctx context.Context, userID uint, dailyTask *models.DailyTask, completedAt time.Time) []models.AntiCheatFlag {
+	ctx, span := tracing.Start(ctx, "AntiCheatService.EvaluateCompletion")
+	defer span.End()
+
+	startOfDay := completedAt.UTC().Truncate(24 * time.Hour)
+	baselineStart := startOfDay.AddDate(0, 0, -s.config.BaselineDays)
+
+	completions, err := s.taskRepo.GetCompletedSince(ctx, userID, baselineStart)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Anti-cheat could not load completions", "user_id", userID, "error", err)
+		return nil
+	}
+
+	var flags []models.AntiCheatFlag
+
+	// Impossible completion speed: two completions closer together than any task could take
+	for _, completion := range completions {
+		if completion.ID == dailyTask.ID || completion.CompletedAt == nil || completion.CompletedAt.After(completedAt) {
+			continue
+		}
+		if interval := completedAt.Sub(*completion.CompletedAt); interval < s.config.MinCompletionInterval {
+			flags = s.raise(ctx, flags, userID, &dailyTask.ID, models.AntiCheatRuleCompletionSpeed,
+				fmt.Sprintf("Completed task %d only %s after task %d", dailyTask.ID, interval.Round(time.Second), completion.ID))
+		}
+		break // Completions are ordered newest first, only the previous one matters
+	}
+
+	// Daily totals far above the user's typical day
+	todayPoints, baselinePoints := 0, 0
+	for _, completion := range completions {
+		if completion.CompletedAt == nil {
+			continue
+		}
+		if completion.CompletedAt.Before(startOfDay) {
+			baselinePoints += completion.Points
+		} else {
+			todayPoints += completion.Points
+		}
+	}
+	typicalDaily := float64(baselinePoints) / float64(s.config.BaselineDays)
+	threshold := typicalDaily * s.config.DailySpikeMultiplier
+	if todayPoints >= s.config.DailySpikeMinPoints && float64(todayPoints) > threshold {
+		// Only flag the first spike of the day
+		if flagged, err := s.antiCheatRepo.HasFlagSince(ctx, userID, models.AntiCheatRuleDailyPointSpike, startOfDay); err == nil && !flagged {
+			flags = s.raise(ctx, flags, userID, &dailyTask.ID, models.AntiCheatRuleDailyPointSpike,
+				fmt.Sprintf("Earned %d points today, typical daily total is %.0f", todayPoints, typicalDaily))
+		}
+	}
+
+	return flags
+}
+
+// FlagDirectPointEdit records an attempt to set game-managed profile fields directly
+func (s *AntiCheatService) FlagDirectPointEdit(ctx context.Context, userID uint, fields []string) {
+	ctx, span := tracing.Start(ctx, "AntiCheatService.FlagDirectPointEdit")
+	defer span.End()
+
+	s.raise(ctx, nil, userID, nil, models.AntiCheatRuleDirectPointEdit,
+		fmt.Sprintf("Attempted to set %s through a profile update", strings.Join(fields, ", ")))
+}
+
+// GetFlags returns the review queue, filtered by status when non-empty
+func (s *AntiCheatService) GetFlags(ctx context.Context, status string) ([]models.AntiCheatFlag, error) {
+	ctx, span := tracing.Start(ctx, "AntiCheatService.GetFlags")
+	defer span.End()
+
+	return s.antiCheatRepo.GetFlags(ctx, status)
+}
+
+// ReviewFlag records an admin decision on a flag
+func (s *AntiCheatService) ReviewFlag(ctx context.Context, reviewerID, flagID uint, req *models.ReviewFlagRequest) (*models.AntiCheatFlag, error) {
+	ctx, span := tracing.Start(ctx, "AntiCheatService.ReviewFlag")
+	defer span.End()
+
+	flag, err := s.antiCheatRepo.GetFlagByID(ctx, flagID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("flag not found")
+		}
+		return nil, err
+	}
+
+	if flag.Status != models.FlagStatusOpen {
+		return nil, errors.New("flag already reviewed")
+	}
+
+	now := time.Now()
+	flag.Status = req.Status
+	flag.ReviewedBy = &reviewerID
+	flag.ReviewedAt = &now
+	flag.ReviewNote = req.Note
+
+	if err := s.antiCheatRepo.ReviewFlag(ctx, flag); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("flag already reviewed")
+		}
+		return nil, err
+	}
+	return flag, nil
+}
+
+// raise stores a flag and appends it to flags
+func (s *AntiCheatService) raise(ctx context.Context, flags []models.AntiCheatFlag, userID uint, dailyTaskID *uint, rule, details string) []models.AntiCheatFlag {
+	flag := models.AntiCheatFlag{
+		UserID:      userID,
+		Rule:        rule,
+		Details:     details,
+		DailyTaskID: dailyTaskID,
+		Status:      models.FlagStatusOpen,
+	}
+	if err := s.antiCheatRepo.CreateFlag(ctx, &flag); err != nil {
+		logging.FromContext(ctx).Warn("Failed to record anti-cheat flag", "user_id", userID, "rule", rule, "error", err)
+		return flags
+	}
+	return append(flags, flag)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+
+	"gorm.io/gorm"
+)
+
+// memoryAntiCheatRepo keeps anti-cheat flags in memory
+type memoryAntiCheatRepo struct {
+	repositories.AntiCheatRepositoryInterface
+	flags map[uint]*models.AntiCheatFlag
+	// reviewedMeanwhile makes a flag look open when loaded although another review closed it
+	reviewedMeanwhile bool
+}
+
+func (r *memoryAntiCheatRepo) CreateFlag(ctx context.Context, flag *models.AntiCheatFlag) error {
+	flag.ID = uint(len(r.flags) + 1)
+	stored := *flag
+	r.flags[flag.ID] = &stored
+	return nil
+}
+
+func (r *memoryAntiCheatRepo) GetFlagByID(ctx context.Context, id uint) (*models.AntiCheatFlag, error) {
+	flag, ok := r.flags[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *flag
+	if r.reviewedMeanwhile {
+		flag.Status = models.FlagStatusConfirmed
+	}
+	return &copied, nil
+}
+
+func (r *memoryAntiCheatRepo) ReviewFlag(ctx context.Context, flag *models.AntiCheatFlag) error {
+	stored, ok := r.flags[flag.ID]
+	if !ok || stored.Status != models.FlagStatusOpen {
+		return gorm.ErrRecordNotFound
+	}
+	*stored = *flag
+	return nil
+}
+
+func TestReviewFlagDecidesOnce(t *testing.T) {
+	repo := &memoryAntiCheatRepo{flags: map[uint]*models.AntiCheatFlag{}}
+	service := NewAntiCheatService(repo, nil, nil)
+	service.FlagDirectPointEdit(context.Background(), 7, []string{"points", "level"})
+	if len(repo.flags) != 1 || repo.flags[1].Rule != models.AntiCheatRuleDirectPointEdit || repo.flags[1].Status != models.FlagStatusOpen {
+		t.Fatalf("flags = %+v", repo.flags)
+	}
+
+	flag, err := service.ReviewFlag(context.Background(), 1, 1, &models.ReviewFlagRequest{Status: models.FlagStatusDismissed, Note: "Testing the API"})
+	if err != nil {
+		t.Fatalf("ReviewFlag: %v", err)
+	}
+	if flag.Status != models.FlagStatusDismissed || flag.ReviewedBy == nil || *flag.ReviewedBy != 1 || repo.flags[1].ReviewNote != "Testing the API" {
+		t.Errorf("reviewed flag = %+v", flag)
+	}
+
+	_, err = service.ReviewFlag(context.Background(), 2, 1, &models.ReviewFlagRequest{Status: models.FlagStatusConfirmed})
+	if err == nil || err.Error() != "flag already reviewed" {
+		t.Fatalf("second review: err = %v", err)
+	}
+	if repo.flags[1].Status != models.FlagStatusDismissed || *repo.flags[1].ReviewedBy != 1 {
+		t.Errorf("second review overwrote the first: %+v", repo.flags[1])
+	}
+
+	if _, err := service.ReviewFlag(context.Background(), 1, 99, &models.ReviewFlagRequest{Status: models.FlagStatusConfirmed}); err == nil || err.Error() != "flag not found" {
+		t.Errorf("unknown flag: err = %v", err)
+	}
+}
+
+func TestReviewFlagLosingRaceReportsAlreadyReviewed(t *testing.T) {
+	repo := &memoryAntiCheatRepo{flags: map[uint]*models.AntiCheatFlag{}}
+	service := NewAntiCheatService(repo, nil, nil)
+	service.FlagDirectPointEdit(context.Background(), 7, []string{"points"})
+
+	// Another admin confirms the flag after it was loaded as open
+	repo.reviewedMeanwhile = true
+	_, err := service.ReviewFlag(context.Background(), 2, 1, &models.ReviewFlagRequest{Status: models.FlagStatusDismissed})
+	if err == nil || err.Error() != "flag already reviewed" {
+		t.Fatalf("err = %v, want flag already reviewed", err)
+	}
+	if repo.flags[1].Status != models.FlagStatusConfirmed {
+		t.Errorf("status = %q, want the other admin's decision kept", repo.flags[1].Status)
+	}
+}
@@ -2,85 +2,252 @@ package services
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"fithero-backend/config"
+	"fithero-backend/logging"
+	"fithero-backend/metrics"
 	"fithero-backend/models"
 	"fithero-backend/repositories"
+	"fithero-backend/tracing"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/oauth2"
+	"gorm.io/gorm"
 )
 
 type AuthService struct {
-	userRepo   repositories.UserRepositoryInterface
-	authConfig *config.AuthConfig
+	userRepo     repositories.UserRepositoryInterface
+	sessionRepo  repositories.SessionRepositoryInterface
+	identityRepo repositories.IdentityRepositoryInterface
+	providers    *ProviderRegistry
+	authConfig   *config.AuthConfig
+	signer       *TokenSigner
+	usernames    *UsernamePolicy
 }
 
-func NewAuthService(userRepo repositories.UserRepositoryInterface, authConfig *config.AuthConfig) *AuthService {
+func NewAuthService(userRepo repositories.UserRepositoryInterface, sessionRepo repositories.SessionRepositoryInterface, identityRepo repositories.IdentityRepositoryInterface, providers *ProviderRegistry, authConfig *config.AuthConfig, signer *TokenSigner, usernames *UsernamePolicy) *AuthService {
 	return &AuthService{
-		userRepo:   userRepo,
-		authConfig: authConfig,
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		identityRepo: identityRepo,
+		providers:    providers,
+		authConfig:   authConfig,
+		signer:       signer,
+		usernames:    usernames,
 	}
 }
 
-// GetGoogleAuthURL generates the Google OAuth URL for authentication
-func (s *AuthService) GetGoogleAuthURL(state string) string {
-	return s.authConfig.GoogleOAuth.AuthCodeURL(state, oauth2.AccessTypeOffline)
+// ListProviders returns the identity providers users can sign in with
+func (s *AuthService) ListProviders() []models.IdentityProviderInfo {
+	return s.providers.List()
 }
 
-// HandleGoogleCallback processes the Google OAuth callback
-func (s *AuthService) HandleGoogleCallback(code string) (*models.AuthResponse, error) {
-	// Exchange authorization code for token
-	token, err := s.authConfig.GoogleOAuth.Exchange(context.Background(), code)
+// StartLogin begins an authorization code flow with PKCE at the named provider. The returned
+// flow must be kept by the client until the callback.
+func (s *AuthService) StartLogin(ctx context.Context, providerName string, link bool, returnTo string) (string, *models.OAuthFlow, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.StartLogin")
+	defer span.End()
+
+	provider, ok := s.providers.Get(providerName)
+	if !ok {
+		return "", nil, errors.New("unknown provider")
+	}
+
+	returnTo, err := s.ResolveReturnTo(returnTo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
+		return "", nil, err
 	}
 
-	// Get user info from Google
-	googleUser, err := s.getGoogleUserInfo(token.AccessToken)
+	state, err := generateRandomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	nonce, err := generateRandomToken()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user info from Google: %w", err)
+		return "", nil, err
+	}
+	flow := &models.OAuthFlow{
+		Provider:     providerName,
+		State:        state,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        nonce,
+		Link:         link,
+		ReturnTo:     returnTo,
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, flow.State, flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		return "", nil, err
+	}
+	return authURL, flow, nil
+}
+
+// ResolveReturnTo validates a post-login deep link. Paths are resolved against the frontend URL;
+// absolute URLs must fall under one of the allowed redirect URLs.
+func (s *AuthService) ResolveReturnTo(returnTo string) (string, error) {
+	if returnTo == "" {
+		return "", nil
+	}
+
+	target, err := url.Parse(returnTo)
+	if err != nil || strings.Contains(returnTo, "\\") || strings.Contains(target.Path, "..") {
+		return "", errors.New("invalid return_to")
+	}
+	if !target.IsAbs() {
+		// Only same-site paths; "//host" would be protocol-relative
+		if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") {
+			return "", errors.New("invalid return_to")
+		}
+		base, err := url.Parse(s.authConfig.FrontendURL)
+		if err != nil {
+			return "", err
+		}
+		target = base.ResolveReference(target)
+	}
+	if target.User != nil {
+		return "", errors.New("invalid return_to")
+	}
+
+	for _, allowedURL := range s.authConfig.AllowedRedirectURLs {
+		allowed, err := url.Parse(allowedURL)
+		if err != nil {
+			continue
+		}
+		// Match whole path segments, so /app does not allow /application
+		allowedDir := allowed.Path
+		if !strings.HasSuffix(allowedDir, "/") {
+			allowedDir += "/"
+		}
+		if target.Scheme == allowed.Scheme && target.Host == allowed.Host &&
+			(target.Path == allowed.Path || target.Path+"/" == allowedDir || strings.HasPrefix(target.Path, allowedDir)) {
+			return target.String(), nil
+		}
+	}
+	return "", errors.New("invalid return_to")
+}
+
+// HandleCallback completes a provider login and starts a new session
+func (s *AuthService) HandleCallback(ctx context.Context, flow *models.OAuthFlow, code string, client models.ClientInfo) (*models.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.HandleCallback")
+	defer span.End()
+
+	provider, ok := s.providers.Get(flow.Provider)
+	if !ok {
+		return nil, errors.New("unknown provider")
+	}
+
+	// Exchange authorization code for verified identity claims
+	userInfo, err := provider.Exchange(ctx, code, flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		return nil, err
 	}
 
 	// Find or create user
-	user, err := s.findOrCreateUser(googleUser)
+	user, err := s.findOrCreateUser(ctx, flow.Provider, userInfo)
 	if err != nil {
+		if err.Error() == "email not verified by provider" || err.Error() == "account email not verified" {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to find or create user: %w", err)
 	}
 
-	// Update last login time
-	now := time.Now()
-	user.LastLoginAt = &now
-	if err := s.userRepo.Update(user.ID, &models.UpdateUserRequest{}); err != nil {
-		// Don't fail auth if we can't update last login time
-		fmt.Printf("Warning: failed to update last login time for user %d: %v\n", user.ID, err)
+	return s.completeLogin(ctx, user, flow.Provider, client)
+}
+
+// LinkIdentity completes a provider flow by attaching the provider account to the signed-in user
+func (s *AuthService) LinkIdentity(ctx context.Context, flow *models.OAuthFlow, code string, userID uint) (*models.UserIdentity, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.LinkIdentity")
+	defer span.End()
+
+	provider, ok := s.providers.Get(flow.Provider)
+	if !ok {
+		return nil, errors.New("unknown provider")
 	}
 
-	// Generate JWT token
-	jwtToken, err := s.generateJWT(user)
+	userInfo, err := provider.Exchange(ctx, code, flow.CodeVerifier, flow.Nonce)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate JWT token: %w", err)
+		return nil, err
 	}
 
-	return &models.AuthResponse{
-		User:  *user,
-		Token: jwtToken,
-	}, nil
+	existing, err := s.identityRepo.GetByProviderSubject(ctx, flow.Provider, userInfo.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, errors.New("identity already linked to another account")
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	identity := &models.UserIdentity{
+		UserID:   userID,
+		Provider: flow.Provider,
+		Subject:  userInfo.Subject,
+		Email:    userInfo.Email,
+	}
+	if err := s.identityRepo.Create(ctx, identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// ListIdentities returns the provider accounts linked to a user
+func (s *AuthService) ListIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ListIdentities")
+	defer span.End()
+
+	return s.identityRepo.GetByUserID(ctx, userID)
+}
+
+// UnlinkIdentity removes a linked provider account, keeping at least one way to sign in
+func (s *AuthService) UnlinkIdentity(ctx context.Context, userID, identityID uint) error {
+	ctx, span := tracing.Start(ctx, "AuthService.UnlinkIdentity")
+	defer span.End()
+
+	identity, err := s.identityRepo.GetByID(ctx, identityID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("identity not found")
+		}
+		return err
+	}
+	if identity.UserID != userID {
+		return errors.New("identity not found")
+	}
+
+	identities, err := s.identityRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(identities) <= 1 {
+		return errors.New("cannot unlink last identity")
+	}
+
+	// Unlinking email login also removes the password
+	if identity.Provider == models.LocalProvider {
+		if err := s.userRepo.SetPasswordHash(ctx, userID, ""); err != nil {
+			return err
+		}
+	}
+
+	return s.identityRepo.Delete(ctx, identityID)
 }
 
 // ValidateJWT validates a JWT token and returns the claims
 func (s *AuthService) ValidateJWT(tokenString string) (*models.JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &models.JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.authConfig.JWTSecret), nil
-	})
+	token, err := s.signer.Parse(tokenString, &models.JWTClaims{})
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -93,134 +260,499 @@ func (s *AuthService) ValidateJWT(tokenString string) (*models.JWTClaims, error)
 	return nil, fmt.Errorf("invalid token")
 }
 
+// JWKS returns the public keys used to verify access tokens
+func (s *AuthService) JWKS() models.JSONWebKeySet {
+	return s.signer.JWKS()
+}
+
 // GetUserByID retrieves a user by ID (used for authorization checks)
-func (s *AuthService) GetUserByID(userID uint) (*models.User, error) {
-	return s.userRepo.GetByID(userID)
+func (s *AuthService) GetUserByID(ctx context.Context, userID uint) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetUserByID")
+	defer span.End()
+
+	return s.userRepo.GetByID(ctx, userID)
 }
 
-// RefreshToken generates a new JWT token for the user
-func (s *AuthService) RefreshToken(userID uint) (string, error) {
-	user, err := s.userRepo.GetByID(userID)
+// IsSessionActive reports whether the session an access token belongs to is still valid
+func (s *AuthService) IsSessionActive(ctx context.Context, sessionID uint) bool {
+	ctx, span := tracing.Start(ctx, "AuthService.IsSessionActive")
+	defer span.End()
+
+	if sessionID == 0 {
+		return false
+	}
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
 	if err != nil {
-		return "", fmt.Errorf("user not found: %w", err)
+		return false
 	}
+	return session.IsActive(time.Now())
+}
 
-	if !user.IsActive {
-		return "", fmt.Errorf("user account is disabled")
+// IssueCSRFToken creates a CSRF token bound to a session. Tokens are a random nonce and an
+// HMAC over the session ID and nonce, so they need no storage and cannot be reused across sessions.
+func (s *AuthService) IssueCSRFToken(sessionID uint) (string, error) {
+	nonce, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	return nonce + "." + s.csrfSignature(sessionID, nonce), nil
+}
+
+// ValidateCSRFToken reports whether a CSRF token was issued for the session
+func (s *AuthService) ValidateCSRFToken(sessionID uint, token string) bool {
+	nonce, signature, found := strings.Cut(token, ".")
+	if !found || nonce == "" || sessionID == 0 {
+		return false
 	}
+	return hmac.Equal([]byte(signature), []byte(s.csrfSignature(sessionID, nonce)))
+}
 
-	return s.generateJWT(user)
+// SealOAuthFlow encodes a flow for the browser to keep until the callback. The encoding carries
+// an HMAC, so the provider, nonce, link flag and return_to cannot be altered in between.
+func (s *AuthService) SealOAuthFlow(flow *models.OAuthFlow) (string, error) {
+	data, err := json.Marshal(flow)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + s.oauthFlowSignature(payload), nil
 }
 
-// Private methods
+// OpenOAuthFlow verifies and decodes a flow sealed by SealOAuthFlow
+func (s *AuthService) OpenOAuthFlow(sealed string) (*models.OAuthFlow, error) {
+	payload, signature, found := strings.Cut(sealed, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(s.oauthFlowSignature(payload))) {
+		return nil, errors.New("invalid oauth flow")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.New("invalid oauth flow")
+	}
+	var flow models.OAuthFlow
+	if err := json.Unmarshal(data, &flow); err != nil || flow.State == "" {
+		return nil, errors.New("invalid oauth flow")
+	}
+	return &flow, nil
+}
+
+// RefreshSession exchanges a refresh token for a new access token and a rotated refresh token.
+// Presenting an already used refresh token revokes the whole session.
+func (s *AuthService) RefreshSession(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RefreshSession")
+	defer span.End()
 
-func (s *AuthService) getGoogleUserInfo(accessToken string) (*models.GoogleUserInfo, error) {
-	url := "https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + accessToken
-	resp, err := http.Get(url)
+	if refreshToken == "" {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	stored, err := s.sessionRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid refresh token")
+		}
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get user info: status %d", resp.StatusCode)
+	now := time.Now()
+	session := &stored.Session
+	if !session.IsActive(now) {
+		return nil, errors.New("session revoked")
+	}
+	if now.After(stored.ExpiresAt) {
+		return nil, errors.New("invalid refresh token")
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	// Rotate: each refresh token can be used exactly once
+	fresh, err := s.sessionRepo.MarkRefreshTokenUsed(ctx, stored.ID, now)
 	if err != nil {
 		return nil, err
 	}
+	if !fresh {
+		if err := s.sessionRepo.Revoke(ctx, session.ID, models.SessionRevokedReuse); err != nil {
+			logging.FromContext(ctx).Warn("Failed to revoke session after refresh token reuse", "session_id", session.ID, "error", err)
+		}
+		return nil, errors.New("refresh token reuse detected")
+	}
 
-	var googleUser models.GoogleUserInfo
-	if err := json.Unmarshal(body, &googleUser); err != nil {
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if !user.IsActive {
+		return nil, fmt.Errorf("user account is disabled")
+	}
+
+	newRefreshToken, err := s.issueRefreshToken(ctx, session)
+	if err != nil {
 		return nil, err
 	}
+	if err := s.sessionRepo.Touch(ctx, session.ID, now); err != nil {
+		logging.FromContext(ctx).Warn("Failed to update last use of session", "session_id", session.ID, "error", err)
+	}
+
+	accessToken, expiresAt, err := s.generateJWT(user, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT token: %w", err)
+	}
 
-	return &googleUser, nil
+	return &models.AuthResponse{
+		User:         *user,
+		Token:        accessToken,
+		ExpiresAt:    expiresAt,
+		RefreshToken: newRefreshToken,
+		SessionID:    session.ID,
+	}, nil
 }
 
-func (s *AuthService) findOrCreateUser(googleUser *models.GoogleUserInfo) (*models.User, error) {
-	// Try to find existing user by Google ID
-	if existingUser, err := s.userRepo.GetByGoogleID(googleUser.ID); err == nil && existingUser != nil {
-		// Update user info from Google (in case they changed their profile)
-		updateReq := &models.UpdateUserRequest{
-			Email:     &googleUser.Email,
-			FirstName: &googleUser.GivenName,
-			LastName:  &googleUser.FamilyName,
+// Logout revokes the session identified by a refresh token or, failing that, by session ID
+func (s *AuthService) Logout(ctx context.Context, refreshToken string, sessionID uint) error {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	if refreshToken != "" {
+		if stored, err := s.sessionRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken)); err == nil {
+			sessionID = stored.SessionID
 		}
-		if err := s.userRepo.Update(existingUser.ID, updateReq); err != nil {
-			fmt.Printf("Warning: failed to update user info for user %d: %v\n", existingUser.ID, err)
+	}
+	if sessionID == 0 {
+		return nil
+	}
+	return s.sessionRepo.Revoke(ctx, sessionID, models.SessionRevokedLogout)
+}
+
+// ListSessions returns the user's active sessions, marking the current one
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentSessionID uint) ([]models.Session, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ListSessions")
+	defer span.End()
+
+	sessions, err := s.sessionRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes one of the user's sessions
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	ctx, span := tracing.Start(ctx, "AuthService.RevokeSession")
+	defer span.End()
+
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("session not found")
 		}
+		return err
+	}
+	if session.UserID != userID {
+		return errors.New("session not found")
+	}
+	return s.sessionRepo.Revoke(ctx, sessionID, models.SessionRevokedByUser)
+}
+
+// RevokeAllSessions revokes every session of the user, optionally keeping the current one
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID, keepSessionID uint) error {
+	ctx, span := tracing.Start(ctx, "AuthService.RevokeAllSessions")
+	defer span.End()
+
+	return s.sessionRepo.RevokeAllForUser(ctx, userID, keepSessionID, models.SessionRevokedAllByUser)
+}
+
+// Private methods
+
+// findOrCreateUser resolves the user for a provider account: by linked identity, by the legacy
+// Google ID column, by email, or by creating a new user.
+func (s *AuthService) findOrCreateUser(ctx context.Context, providerName string, userInfo *models.OIDCUserInfo) (*models.User, error) {
+	now := time.Now()
+
+	// Try to find the user through a linked identity
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, providerName, userInfo.Subject)
+	if err == nil {
+		existingUser, err := s.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		// Update user info from the provider (in case they changed their profile)
+		updateReq := &models.UpdateUserRequest{}
+		if userInfo.GivenName != "" {
+			updateReq.FirstName = &userInfo.GivenName
+		}
+		if userInfo.FamilyName != "" {
+			updateReq.LastName = &userInfo.FamilyName
+		}
+		if err := s.userRepo.Update(ctx, existingUser.ID, updateReq); err != nil {
+			logging.FromContext(ctx).Warn("Failed to update user info", "user_id", existingUser.ID, "error", err)
+		}
+		if err := s.identityRepo.Touch(ctx, identity.ID, userInfo.Email, now); err != nil {
+			logging.FromContext(ctx).Warn("Failed to update identity", "identity_id", identity.ID, "error", err)
+		}
+		s.confirmEmail(ctx, existingUser, userInfo)
 		return existingUser, nil
 	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	newIdentity := &models.UserIdentity{
+		Provider:    providerName,
+		Subject:     userInfo.Subject,
+		Email:       userInfo.Email,
+		LastLoginAt: &now,
+	}
 
-	// Try to find existing user by email
-	if existingUser, err := s.userRepo.GetByEmail(googleUser.Email); err == nil && existingUser != nil {
-		// Link the Google account to existing user
-		existingUser.GoogleID = googleUser.ID
-		existingUser.FirstName = googleUser.GivenName
-		existingUser.LastName = googleUser.FamilyName
-		existingUser.Picture = googleUser.Picture
-		
-		if err := s.userRepo.Update(existingUser.ID, &models.UpdateUserRequest{
-			FirstName: &googleUser.GivenName,
-			LastName:  &googleUser.FamilyName,
-		}); err != nil {
-			fmt.Printf("Warning: failed to link Google account for user %d: %v\n", existingUser.ID, err)
-		}
-		
+	// Users who signed in with Google before identities existed are matched by Google ID,
+	// then by email
+	existingUser, err := s.findUserToLink(ctx, providerName, userInfo)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser != nil {
+		newIdentity.UserID = existingUser.ID
+		if err := s.identityRepo.Create(ctx, newIdentity); err != nil {
+			return nil, fmt.Errorf("failed to link %s account: %w", providerName, err)
+		}
+		s.confirmEmail(ctx, existingUser, userInfo)
 		return existingUser, nil
 	}
 
+	if userInfo.Email == "" {
+		return nil, errors.New("identity provider did not return an email address")
+	}
+
 	// Create new user
 	newUser := &models.User{
-		GoogleID:  googleUser.ID,
-		Email:     googleUser.Email,
-		Username:  generateUsernameFromEmail(googleUser.Email),
-		FirstName: googleUser.GivenName,
-		LastName:  googleUser.FamilyName,
-		Picture:   googleUser.Picture,
+		Email:     userInfo.Email,
+		FirstName: userInfo.GivenName,
+		LastName:  userInfo.FamilyName,
+		Picture:   userInfo.Picture,
 		Level:     1,
 		Points:    0,
 		Character: "Rookie Hero",
 		JobTitle:  "Fitness Novice",
 		IsActive:  true,
 	}
+	if userInfo.EmailVerified {
+		newUser.EmailVerifiedAt = &now
+	}
+
+	return s.createUserWithIdentity(ctx, newUser, newIdentity)
+}
+
+// createUserWithIdentity creates a user with a username generated from their email address.
+// Generation is retried when a concurrent sign-up claims the same username first.
+func (s *AuthService) createUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) (*models.User, error) {
+	for attempt := 0; ; attempt++ {
+		username, err := s.usernames.Generate(ctx, user.Email)
+		if err != nil {
+			return nil, err
+		}
+		user.Username = username
+
+		created, err := s.identityRepo.CreateUserWithIdentity(ctx, user, identity)
+		if err != nil && attempt < 2 && isUniqueViolation(err, "username") {
+			user.ID = 0
+			identity.ID = 0
+			continue
+		}
+		return created, err
+	}
+}
+
+// confirmEmail marks the user's email verified when the provider vouched for the same address.
+// A password set by an unverified sign-up is discarded, since whoever registered it never
+// proved they own the address.
+func (s *AuthService) confirmEmail(ctx context.Context, user *models.User, userInfo *models.OIDCUserInfo) {
+	if user.EmailVerifiedAt != nil || !userInfo.EmailVerified || !strings.EqualFold(user.Email, userInfo.Email) {
+		return
+	}
+
+	if user.PasswordHash != "" {
+		if err := s.userRepo.SetPasswordHash(ctx, user.ID, ""); err != nil {
+			logging.FromContext(ctx).Warn("Failed to clear unverified password", "user_id", user.ID, "error", err)
+			return
+		}
+		user.PasswordHash = ""
+	}
+
+	now := time.Now()
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+		logging.FromContext(ctx).Warn("Failed to mark email verified", "user_id", user.ID, "error", err)
+		return
+	}
+	user.EmailVerifiedAt = &now
+}
+
+// findUserToLink finds an existing user a new provider account belongs to, or nil
+func (s *AuthService) findUserToLink(ctx context.Context, providerName string, userInfo *models.OIDCUserInfo) (*models.User, error) {
+	if providerName == "google" {
+		if existingUser, err := s.userRepo.GetByGoogleID(ctx, userInfo.Subject); err == nil {
+			return existingUser, nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	if userInfo.Email != "" {
+		if existingUser, err := s.userRepo.GetByEmail(ctx, userInfo.Email); err == nil {
+			// Only link by email when the provider vouches for the address, otherwise anyone
+			// could take over an account by registering its email at a lax provider
+			if !userInfo.EmailVerified {
+				return nil, errors.New("email not verified by provider")
+			}
+			// Nor to an account whose own address was never confirmed, which anyone could have
+			// registered in advance to capture the owner's sign-in
+			if existingUser.EmailVerifiedAt == nil {
+				return nil, errors.New("account email not verified")
+			}
+			return existingUser, nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *AuthService) isAdminEmail(email string) bool {
+	for _, adminEmail := range s.authConfig.AdminEmails {
+		if strings.EqualFold(adminEmail, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// completeLogin promotes configured administrators, records the login and starts a new session
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, provider string, client models.ClientInfo) (*models.AuthResponse, error) {
+	if !user.IsActive {
+		return nil, errors.New("user account is disabled")
+	}
+
+	// Promote configured administrators, once they have proven they own the address
+	if user.Role != models.RoleAdmin && user.EmailVerifiedAt != nil && s.isAdminEmail(user.Email) {
+		if err := s.userRepo.UpdateRole(ctx, user.ID, models.RoleAdmin); err != nil {
+			logging.FromContext(ctx).Warn("Failed to grant admin role", "user_id", user.ID, "error", err)
+		} else {
+			user.Role = models.RoleAdmin
+		}
+	}
+
+	// Update last login time
+	now := time.Now()
+	user.LastLoginAt = &now
+	if err := s.userRepo.Update(ctx, user.ID, &models.UpdateUserRequest{}); err != nil {
+		// Don't fail auth if we can't update last login time
+		logging.FromContext(ctx).Warn("Failed to update last login time", "user_id", user.ID, "error", err)
+	}
+
+	// Start a session for this device
+	authResponse, err := s.createSession(ctx, user, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	metrics.Logins.WithLabelValues(provider).Inc()
+
+	return authResponse, nil
+}
+
+// createSession starts a new session and issues its first access and refresh tokens
+func (s *AuthService) createSession(ctx context.Context, user *models.User, client models.ClientInfo) (*models.AuthResponse, error) {
+	now := time.Now()
+	session, err := s.sessionRepo.Create(ctx, &models.Session{
+		UserID:     user.ID,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.authConfig.RefreshTokenExpiration),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx, session)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.userRepo.Create(newUser)
+	accessToken, expiresAt, err := s.generateJWT(user, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT token: %w", err)
+	}
+
+	return &models.AuthResponse{
+		User:         *user,
+		Token:        accessToken,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+		SessionID:    session.ID,
+	}, nil
 }
 
-func (s *AuthService) generateJWT(user *models.User) (string, error) {
+// issueRefreshToken creates a new refresh token for the session, storing only its hash
+func (s *AuthService) issueRefreshToken(ctx context.Context, session *models.Session) (string, error) {
+	token, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.sessionRepo.CreateRefreshToken(ctx, &models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: session.ExpiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AuthService) generateJWT(user *models.User, sessionID uint) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.authConfig.AccessTokenExpiration)
 	claims := &models.JWTClaims{
-		UserID:   user.ID,
-		Email:    user.Email,
-		Username: user.Username,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Username:  user.Username,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.authConfig.JWTExpiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "fithero-backend",
 			Subject:   fmt.Sprintf("%d", user.ID),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.authConfig.JWTSecret))
+	signed, err := s.signer.Sign(claims)
+	return signed, expiresAt, err
 }
 
-// generateUsernameFromEmail creates a username from email address
-func generateUsernameFromEmail(email string) string {
-	// Simple implementation - take part before @ and add random suffix if needed
-	// In production, you might want to check for uniqueness and add numbers
-	atIndex := 0
-	for i, char := range email {
-		if char == '@' {
-			atIndex = i
-			break
-		}
-	}
-	if atIndex > 0 {
-		return email[:atIndex]
+func (s *AuthService) oauthFlowSignature(payload string) string {
+	mac := hmac.New(sha256.New, s.authConfig.CSRFSecret)
+	fmt.Fprintf(mac, "oauth-flow:%s", payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *AuthService) csrfSignature(sessionID uint, nonce string) string {
+	mac := hmac.New(sha256.New, s.authConfig.CSRFSecret)
+	fmt.Fprintf(mac, "%d:%s", sessionID, nonce)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// generateRandomToken returns 32 random bytes, base64url encoded
+func generateRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	return "user"
-} 
\ No newline at end of file
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 hex digest used to store opaque tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+)
+
+func TestResolveReturnTo(t *testing.T) {
+	service := NewAuthService(nil, nil, nil, NewProviderRegistry(nil), &config.AuthConfig{
+		FrontendURL:         "https://fithero.example.com",
+		AllowedRedirectURLs: []string{"https://fithero.example.com", "https://partner.example.com/app"},
+	}, nil, nil)
+
+	tests := []struct {
+		returnTo string
+		want     string
+		wantErr  bool
+	}{
+		{returnTo: "", want: ""},
+		{returnTo: "/quests/7", want: "https://fithero.example.com/quests/7"},
+		{returnTo: "https://partner.example.com/app", want: "https://partner.example.com/app"},
+		{returnTo: "https://partner.example.com/app/", want: "https://partner.example.com/app/"},
+		{returnTo: "https://partner.example.com/app/callback?x=1", want: "https://partner.example.com/app/callback?x=1"},
+		{returnTo: "https://partner.example.com/application", wantErr: true},
+		{returnTo: "https://partner.example.com/app-evil/callback", wantErr: true},
+		{returnTo: "https://partner.example.com/", wantErr: true},
+		{returnTo: "https://evil.example.com/app", wantErr: true},
+		{returnTo: "//evil.example.com/quests", wantErr: true},
+		{returnTo: "/app/../admin", wantErr: true},
+		{returnTo: "https://user@partner.example.com/app", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := service.ResolveReturnTo(tt.returnTo)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ResolveReturnTo(%q) = %q, want an error", tt.returnTo, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ResolveReturnTo(%q) = %q, %v; want %q", tt.returnTo, got, err, tt.want)
+		}
+	}
+}
+
+func TestOAuthFlowSealing(t *testing.T) {
+	service := NewAuthService(nil, nil, nil, NewProviderRegistry(nil), &config.AuthConfig{
+		CSRFSecret: []byte(strings.Repeat("k", 32)),
+	}, nil, nil)
+	flow := &models.OAuthFlow{Provider: "test", State: "state", CodeVerifier: "verifier", Nonce: "nonce", ReturnTo: "https://fithero.example.com/quests"}
+
+	sealed, err := service.SealOAuthFlow(flow)
+	if err != nil {
+		t.Fatalf("SealOAuthFlow: %v", err)
+	}
+	opened, err := service.OpenOAuthFlow(sealed)
+	if err != nil || *opened != *flow {
+		t.Fatalf("OpenOAuthFlow = %+v, %v; want %+v", opened, err, flow)
+	}
+
+	altered := *flow
+	altered.ReturnTo = "https://evil.example.com"
+	alteredSealed, err := service.SealOAuthFlow(&altered)
+	if err != nil {
+		t.Fatalf("SealOAuthFlow: %v", err)
+	}
+	alteredPayload, _, _ := strings.Cut(alteredSealed, ".")
+	_, signature, _ := strings.Cut(sealed, ".")
+	for _, value := range []string{alteredPayload + "." + signature, alteredPayload, sealed + "x", ""} {
+		if _, err := service.OpenOAuthFlow(value); err == nil {
+			t.Errorf("OpenOAuthFlow(%q) accepted an altered flow", value)
+		}
+	}
+
+	other := NewAuthService(nil, nil, nil, NewProviderRegistry(nil), &config.AuthConfig{
+		CSRFSecret: []byte(strings.Repeat("o", 32)),
+	}, nil, nil)
+	if _, err := other.OpenOAuthFlow(sealed); err == nil {
+		t.Error("a flow sealed with another key was accepted")
+	}
+}
+
+// newTestSessionService returns an AuthService with sessions in memory and the user signed in
+func newTestSessionService(t *testing.T, user *models.User) (*AuthService, *memorySessionRepo, *models.AuthResponse) {
+	t.Helper()
+	authConfig := &config.AuthConfig{
+		JWTSecret:              strings.Repeat("s", 32),
+		AccessTokenExpiration:  15 * time.Minute,
+		RefreshTokenExpiration: 24 * time.Hour,
+	}
+	signer, err := NewTokenSigner(authConfig)
+	if err != nil {
+		t.Fatalf("NewTokenSigner: %v", err)
+	}
+	sessionRepo := &memorySessionRepo{}
+	userRepo := &memoryUserRepo{users: map[uint]*models.User{user.ID: user}}
+	service := NewAuthService(userRepo, sessionRepo, nil, NewProviderRegistry(nil), authConfig, signer, nil)
+
+	login, err := service.createSession(context.Background(), user, models.ClientInfo{UserAgent: "test"})
+	if err != nil {
+		t.Fatalf("createSession: %v", err)
+	}
+	return service, sessionRepo, login
+}
+
+func TestRefreshSessionRotatesRefreshToken(t *testing.T) {
+	service, _, login := newTestSessionService(t, &models.User{ID: 1, Email: "hero@example.com", IsActive: true})
+
+	refreshed, err := service.RefreshSession(context.Background(), login.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshSession: %v", err)
+	}
+	if refreshed.SessionID != login.SessionID || refreshed.RefreshToken == login.RefreshToken || refreshed.Token == "" {
+		t.Fatalf("refresh returned %+v, want a rotated token for session %d", refreshed, login.SessionID)
+	}
+	claims, err := service.ValidateJWT(refreshed.Token)
+	if err != nil || claims.UserID != 1 || claims.SessionID != login.SessionID {
+		t.Errorf("access token claims = %+v, %v", claims, err)
+	}
+
+	// The rotated token works once in turn
+	if _, err := service.RefreshSession(context.Background(), refreshed.RefreshToken); err != nil {
+		t.Errorf("refresh with the rotated token: %v", err)
+	}
+	if _, err := service.RefreshSession(context.Background(), "unknown"); err == nil || err.Error() != "invalid refresh token" {
+		t.Errorf("unknown token: err = %v", err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	service, sessionRepo, login := newTestSessionService(t, &models.User{ID: 1, IsActive: true})
+
+	refreshed, err := service.RefreshSession(context.Background(), login.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshSession: %v", err)
+	}
+
+	// A stolen copy of the first token is presented after the legitimate client rotated it
+	if _, err := service.RefreshSession(context.Background(), login.RefreshToken); err == nil || err.Error() != "refresh token reuse detected" {
+		t.Fatalf("reuse: err = %v, want refresh token reuse detected", err)
+	}
+	session := sessionRepo.sessions[login.SessionID-1]
+	if session.RevokedAt == nil || session.RevokedReason != models.SessionRevokedReuse {
+		t.Fatalf("session after reuse = %+v, want it revoked for reuse", session)
+	}
+
+	// Every token of the session stops working, including the legitimately rotated one
+	if _, err := service.RefreshSession(context.Background(), refreshed.RefreshToken); err == nil || err.Error() != "session revoked" {
+		t.Errorf("rotated token after reuse: err = %v, want session revoked", err)
+	}
+	if service.IsSessionActive(context.Background(), login.SessionID) {
+		t.Error("access tokens of the revoked session are still accepted")
+	}
+}
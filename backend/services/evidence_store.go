@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// evidencePhotoTypes maps the image types accepted as evidence, detected from the uploaded
+// content rather than the client's filename or header, to the extensions they are stored with
+var evidencePhotoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// ErrUnsupportedPhoto is returned for evidence photos that are not a JPEG, PNG or WebP image
+var ErrUnsupportedPhoto = errors.New("photo must be a JPEG, PNG or WebP image")
+
+// EvidenceStore persists photos uploaded as task completion evidence
+type EvidenceStore interface {
+	// SavePhoto stores the photo and returns the URL it can be retrieved from
+	SavePhoto(userID uint, content io.Reader) (string, error)
+	// OpenPhoto opens a photo by the URL SavePhoto returned and reports its content type
+	OpenPhoto(photoURL string) (io.ReadSeekCloser, string, error)
+	// DeletePhoto removes a photo by the URL SavePhoto returned
+	DeletePhoto(photoURL string) error
+}
+
+// LocalEvidenceStore stores evidence photos on the local filesystem
+type LocalEvidenceStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalEvidenceStore creates an evidence store writing to dir and served from baseURL
+func NewLocalEvidenceStore(dir, baseURL string) (*LocalEvidenceStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create evidence directory: %w", err)
+	}
+	return &LocalEvidenceStore{
+		dir:     dir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+// SavePhoto checks the photo's content type and writes it under a random name, to avoid
+// collisions and path traversal
+func (s *LocalEvidenceStore) SavePhoto(userID uint, content io.Reader) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	head = head[:n]
+	ext, ok := evidencePhotoTypes[http.DetectContentType(head)]
+	if !ok {
+		return "", ErrUnsupportedPhoto
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%d-%s%s", userID, hex.EncodeToString(b), ext)
+	path := filepath.Join(s.dir, name)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, io.MultiReader(bytes.NewReader(head), content)); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	return s.baseURL + "/" + name, nil
+}
+
+// OpenPhoto opens a stored photo. Files with an extension SavePhoto does not produce are
+// reported as missing, so nothing but the accepted image types is ever served.
+func (s *LocalEvidenceStore) OpenPhoto(photoURL string) (io.ReadSeekCloser, string, error) {
+	path, contentType, err := s.photoPath(photoURL)
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, contentType, nil
+}
+
+// DeletePhoto removes a stored photo, e.g. when the completion it was uploaded for is not saved
+func (s *LocalEvidenceStore) DeletePhoto(photoURL string) error {
+	path, _, err := s.photoPath(photoURL)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// photoPath returns the file and content type of a photo URL SavePhoto returned
+func (s *LocalEvidenceStore) photoPath(photoURL string) (string, string, error) {
+	name, ok := strings.CutPrefix(photoURL, s.baseURL+"/")
+	if !ok || name == "" || filepath.Base(name) != name {
+		return "", "", os.ErrNotExist
+	}
+
+	ext := filepath.Ext(name)
+	for contentType, typeExt := range evidencePhotoTypes {
+		if ext == typeExt {
+			return filepath.Join(s.dir, name), contentType, nil
+		}
+	}
+	return "", "", os.ErrNotExist
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+)
+
+// HealthChecker is implemented by dependencies that can report whether they are usable
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+type healthCheck struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error
+}
+
+// HealthService checks the backend's dependencies for the readiness probe. Reports are
+// cached briefly so frequent probes don't add load to the database.
+type HealthService struct {
+	checks   []healthCheck
+	config   *config.HealthConfig
+	draining atomic.Bool
+
+	mu       sync.Mutex
+	report   *models.HealthReport
+	cachedAt time.Time
+}
+
+// NewHealthService creates a health service checking the database connection and schema version
+func NewHealthService(healthRepo repositories.HealthRepositoryInterface, cfg *config.HealthConfig) *HealthService {
+	s := &HealthService{config: cfg}
+	s.AddCheck("database", true, healthRepo.Ping)
+	s.AddCheck("migrations", true, func(ctx context.Context) error {
+		version, err := healthRepo.GetSchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version < config.SchemaVersion {
+			return fmt.Errorf("schema version %d, expected %d", version, config.SchemaVersion)
+		}
+		return nil
+	})
+	return s
+}
+
+// AddCheck registers a dependency check. The backend is not ready while a required check
+// fails; a failing optional check only marks it as degraded.
+func (s *HealthService) AddCheck(name string, required bool, check func(ctx context.Context) error) {
+	s.checks = append(s.checks, healthCheck{name: name, required: required, check: check})
+}
+
+// SetDraining makes the backend report itself not ready from now on, so load balancers stop
+// sending it requests before it shuts down
+func (s *HealthService) SetDraining() {
+	s.draining.Store(true)
+}
+
+// Readiness returns the latest report, checking all dependencies concurrently when the cached
+// report has expired
+func (s *HealthService) Readiness(ctx context.Context) *models.HealthReport {
+	if s.draining.Load() {
+		return &models.HealthReport{
+			Status: models.HealthStatusUnavailable,
+			Checks: map[string]models.HealthCheckResult{
+				"shutdown": {Status: models.HealthStatusFail, Required: true, Error: "server is shutting down"},
+			},
+			CheckedAt: time.Now(),
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report != nil && time.Since(s.cachedAt) < s.config.CacheTTL {
+		return s.report
+	}
+
+	// The cached report is shared, so a probe that gives up mustn't fail the checks for others
+	ctx = context.WithoutCancel(ctx)
+	results := make([]models.HealthCheckResult, len(s.checks))
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func(i int, check healthCheck) {
+			defer wg.Done()
+			results[i] = s.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := &models.HealthReport{
+		Status:    models.HealthStatusOK,
+		Checks:    make(map[string]models.HealthCheckResult, len(s.checks)),
+		CheckedAt: time.Now(),
+	}
+	for i, check := range s.checks {
+		result := results[i]
+		report.Checks[check.name] = result
+		if result.Status == models.HealthStatusOK {
+			continue
+		}
+		if check.required {
+			report.Status = models.HealthStatusUnavailable
+		} else if report.Status == models.HealthStatusOK {
+			report.Status = models.HealthStatusDegraded
+		}
+	}
+
+	s.report = report
+	s.cachedAt = time.Now()
+	return report
+}
+
+// run performs one check within the configured timeout
+func (s *HealthService) run(ctx context.Context, check healthCheck) models.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, s.config.CheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.check(ctx)
+	result := models.HealthCheckResult{
+		Status:    models.HealthStatusOK,
+		Required:  check.required,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = models.HealthStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
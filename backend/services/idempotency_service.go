@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/logging"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+	"fithero-backend/tracing"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyService records requests made with an Idempotency-Key so retries replay the
+// first response instead of repeating the work
+type IdempotencyService struct {
+	idempotencyRepo repositories.IdempotencyRepositoryInterface
+	config          *config.IdempotencyConfig
+	stop            chan struct{}
+	once            sync.Once
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService(idempotencyRepo repositories.IdempotencyRepositoryInterface, idempotencyConfig *config.IdempotencyConfig) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		config:          idempotencyConfig,
+		stop:            make(chan struct{}),
+	}
+}
+
+// Begin claims an idempotency key for a request. When the key was already used for the same
+// request, the stored record is returned with replay set and its response should be sent again.
+func (s *IdempotencyService) Begin(ctx context.Context, userID uint, key, method, path, fingerprint string) (*models.IdempotencyKey, bool, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Begin")
+	defer span.End()
+
+	now := time.Now()
+	record := &models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Method:      method,
+		Path:        path,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(s.config.KeyTTL),
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := s.idempotencyRepo.Create(ctx, record)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			return record, false, nil
+		}
+
+		existing, err := s.idempotencyRepo.GetByUserKey(ctx, userID, key)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue // Removed in the meantime
+			}
+			return nil, false, err
+		}
+
+		if now.After(existing.ExpiresAt) {
+			if err := s.idempotencyRepo.Delete(ctx, existing.ID); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		if existing.Fingerprint != fingerprint {
+			return nil, false, errors.New("idempotency key reused with different request")
+		}
+		if existing.CompletedAt == nil {
+			// A request that never finished, e.g. because the server crashed, frees its key after a while
+			if now.Sub(existing.CreatedAt) < s.config.LockTimeout {
+				return nil, false, errors.New("request with this idempotency key in progress")
+			}
+			if err := s.idempotencyRepo.Delete(ctx, existing.ID); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		return existing, true, nil
+	}
+
+	return nil, false, errors.New("request with this idempotency key in progress")
+}
+
+// Complete stores the response of a request so retries can replay it
+func (s *IdempotencyService) Complete(ctx context.Context, record *models.IdempotencyKey, statusCode int, contentType string, response []byte) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Complete")
+	defer span.End()
+
+	return s.idempotencyRepo.Complete(ctx, record.ID, statusCode, contentType, response, time.Now())
+}
+
+// Release frees the key of a request that failed without a result worth replaying, so the client can retry it
+func (s *IdempotencyService) Release(ctx context.Context, record *models.IdempotencyKey) error {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Release")
+	defer span.End()
+
+	return s.idempotencyRepo.Delete(ctx, record.ID)
+}
+
+// StartCleanup deletes expired keys in the background until ctx is done or Close is called
+func (s *IdempotencyService) StartCleanup(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.config.CleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if deleted, err := s.idempotencyRepo.DeleteExpired(ctx, time.Now()); err != nil {
+					logging.FromContext(ctx).Warn("Failed to delete expired idempotency keys", "error", err)
+				} else if deleted > 0 {
+					logging.FromContext(ctx).Debug("Deleted expired idempotency keys", "count", deleted)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the background cleanup
+func (s *IdempotencyService) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// IdentityProvider signs users in through an external OpenID Connect provider
+type IdentityProvider interface {
+	Info() models.IdentityProviderInfo
+	// AuthCodeURL returns the provider's authorization URL for the given state, PKCE verifier and nonce
+	AuthCodeURL(ctx context.Context, state, codeVerifier, nonce string) (string, error)
+	// Exchange redeems an authorization code and returns the verified ID token claims
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*models.OIDCUserInfo, error)
+}
+
+// ProviderRegistry holds the identity providers users can sign in with
+type ProviderRegistry struct {
+	mu        sync.RWMutex
+	providers map[string]IdentityProvider
+	order     []string
+}
+
+// NewProviderRegistry creates a registry with an OIDC provider for each configuration
+func NewProviderRegistry(configs []config.OIDCProviderConfig) *ProviderRegistry {
+	registry := &ProviderRegistry{providers: make(map[string]IdentityProvider)}
+	for _, cfg := range configs {
+		registry.Register(cfg.Name, NewOIDCProvider(cfg))
+	}
+	return registry
+}
+
+// Register adds or replaces a provider, e.g. a provider backed by a mock OIDC server
+func (r *ProviderRegistry) Register(name string, provider IdentityProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.providers[name]; !exists {
+		r.order = append(r.order, name)
+	}
+	r.providers[name] = provider
+}
+
+// Get returns the provider registered under name
+func (r *ProviderRegistry) Get(name string) (IdentityProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// List returns the registered providers in registration order
+func (r *ProviderRegistry) List() []models.IdentityProviderInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	infos := make([]models.IdentityProviderInfo, 0, len(r.order))
+	for _, name := range r.order {
+		infos = append(infos, r.providers[name].Info())
+	}
+	return infos
+}
+
+// OIDCProvider is an IdentityProvider for any issuer supporting OpenID Connect discovery.
+// Discovery runs on first use and is retried until it succeeds, so an unreachable
+// provider does not prevent the server from starting.
+type OIDCProvider struct {
+	cfg        config.OIDCProviderConfig
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider creates a provider for the configured issuer
+func NewOIDCProvider(cfg config.OIDCProviderConfig) *OIDCProvider {
+	return &OIDCProvider{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Info returns the provider's name and display name
+func (p *OIDCProvider) Info() models.IdentityProviderInfo {
+	return models.IdentityProviderInfo{Name: p.cfg.Name, DisplayName: p.cfg.DisplayName}
+}
+
+// AuthCodeURL returns the authorization URL using PKCE (S256) and a nonce
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, codeVerifier, nonce string) (string, error) {
+	oauthConfig, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauthConfig.AuthCodeURL(state, oauth2.S256ChallengeOption(codeVerifier), oidc.Nonce(nonce)), nil
+}
+
+// Exchange redeems the authorization code and verifies the returned ID token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*models.OIDCUserInfo, error) {
+	oauthConfig, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = oidc.ClientContext(ctx, p.httpClient)
+
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response did not include an ID token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("ID token nonce mismatch")
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode ID token claims: %w", err)
+	}
+	info := models.OIDCUserInfo{Subject: idToken.Subject}
+	claims.applyTo(&info)
+
+	// Some providers only return profile claims from the userinfo endpoint, which is
+	// called with the access token in the Authorization header
+	if info.Email == "" {
+		userInfo, err := p.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user info: %w", err)
+		}
+		if userInfo.Subject != info.Subject {
+			return nil, errors.New("user info subject mismatch")
+		}
+		var userInfoClaims oidcClaims
+		if err := userInfo.Claims(&userInfoClaims); err != nil {
+			return nil, fmt.Errorf("failed to decode user info: %w", err)
+		}
+		userInfoClaims.applyTo(&info)
+	}
+
+	return &info, nil
+}
+
+// discover loads the issuer's metadata and signing keys once
+func (p *OIDCProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider == nil {
+		// The provider keeps this context for later JWKS refreshes, so it must outlive the request
+		provider, err := oidc.NewProvider(oidc.ClientContext(context.WithoutCancel(ctx), p.httpClient), p.cfg.IssuerURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to discover OIDC provider %s: %w", p.cfg.Name, err)
+		}
+		scopes := p.cfg.Scopes
+		if !containsScope(scopes, oidc.ScopeOpenID) {
+			scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+		}
+
+		p.provider = provider
+		p.oauth2 = &oauth2.Config{
+			ClientID:     p.cfg.ClientID,
+			ClientSecret: p.cfg.ClientSecret,
+			RedirectURL:  p.cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		}
+		// The verifier fetches the issuer's keys lazily and refreshes them on unknown key IDs
+		p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	}
+
+	return p.oauth2, p.verifier, nil
+}
+
+// oidcClaims are the profile claims read from ID tokens and userinfo responses
+type oidcClaims struct {
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"` // Some providers send "true" as a string
+	GivenName     string      `json:"given_name"`
+	FamilyName    string      `json:"family_name"`
+	Picture       string      `json:"picture"`
+}
+
+// applyTo copies the claims that are present into info
+func (c *oidcClaims) applyTo(info *models.OIDCUserInfo) {
+	if c.Email != "" {
+		info.Email = c.Email
+		switch verified := c.EmailVerified.(type) {
+		case bool:
+			info.EmailVerified = verified
+		case string:
+			info.EmailVerified = strings.EqualFold(verified, "true")
+		default:
+			info.EmailVerified = false
+		}
+	}
+	if c.GivenName != "" {
+		info.GivenName = c.GivenName
+	}
+	if c.FamilyName != "" {
+		info.FamilyName = c.FamilyName
+	}
+	if c.Picture != "" {
+		info.Picture = c.Picture
+	}
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
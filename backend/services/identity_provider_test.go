@@ -0,0 +1,585 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const testClientID = "fithero-test"
+
+// testIssuer is an OpenID Connect provider serving discovery, JWKS and token endpoints
+type testIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]testGrant
+}
+
+// testGrant is what the issuer returns for an authorization code
+type testGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	issuer := &testIssuer{t: t, key: key, codes: make(map[string]testGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, map[string]interface{}{
+		"issuer":                                i.server.URL,
+		"authorization_endpoint":                i.server.URL + "/authorize",
+		"token_endpoint":                        i.server.URL + "/token",
+		"jwks_uri":                              i.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (i *testIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeTestJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *testIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	i.mu.Lock()
+	grant, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	i.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		writeTestJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	if pkceChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeTestJSON(w, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	idToken.Header["kid"] = "test"
+	signed, err := idToken.SignedString(i.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeTestJSON(w, map[string]interface{}{
+		"access_token": "test-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// authorize plays the user signing in at the provider: it checks the authorization URL and
+// returns a code for an ID token with the given profile claims and the requested nonce
+func (i *testIssuer) authorize(authURL string, profile jwt.MapClaims) string {
+	i.t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		i.t.Fatalf("parse auth URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != testClientID || query.Get("code_challenge_method") != "S256" {
+		i.t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   i.server.URL,
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for name, value := range profile {
+		claims[name] = value
+	}
+
+	code, err := generateRandomToken()
+	if err != nil {
+		i.t.Fatalf("generate code: %v", err)
+	}
+	i.mu.Lock()
+	i.codes[code] = testGrant{challenge: query.Get("code_challenge"), claims: claims}
+	i.mu.Unlock()
+	return code
+}
+
+func (i *testIssuer) provider(name string) *OIDCProvider {
+	return NewOIDCProvider(config.OIDCProviderConfig{
+		Name:        name,
+		DisplayName: "Test",
+		IssuerURL:   i.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:8080/api/auth/" + name + "/callback",
+		Scopes:      []string{"email", "profile"},
+	})
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeTestJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// In-memory repositories for the identity flows. Methods the flows do not use are left
+// to the embedded nil interfaces and panic if called.
+
+type memoryUserRepo struct {
+	repositories.UserRepositoryInterface
+	users map[uint]*models.User
+}
+
+func (r *memoryUserRepo) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	if user, ok := r.users[id]; ok {
+		copied := *user
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepo) IsUsernameTaken(ctx context.Context, username string, exceptUserID uint) (bool, error) {
+	for _, user := range r.users {
+		if user.ID != exceptUserID && strings.EqualFold(user.Username, username) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryUserRepo) Update(ctx context.Context, id uint, updates *models.UpdateUserRequest) error {
+	return nil
+}
+
+func (r *memoryUserRepo) UpdateRole(ctx context.Context, id uint, role string) error {
+	r.users[id].Role = role
+	return nil
+}
+
+func (r *memoryUserRepo) MarkEmailVerified(ctx context.Context, id uint, verifiedAt time.Time) error {
+	r.users[id].EmailVerifiedAt = &verifiedAt
+	return nil
+}
+
+type memoryIdentityRepo struct {
+	repositories.IdentityRepositoryInterface
+	users      *memoryUserRepo
+	identities []*models.UserIdentity
+}
+
+func (r *memoryIdentityRepo) Create(ctx context.Context, identity *models.UserIdentity) error {
+	identity.ID = uint(len(r.identities) + 1)
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *memoryIdentityRepo) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) (*models.User, error) {
+	user.ID = uint(len(r.users.users) + 1)
+	r.users.users[user.ID] = user
+	identity.UserID = user.ID
+	if err := r.Create(ctx, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *memoryIdentityRepo) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryIdentityRepo) Touch(ctx context.Context, id uint, email string, lastLoginAt time.Time) error {
+	return nil
+}
+
+type memorySessionRepo struct {
+	repositories.SessionRepositoryInterface
+	sessions []*models.Session
+	tokens   []*models.RefreshToken
+}
+
+func (r *memorySessionRepo) Create(ctx context.Context, session *models.Session) (*models.Session, error) {
+	session.ID = uint(len(r.sessions) + 1)
+	r.sessions = append(r.sessions, session)
+	return session, nil
+}
+
+func (r *memorySessionRepo) GetByID(ctx context.Context, id uint) (*models.Session, error) {
+	if id == 0 || int(id) > len(r.sessions) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *r.sessions[id-1]
+	return &copied, nil
+}
+
+func (r *memorySessionRepo) Touch(ctx context.Context, id uint, lastUsedAt time.Time) error {
+	r.sessions[id-1].LastUsedAt = lastUsedAt
+	return nil
+}
+
+func (r *memorySessionRepo) Revoke(ctx context.Context, id uint, reason string) error {
+	now := time.Now()
+	r.sessions[id-1].RevokedAt = &now
+	r.sessions[id-1].RevokedReason = reason
+	return nil
+}
+
+func (r *memorySessionRepo) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	stored := *token
+	r.tokens = append(r.tokens, &stored)
+	return nil
+}
+
+func (r *memorySessionRepo) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			copied.Session = *r.sessions[token.SessionID-1]
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySessionRepo) MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	token := r.tokens[id-1]
+	if token.UsedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &usedAt
+	return true, nil
+}
+
+// testAuthService returns an AuthService signing in through the issuer as provider "test"
+func testAuthService(t *testing.T, issuer *testIssuer, users ...*models.User) (*AuthService, *memoryIdentityRepo) {
+	t.Helper()
+	userRepo := &memoryUserRepo{users: make(map[uint]*models.User)}
+	for _, user := range users {
+		userRepo.users[user.ID] = user
+	}
+	identityRepo := &memoryIdentityRepo{users: userRepo}
+
+	authConfig := &config.AuthConfig{
+		JWTSecret:              strings.Repeat("s", 32),
+		AccessTokenExpiration:  15 * time.Minute,
+		RefreshTokenExpiration: 24 * time.Hour,
+		AdminEmails:            []string{"admin@example.com"},
+	}
+	signer, err := NewTokenSigner(authConfig)
+	if err != nil {
+		t.Fatalf("NewTokenSigner: %v", err)
+	}
+	providers := NewProviderRegistry(nil)
+	providers.Register("test", issuer.provider("test"))
+
+	service := NewAuthService(userRepo, &memorySessionRepo{}, identityRepo, providers, authConfig, signer,
+		NewUsernamePolicy(userRepo, &config.UsernameConfig{}))
+	return service, identityRepo
+}
+
+// signIn starts a flow and has the issuer authorize it with the given profile claims
+func signIn(t *testing.T, service *AuthService, issuer *testIssuer, link bool, profile jwt.MapClaims) (*models.OAuthFlow, string) {
+	t.Helper()
+	authURL, flow, err := service.StartLogin(context.Background(), "test", link, "")
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	if got := mustQuery(t, authURL).Get("state"); got != flow.State {
+		t.Fatalf("auth URL state = %q, want the flow state %q", got, flow.State)
+	}
+	return flow, issuer.authorize(authURL, profile)
+}
+
+func mustQuery(t *testing.T, rawURL string) url.Values {
+	t.Helper()
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse %q: %v", rawURL, err)
+	}
+	return parsed.Query()
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.provider("test")
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "verifier-0123456789012345678901234567890123", "nonce-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	query := mustQuery(t, authURL)
+	if query.Get("nonce") != "nonce-1" || query.Get("state") != "state-1" {
+		t.Fatalf("auth URL %s does not carry the state and nonce", authURL)
+	}
+	if !strings.Contains(query.Get("scope"), "openid") {
+		t.Errorf("scope = %q, want openid to be requested", query.Get("scope"))
+	}
+
+	code := issuer.authorize(authURL, jwt.MapClaims{
+		"sub":            "user-1",
+		"email":          "hero@example.com",
+		"email_verified": "true",
+		"given_name":     "Hero",
+	})
+	info, err := provider.Exchange(ctx, code, "verifier-0123456789012345678901234567890123", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if info.Subject != "user-1" || info.Email != "hero@example.com" || !info.EmailVerified || info.GivenName != "Hero" {
+		t.Errorf("Exchange returned %+v", info)
+	}
+}
+
+func TestOIDCProviderExchangeRejectsWrongVerifier(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.provider("test")
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "verifier-0123456789012345678901234567890123", "nonce-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := issuer.authorize(authURL, jwt.MapClaims{"sub": "user-1"})
+	if _, err := provider.Exchange(ctx, code, "another-verifier-012345678901234567890123456", "nonce-1"); err == nil {
+		t.Fatal("Exchange succeeded with a PKCE verifier that does not match the challenge")
+	}
+}
+
+func TestOIDCProviderExchangeRejectsNonceMismatch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.provider("test")
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "verifier-0123456789012345678901234567890123", "nonce-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	// An ID token issued for another login, e.g. one replayed by an attacker
+	code := issuer.authorize(authURL, jwt.MapClaims{"sub": "user-1", "nonce": "nonce-of-another-login"})
+	_, err = provider.Exchange(ctx, code, "verifier-0123456789012345678901234567890123", "nonce-1")
+	if err == nil || err.Error() != "ID token nonce mismatch" {
+		t.Fatalf("Exchange error = %v, want ID token nonce mismatch", err)
+	}
+}
+
+func TestHandleCallbackCreatesUserThenSignsInThroughIdentity(t *testing.T) {
+	issuer := newTestIssuer(t)
+	service, identities := testAuthService(t, issuer)
+	ctx := context.Background()
+	profile := jwt.MapClaims{"sub": "user-1", "email": "hero@example.com", "email_verified": true}
+
+	flow, code := signIn(t, service, issuer, false, profile)
+	first, err := service.HandleCallback(ctx, flow, code, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if first.User.Email != "hero@example.com" || first.User.EmailVerifiedAt == nil || first.Token == "" || first.RefreshToken == "" {
+		t.Fatalf("first login returned %+v", first)
+	}
+	if len(identities.identities) != 1 || identities.identities[0].UserID != first.User.ID {
+		t.Fatalf("identities after first login = %+v", identities.identities)
+	}
+
+	flow, code = signIn(t, service, issuer, false, profile)
+	second, err := service.HandleCallback(ctx, flow, code, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("second HandleCallback: %v", err)
+	}
+	if second.User.ID != first.User.ID || len(identities.identities) != 1 {
+		t.Errorf("second login signed in user %d with %d identities, want user %d with 1", second.User.ID, len(identities.identities), first.User.ID)
+	}
+}
+
+func TestHandleCallbackRejectsFlowWithAnotherNonce(t *testing.T) {
+	issuer := newTestIssuer(t)
+	service, identities := testAuthService(t, issuer)
+
+	flow, code := signIn(t, service, issuer, false, jwt.MapClaims{"sub": "user-1", "email": "hero@example.com", "email_verified": true})
+	flow.Nonce = "nonce-of-another-login"
+	if _, err := service.HandleCallback(context.Background(), flow, code, models.ClientInfo{}); err == nil {
+		t.Fatal("HandleCallback accepted an ID token issued for another nonce")
+	}
+	if len(identities.identities) != 0 {
+		t.Errorf("a rejected login created identities %+v", identities.identities)
+	}
+}
+
+func TestHandleCallbackLinksByEmail(t *testing.T) {
+	verifiedAt := time.Now()
+	tests := []struct {
+		name          string
+		user          models.User
+		emailVerified bool
+		wantErr       string
+		wantRole      string
+	}{
+		{
+			name:          "verified account and verified email",
+			user:          models.User{ID: 7, Email: "admin@example.com", IsActive: true, EmailVerifiedAt: &verifiedAt},
+			emailVerified: true,
+			wantRole:      models.RoleAdmin,
+		},
+		{
+			name:          "email not verified by provider",
+			user:          models.User{ID: 7, Email: "admin@example.com", IsActive: true, EmailVerifiedAt: &verifiedAt},
+			emailVerified: false,
+			wantErr:       "email not verified by provider",
+		},
+		{
+			name:          "account email not verified",
+			user:          models.User{ID: 7, Email: "admin@example.com", IsActive: true},
+			emailVerified: true,
+			wantErr:       "account email not verified",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newTestIssuer(t)
+			user := tt.user
+			service, identities := testAuthService(t, issuer, &user)
+
+			flow, code := signIn(t, service, issuer, false, jwt.MapClaims{"sub": "user-1", "email": "Admin@example.com", "email_verified": tt.emailVerified})
+			response, err := service.HandleCallback(context.Background(), flow, code, models.ClientInfo{})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("HandleCallback error = %v, want %s", err, tt.wantErr)
+				}
+				if len(identities.identities) != 0 {
+					t.Errorf("identity linked despite %s: %+v", tt.wantErr, identities.identities)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HandleCallback: %v", err)
+			}
+			if response.User.ID != user.ID || response.User.Role != tt.wantRole {
+				t.Errorf("signed in user %d with role %q, want user %d with role %q", response.User.ID, response.User.Role, user.ID, tt.wantRole)
+			}
+			if len(identities.identities) != 1 || identities.identities[0].UserID != user.ID {
+				t.Errorf("identities = %+v, want one linked to user %d", identities.identities, user.ID)
+			}
+		})
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifiedAt := time.Now()
+	service, identities := testAuthService(t, issuer,
+		&models.User{ID: 1, Email: "first@example.com", IsActive: true, EmailVerifiedAt: &verifiedAt},
+		&models.User{ID: 2, Email: "second@example.com", IsActive: true, EmailVerifiedAt: &verifiedAt},
+	)
+	ctx := context.Background()
+	profile := jwt.MapClaims{"sub": "provider-account", "email": "elsewhere@example.com"}
+
+	flow, code := signIn(t, service, issuer, true, profile)
+	if !flow.Link {
+		t.Fatal("StartLogin did not mark the flow as a link flow")
+	}
+	identity, err := service.LinkIdentity(ctx, flow, code, 1)
+	if err != nil {
+		t.Fatalf("LinkIdentity: %v", err)
+	}
+	if identity.UserID != 1 || identity.Provider != "test" || identity.Subject != "provider-account" {
+		t.Errorf("linked identity %+v", identity)
+	}
+
+	// Linking the same provider account again is a no-op for its owner
+	flow, code = signIn(t, service, issuer, true, profile)
+	again, err := service.LinkIdentity(ctx, flow, code, 1)
+	if err != nil || again.ID != identity.ID {
+		t.Errorf("relinking returned %+v, %v; want the existing identity", again, err)
+	}
+
+	// and refused for anyone else
+	flow, code = signIn(t, service, issuer, true, profile)
+	if _, err := service.LinkIdentity(ctx, flow, code, 2); err == nil || err.Error() != "identity already linked to another account" {
+		t.Errorf("linking to another user error = %v, want identity already linked to another account", err)
+	}
+	if len(identities.identities) != 1 {
+		t.Errorf("identities = %d, want 1", len(identities.identities))
+	}
+}
+
+func TestLinkIdentityRejectsNonceMismatch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	service, identities := testAuthService(t, issuer, &models.User{ID: 1, Email: "first@example.com", IsActive: true})
+
+	flow, code := signIn(t, service, issuer, true, jwt.MapClaims{"sub": "provider-account"})
+	flow.Nonce = fmt.Sprintf("%s-replayed", flow.Nonce)
+	if _, err := service.LinkIdentity(context.Background(), flow, code, 1); err == nil {
+		t.Fatal("LinkIdentity accepted an ID token issued for another nonce")
+	}
+	if len(identities.identities) != 0 {
+		t.Errorf("a rejected link created identities %+v", identities.identities)
+	}
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+	"fithero-backend/tracing"
+
+	"gorm.io/gorm"
+)
+
+// Lifetimes of emailed tokens
+const (
+	magicLinkTTL     = 15 * time.Minute
+	verifyEmailTTL   = 24 * time.Hour
+	passwordResetTTL = time.Hour
+)
+
+// dummyPasswordHash is verified against when no user matches, so failed logins take
+// the same time whether or not the email is registered
+var dummyPasswordHash, _ = hashPassword("fithero-dummy-password")
+
+// LocalAuthService signs users in with their email address, through magic links or an
+// optional password. Accounts are resolved through the same identity linking as OIDC
+// providers, with the email address as the identity subject.
+type LocalAuthService struct {
+	authService    *AuthService
+	userRepo       repositories.UserRepositoryInterface
+	identityRepo   repositories.IdentityRepositoryInterface
+	emailTokenRepo repositories.EmailTokenRepositoryInterface
+	sessionRepo    repositories.SessionRepositoryInterface
+	mailer         Mailer
+	authConfig     *config.AuthConfig
+}
+
+func NewLocalAuthService(authService *AuthService, userRepo repositories.UserRepositoryInterface, identityRepo repositories.IdentityRepositoryInterface, emailTokenRepo repositories.EmailTokenRepositoryInterface, sessionRepo repositories.SessionRepositoryInterface, mailer Mailer, authConfig *config.AuthConfig) *LocalAuthService {
+	return &LocalAuthService{
+		authService:    authService,
+		userRepo:       userRepo,
+		identityRepo:   identityRepo,
+		emailTokenRepo: emailTokenRepo,
+		sessionRepo:    sessionRepo,
+		mailer:         mailer,
+		authConfig:     authConfig,
+	}
+}
+
+// SendMagicLink emails a single-use login link. Addresses without an account get an
+// account when the link is used.
+func (s *LocalAuthService) SendMagicLink(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "LocalAuthService.SendMagicLink")
+	defer span.End()
+
+	email = normalizeEmail(email)
+
+	var userID *uint
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err == nil {
+		if !user.IsActive {
+			return nil
+		}
+		userID = &user.ID
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	token, err := s.issueToken(ctx, userID, email, models.EmailTokenMagicLink, magicLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, Email{
+		To:      email,
+		Subject: "Your FitHero sign-in link",
+		Body: fmt.Sprintf("Click the link below to sign in to FitHero:\n\n%s\n\nThe link expires in %d minutes. If you did not request it, you can ignore this email.\n",
+			s.link("/auth/magic-link", token), int(magicLinkTTL.Minutes())),
+	})
+}
+
+// LoginWithMagicLink redeems a magic link token and starts a new session
+func (s *LocalAuthService) LoginWithMagicLink(ctx context.Context, token string, client models.ClientInfo) (*models.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "LocalAuthService.LoginWithMagicLink")
+	defer span.End()
+
+	emailToken, err := s.redeemToken(ctx, token, models.EmailTokenMagicLink)
+	if err != nil {
+		return nil, err
+	}
+
+	// Following the link proves ownership of the address
+	user, err := s.authService.findOrCreateUser(ctx, models.LocalProvider, &models.OIDCUserInfo{
+		Subject:       emailToken.Email,
+		Email:         emailToken.Email,
+		EmailVerified: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find or create user: %w", err)
+	}
+
+	return s.authService.completeLogin(ctx, user, models.LocalProvider, client)
+}
+
+// Register creates an account with a password and sends a verification email. Registering
+// an address that already has an account sends a notice instead, so the response does not
+// reveal which addresses are registered.
+func (s *LocalAuthService) Register(ctx context.Context, req *models.RegisterRequest) error {
+	ctx, span := tracing.Start(ctx, "LocalAuthService.Register")
+	defer span.End()
+
+	email := normalizeEmail(req.Email)
+
+	if _, err := s.userRepo.GetByEmail(ctx, email); err == nil {
+		return s.mailer.Send(ctx, Email{
+			To:      email,
+			Subject: "You already have a FitHero account",
+			Body: fmt.Sprintf("Someone tried to sign up for FitHero with this address, but it already has an account.\n\nSign in or reset your password here:\n\n%s\n",
+				s.authConfig.FrontendURL+"/"),
+		})
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.authService.createUserWithIdentity(ctx, &models.User{
+		Email:        email,
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		PasswordHash: passwordHash,
+		Level:        1,
+		Points:       0,
+		Character:    "Rookie Hero",
+		JobTitle:     "Fitness Novice",
+		IsActive:     true,
+	}, &models.UserIdentity{
+		Provider: models.LocalProvider,
+		Subject:  email,
+		Email:    email,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// LoginWithPassword checks the password and starts a new session
+func (s *LocalAuthService) LoginWithPassword(ctx context.Context, email, password string, client models.ClientInfo) (*models.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "LocalAuthService.LoginWithPassword")
+	defer span.End()
+
+	user, err := s.userRepo.GetByEmail(ctx, normalizeEmail(email))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if user == nil || user.PasswordHash == "" {
+		verifyPassword(password, dummyPasswordHash)
+		return nil, errors.New("invalid email or password")
+	}
+
+	ok, err := verifyPassword(password, user.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invalid email or password")
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, errors.New("email not verified")
+	}
+
+	return s.authService.completeLogin(ctx, user, models.LocalProvider, client)
+}
+
+// ResendVerification sends a new verification email to an unverified account
+func (s *LocalAuthService) ResendVerification(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "LocalAuthService.ResendVerification")
+	defer span.End()
+
+	user, err := s.userRepo.GetByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil || !user.IsActive {
+		return nil
+	}
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// SendVerification emails a verification link to the user's current address, e.g. after it changed
+func (s *LocalAuthService) SendVerification(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "LocalAuthService.SendVerification")
+	defer span.End()
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// VerifyEmail redeems a verification token
+func (s *LocalAuthService) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "LocalAuthService.VerifyEmail")
+	defer span.End()
+
+	emailToken, err := s.redeemToken(ctx, token, models.EmailTokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.tokenUser(ctx, emailToken)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.MarkEmailVerified(ctx, user.ID, time.Now())
+}
+
+// RequestPasswordReset emails a password reset link to an active account
+func (s *LocalAuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "LocalAuthService.RequestPasswordReset")
+	defer span.End()
+
+	user, err := s.userRepo.GetByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	token, err := s.issueToken(ctx, &user.ID, user.Email, models.EmailTokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, Email{
+		To:      user.Email,
+		Subject: "Reset your FitHero password",
+		Body: fmt.Sprintf("Click the link below to choose a new FitHero password:\n\n%s\n\nThe link expires in %d minutes. If you did not request it, you can ignore this email.\n",
+			s.link("/auth/reset-password", token), int(passwordResetTTL.Minutes())),
+	})
+}
+
+// ResetPassword sets a new password with a reset token and signs out every session
+func (s *LocalAuthService) ResetPassword(ctx context.Context, token, password string) error {
+	ctx, span := tracing.Start(ctx, "LocalAuthService.ResetPassword")
+	defer span.End()
+
+	emailToken, err := s.redeemToken(ctx, token, models.EmailTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.tokenUser(ctx, emailToken)
+	if err != nil {
+		return err
+	}
+
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.SetPasswordHash(ctx, user.ID, passwordHash); err != nil {
+		return err
+	}
+	// Receiving the reset email proves ownership of the address
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID, time.Now()); err != nil {
+		return err
+	}
+
+	// Users who signed up through a provider gain email login
+	if _, err := s.identityRepo.GetByProviderSubject(ctx, models.LocalProvider, user.Email); errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := s.identityRepo.Create(ctx, &models.UserIdentity{
+			UserID:   user.ID,
+			Provider: models.LocalProvider,
+			Subject:  user.Email,
+			Email:    user.Email,
+		}); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	return s.sessionRepo.RevokeAllForUser(ctx, user.ID, 0, models.SessionRevokedPasswordReset)
+}
+
+// Private methods
+
+func (s *LocalAuthService) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := s.issueToken(ctx, &user.ID, user.Email, models.EmailTokenVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, Email{
+		To:      user.Email,
+		Subject: "Verify your FitHero email address",
+		Body: fmt.Sprintf("Welcome to FitHero! Click the link below to verify your email address:\n\n%s\n\nThe link expires in %d hours.\n",
+			s.link("/auth/verify-email", token), int(verifyEmailTTL.Hours())),
+	})
+}
+
+// issueToken creates a single-use token, invalidating earlier tokens of the same purpose
+func (s *LocalAuthService) issueToken(ctx context.Context, userID *uint, email, purpose string, ttl time.Duration) (string, error) {
+	token, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := s.emailTokenRepo.InvalidateForEmail(ctx, email, purpose, now); err != nil {
+		return "", err
+	}
+	err = s.emailTokenRepo.Create(ctx, &models.EmailToken{
+		UserID:    userID,
+		Email:     email,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// redeemToken validates a token for the purpose and marks it used
+func (s *LocalAuthService) redeemToken(ctx context.Context, token, purpose string) (*models.EmailToken, error) {
+	if token == "" {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	emailToken, err := s.emailTokenRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid or expired token")
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if emailToken.Purpose != purpose || emailToken.UsedAt != nil || now.After(emailToken.ExpiresAt) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	fresh, err := s.emailTokenRepo.MarkUsed(ctx, emailToken.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, errors.New("invalid or expired token")
+	}
+	return emailToken, nil
+}
+
+// tokenUser loads the user a token was issued to, rejecting tokens sent to a previous email address
+func (s *LocalAuthService) tokenUser(ctx context.Context, emailToken *models.EmailToken) (*models.User, error) {
+	if emailToken.UserID == nil {
+		return nil, errors.New("invalid or expired token")
+	}
+	user, err := s.userRepo.GetByID(ctx, *emailToken.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid or expired token")
+		}
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, emailToken.Email) {
+		return nil, errors.New("invalid or expired token")
+	}
+	return user, nil
+}
+
+// link builds a frontend URL carrying a token
+func (s *LocalAuthService) link(path, token string) string {
+	return s.authConfig.FrontendURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/logging"
+)
+
+// Email is a plain text message sent to a single recipient
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// NewMailer creates the mailer selected by the configuration
+func NewMailer(cfg *config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case config.MailDriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case config.MailDriverFile:
+		return NewFileMailer(cfg.FileDir, cfg.From)
+	default:
+		return NewLogMailer(cfg.From), nil
+	}
+}
+
+// SMTPMailer sends emails through an SMTP server, using STARTTLS when the server offers it
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates a mailer for the configured SMTP server
+func NewSMTPMailer(cfg *config.MailConfig) *SMTPMailer {
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return &SMTPMailer{
+		addr: cfg.SMTPHost + ":" + strconv.Itoa(cfg.SMTPPort),
+		auth: auth,
+		from: cfg.From,
+	}
+}
+
+// Send delivers the email
+func (m *SMTPMailer) Send(ctx context.Context, email Email) error {
+	message, err := formatEmail(m.from, email)
+	if err != nil {
+		return err
+	}
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	return m.sendMail(ctx, sender.Address, email.To, message)
+}
+
+// HealthCheck connects and signs in to the SMTP server without sending anything
+func (m *SMTPMailer) HealthCheck(ctx context.Context) error {
+	client, closeClient, err := m.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+	return client.Quit()
+}
+
+// sendMail is smtp.SendMail with the connection closed when ctx is cancelled, so a slow
+// server can't hold the request past its deadline
+func (m *SMTPMailer) sendMail(ctx context.Context, from, to string, message []byte) error {
+	client, closeClient, err := m.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// connect opens an SMTP session that is closed early if ctx is cancelled. The returned
+// function closes the session.
+func (m *SMTPMailer) connect(ctx context.Context) (*smtp.Client, func(), error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	client, err := m.handshake(conn)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, nil, err
+	}
+	return client, func() {
+		stop()
+		client.Close()
+	}, nil
+}
+
+// handshake greets the server, upgrades to TLS when the server offers it and signs in
+func (m *SMTPMailer) handshake(conn net.Conn) (*smtp.Client, error) {
+	host, _, _ := net.SplitHostPort(m.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return nil, err
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return nil, err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return nil, errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := client.Auth(m.auth); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// FileMailer writes each email as an .eml file, for development and end-to-end tests
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates a mailer writing into dir
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Send writes the email to a new file
+func (m *FileMailer) Send(ctx context.Context, email Email) error {
+	message, err := formatEmail(m.from, email)
+	if err != nil {
+		return err
+	}
+	token, err := generateRandomToken()
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), token[:8])
+	return os.WriteFile(filepath.Join(m.dir, name), message, 0o600)
+}
+
+// HealthCheck checks that the mail directory still exists
+func (m *FileMailer) HealthCheck(ctx context.Context) error {
+	info, err := os.Stat(m.dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", m.dir)
+	}
+	return nil
+}
+
+// LogMailer prints emails to the server log instead of sending them
+type LogMailer struct {
+	from string
+}
+
+// NewLogMailer creates a mailer that logs emails
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+// Send logs the email
+func (m *LogMailer) Send(ctx context.Context, email Email) error {
+	logging.FromContext(ctx).Info("Email", "to", email.To, "subject", email.Subject, "body", email.Body)
+	return nil
+}
+
+// formatEmail renders an RFC 5322 message, rejecting header injection
+func formatEmail(from string, email Email) ([]byte, error) {
+	for _, header := range []string{from, email.To, email.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, fmt.Errorf("invalid email header")
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + email.To + "\r\n")
+	b.WriteString("Subject: " + email.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(email.Body, "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
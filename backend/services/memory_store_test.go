@@ -0,0 +1,414 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+
+	"gorm.io/gorm"
+)
+
+// errTest is a failure injected into a repository
+var errTest = errors.New("injected failure")
+
+// memoryStore holds the rows shared by the in-memory repositories of the service tests.
+// Its transactor restores the rows when a transaction fails, like a rollback would.
+type memoryStore struct {
+	users            map[uint]*models.User
+	achievements     map[uint]*models.Achievement
+	userAchievements map[uint]*models.UserAchievement
+	dailyTasks       map[uint]*models.DailyTask
+	auditLogs        []models.AchievementAuditLog
+	events           []models.OutboxEvent
+	nextID           uint
+
+	// fail makes the repository method of the same name return the error
+	fail map[string]error
+	// locked records the users locked with GetByIDForUpdate, in order
+	locked []uint
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		users:            map[uint]*models.User{},
+		achievements:     map[uint]*models.Achievement{},
+		userAchievements: map[uint]*models.UserAchievement{},
+		dailyTasks:       map[uint]*models.DailyTask{},
+		fail:             map[string]error{},
+	}
+}
+
+func (s *memoryStore) id() uint {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *memoryStore) addUser(user *models.User) *models.User {
+	if user.ID == 0 {
+		user.ID = s.id()
+	}
+	if user.Level == 0 {
+		user.Level = 1
+	}
+	s.users[user.ID] = user
+	return user
+}
+
+func (s *memoryStore) addAchievement(achievement *models.Achievement) *models.Achievement {
+	if achievement.ID == 0 {
+		achievement.ID = s.id()
+	}
+	s.achievements[achievement.ID] = achievement
+	return achievement
+}
+
+func (s *memoryStore) addUserAchievement(userAchievement *models.UserAchievement) *models.UserAchievement {
+	userAchievement.ID = s.id()
+	s.userAchievements[userAchievement.ID] = userAchievement
+	return userAchievement
+}
+
+func (s *memoryStore) addDailyTask(dailyTask *models.DailyTask) *models.DailyTask {
+	dailyTask.ID = s.id()
+	if dailyTask.Points == 0 {
+		dailyTask.Points = dailyTask.Task.Points
+	}
+	s.dailyTasks[dailyTask.ID] = dailyTask
+	return dailyTask
+}
+
+// owned returns the IDs of the achievements a user owns
+func (s *memoryStore) owned(userID uint) map[uint]bool {
+	owned := map[uint]bool{}
+	for _, userAchievement := range s.userAchievements {
+		if userAchievement.UserID == userID {
+			owned[userAchievement.AchievementID] = true
+		}
+	}
+	return owned
+}
+
+// snapshot copies every row, to be restored if a transaction fails
+func (s *memoryStore) snapshot() *memoryStore {
+	copied := *s
+	copied.users = make(map[uint]*models.User, len(s.users))
+	for id, user := range s.users {
+		row := *user
+		copied.users[id] = &row
+	}
+	copied.userAchievements = make(map[uint]*models.UserAchievement, len(s.userAchievements))
+	for id, userAchievement := range s.userAchievements {
+		row := *userAchievement
+		copied.userAchievements[id] = &row
+	}
+	copied.dailyTasks = make(map[uint]*models.DailyTask, len(s.dailyTasks))
+	for id, dailyTask := range s.dailyTasks {
+		row := *dailyTask
+		copied.dailyTasks[id] = &row
+	}
+	copied.auditLogs = append([]models.AchievementAuditLog(nil), s.auditLogs...)
+	copied.events = append([]models.OutboxEvent(nil), s.events...)
+	return &copied
+}
+
+func (s *memoryStore) restore(snapshot *memoryStore) {
+	s.users = snapshot.users
+	s.userAchievements = snapshot.userAchievements
+	s.dailyTasks = snapshot.dailyTasks
+	s.auditLogs = snapshot.auditLogs
+	s.events = snapshot.events
+}
+
+// memoryTransactor runs transactions against a memoryStore
+type memoryTransactor struct {
+	store *memoryStore
+}
+
+func (t *memoryTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	snapshot := t.store.snapshot()
+	if err := fn(ctx); err != nil {
+		t.store.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+// storeUserRepo serves users from a memoryStore. Methods the tests do not use are left to
+// the embedded nil interface.
+type storeUserRepo struct {
+	repositories.UserRepositoryInterface
+	store *memoryStore
+}
+
+func (r *storeUserRepo) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	if user, ok := r.store.users[id]; ok {
+		copied := *user
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *storeUserRepo) GetByIDForUpdate(ctx context.Context, id uint) (*models.User, error) {
+	r.store.locked = append(r.store.locked, id)
+	return r.GetByID(ctx, id)
+}
+
+func (r *storeUserRepo) Update(ctx context.Context, id uint, updates *models.UpdateUserRequest) error {
+	if err := r.store.fail["Update"]; err != nil {
+		return err
+	}
+	user, ok := r.store.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if updates.Points != nil {
+		user.Points = *updates.Points
+	}
+	if updates.Level != nil {
+		user.Level = *updates.Level
+	}
+	if updates.Character != nil {
+		user.Character = *updates.Character
+	}
+	if updates.JobTitle != nil {
+		user.JobTitle = *updates.JobTitle
+	}
+	return nil
+}
+
+func (r *storeUserRepo) AddPoints(ctx context.Context, id uint, points int) error {
+	if err := r.store.fail["AddPoints"]; err != nil {
+		return err
+	}
+	r.store.users[id].Points += points
+	return nil
+}
+
+// storeOutboxRepo records the events written to a memoryStore
+type storeOutboxRepo struct {
+	repositories.OutboxRepositoryInterface
+	store *memoryStore
+}
+
+func (r *storeOutboxRepo) Create(ctx context.Context, event *models.OutboxEvent) error {
+	if err := r.store.fail["CreateEvent"]; err != nil {
+		return err
+	}
+	event.ID = r.store.id()
+	r.store.events = append(r.store.events, *event)
+	return nil
+}
+
+// storeAchievementRepo serves achievements and the users' achievements from a memoryStore
+type storeAchievementRepo struct {
+	repositories.AchievementRepositoryInterface
+	store *memoryStore
+}
+
+func (r *storeAchievementRepo) GetAll(ctx context.Context) ([]models.Achievement, error) {
+	achievements := make([]models.Achievement, 0, len(r.store.achievements))
+	for _, achievement := range r.store.achievements {
+		achievements = append(achievements, *achievement)
+	}
+	sort.Slice(achievements, func(i, j int) bool { return achievements[i].ID < achievements[j].ID })
+	return achievements, nil
+}
+
+func (r *storeAchievementRepo) GetByID(ctx context.Context, id uint) (*models.Achievement, error) {
+	if achievement, ok := r.store.achievements[id]; ok {
+		copied := *achievement
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *storeAchievementRepo) LockForUpdate(ctx context.Context, id uint) error {
+	return nil
+}
+
+func (r *storeAchievementRepo) CreateUserAchievement(ctx context.Context, userAchievement *models.UserAchievement) (*models.UserAchievement, error) {
+	if r.store.owned(userAchievement.UserID)[userAchievement.AchievementID] {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	stored := *userAchievement
+	r.store.addUserAchievement(&stored)
+	userAchievement.ID = stored.ID
+	return r.withAchievement(&stored), nil
+}
+
+func (r *storeAchievementRepo) GetUserAchievements(ctx context.Context, userID uint) ([]models.UserAchievement, error) {
+	return r.find(func(ua *models.UserAchievement) bool { return ua.UserID == userID }), nil
+}
+
+func (r *storeAchievementRepo) GetUserAchievementByUserAndAchievement(ctx context.Context, userID, achievementID uint) (*models.UserAchievement, error) {
+	found := r.find(func(ua *models.UserAchievement) bool {
+		return ua.UserID == userID && ua.AchievementID == achievementID
+	})
+	if len(found) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &found[0], nil
+}
+
+func (r *storeAchievementRepo) IsAchievementUnlocked(ctx context.Context, userID, achievementID uint) (bool, error) {
+	return r.store.owned(userID)[achievementID], nil
+}
+
+func (r *storeAchievementRepo) GetOwnedDependents(ctx context.Context, userID, achievementID uint) ([]models.UserAchievement, error) {
+	return r.find(func(ua *models.UserAchievement) bool {
+		if ua.UserID != userID {
+			return false
+		}
+		for _, prerequisite := range r.store.achievements[ua.AchievementID].Prerequisites {
+			if prerequisite.ID == achievementID {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *storeAchievementRepo) DeleteUserAchievement(ctx context.Context, id uint) error {
+	if _, ok := r.store.userAchievements[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.userAchievements, id)
+	return nil
+}
+
+func (r *storeAchievementRepo) CountUnlocks(ctx context.Context, achievementID uint) (int64, error) {
+	return int64(len(r.find(func(ua *models.UserAchievement) bool { return ua.AchievementID == achievementID }))), nil
+}
+
+func (r *storeAchievementRepo) CountUnlocksByAchievement(ctx context.Context) (map[uint]int64, error) {
+	counts := map[uint]int64{}
+	for _, ua := range r.store.userAchievements {
+		counts[ua.AchievementID]++
+	}
+	return counts, nil
+}
+
+func (r *storeAchievementRepo) GetEquippedAchievements(ctx context.Context, userIDs []uint) ([]models.UserAchievement, error) {
+	users := map[uint]bool{}
+	for _, id := range userIDs {
+		users[id] = true
+	}
+	equipped := r.find(func(ua *models.UserAchievement) bool { return users[ua.UserID] && ua.IsEquipped })
+	sort.SliceStable(equipped, func(i, j int) bool { return equipped[i].SlotOrder < equipped[j].SlotOrder })
+	return equipped, nil
+}
+
+func (r *storeAchievementRepo) ReplaceEquipped(ctx context.Context, userID uint, achievementType string, achievementIDs []uint) error {
+	if err := r.store.fail["ReplaceEquipped:"+achievementType]; err != nil {
+		return err
+	}
+	for _, ua := range r.store.userAchievements {
+		if ua.UserID == userID && r.store.achievements[ua.AchievementID].Type == achievementType {
+			ua.IsEquipped = false
+			ua.SlotOrder = 0
+		}
+	}
+	for i, achievementID := range achievementIDs {
+		for _, ua := range r.store.userAchievements {
+			if ua.UserID == userID && ua.AchievementID == achievementID {
+				ua.IsEquipped = true
+				ua.SlotOrder = i
+			}
+		}
+	}
+	return nil
+}
+
+func (r *storeAchievementRepo) CreateAuditLog(ctx context.Context, entry *models.AchievementAuditLog) error {
+	entry.ID = r.store.id()
+	r.store.auditLogs = append(r.store.auditLogs, *entry)
+	return nil
+}
+
+// find returns copies of the matching user achievements in ID order, with their achievement
+func (r *storeAchievementRepo) find(match func(ua *models.UserAchievement) bool) []models.UserAchievement {
+	var found []models.UserAchievement
+	for _, ua := range r.store.userAchievements {
+		if match(ua) {
+			found = append(found, *r.withAchievement(ua))
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found
+}
+
+func (r *storeAchievementRepo) withAchievement(ua *models.UserAchievement) *models.UserAchievement {
+	copied := *ua
+	copied.Achievement = *r.store.achievements[ua.AchievementID]
+	return &copied
+}
+
+// storeTaskRepo serves daily tasks from a memoryStore
+type storeTaskRepo struct {
+	repositories.TaskRepositoryInterface
+	store *memoryStore
+}
+
+func (r *storeTaskRepo) GetDailyTaskByID(ctx context.Context, id uint) (*models.DailyTask, error) {
+	if dailyTask, ok := r.store.dailyTasks[id]; ok {
+		copied := *dailyTask
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *storeTaskRepo) CompleteDailyTask(ctx context.Context, id uint, updates *models.UpdateDailyTaskRequest) error {
+	dailyTask, ok := r.store.dailyTasks[id]
+	if !ok || dailyTask.IsCompleted || dailyTask.ExpiredAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	applyDailyTaskUpdates(dailyTask, updates)
+	return nil
+}
+
+func (r *storeTaskRepo) ReviewDailyTask(ctx context.Context, id uint, updates *models.UpdateDailyTaskRequest) error {
+	dailyTask, ok := r.store.dailyTasks[id]
+	if !ok || dailyTask.VerificationStatus != models.VerificationStatusPending {
+		return gorm.ErrRecordNotFound
+	}
+	applyDailyTaskUpdates(dailyTask, updates)
+	return nil
+}
+
+func (r *storeTaskRepo) GetCompletedSince(ctx context.Context, userID uint, since time.Time) ([]models.DailyTask, error) {
+	return nil, nil
+}
+
+func applyDailyTaskUpdates(dailyTask *models.DailyTask, updates *models.UpdateDailyTaskRequest) {
+	if updates.IsCompleted != nil {
+		dailyTask.IsCompleted = *updates.IsCompleted
+	}
+	if updates.CompletedAt != nil {
+		dailyTask.CompletedAt = updates.CompletedAt
+	}
+	if updates.EvidenceNote != nil {
+		dailyTask.EvidenceNote = *updates.EvidenceNote
+	}
+	if updates.EvidenceValue != nil {
+		dailyTask.EvidenceValue = updates.EvidenceValue
+	}
+	if updates.EvidencePhotoURL != nil {
+		dailyTask.EvidencePhotoURL = *updates.EvidencePhotoURL
+	}
+	if updates.VerificationStatus != nil {
+		dailyTask.VerificationStatus = *updates.VerificationStatus
+	}
+	if updates.VerifiedBy != nil {
+		dailyTask.VerifiedBy = updates.VerifiedBy
+	}
+	if updates.VerifiedAt != nil {
+		dailyTask.VerifiedAt = updates.VerifiedAt
+	}
+	if updates.RejectionReason != nil {
+		dailyTask.RejectionReason = *updates.RejectionReason
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/logging"
+	"fithero-backend/metrics"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+	"fithero-backend/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// EventHandler processes an outbox event. Events are delivered at least once, and again to
+// every handler when any of them fails, so handlers must tolerate repeats, e.g. by keying
+// their side effects on the event ID.
+type EventHandler func(ctx context.Context, event *models.OutboxEvent) error
+
+// recordEvent writes a domain event to the outbox. Call it with the context of the transaction
+// making the change the event describes, so the event is stored if and only if the change is.
+func recordEvent(ctx context.Context, outboxRepo repositories.OutboxRepositoryInterface, eventType string, userID uint, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return outboxRepo.Create(ctx, &models.OutboxEvent{
+		Type:        eventType,
+		UserID:      userID,
+		Payload:     string(data),
+		AvailableAt: time.Now(),
+	})
+}
+
+// retryDelay is the exponential backoff before retry number attempt (starting at 1), capped at max
+func retryDelay(base, max time.Duration, attempt int) time.Duration {
+	if attempt > 30 {
+		return max
+	}
+	delay := base << (attempt - 1)
+	if delay <= 0 || delay > max {
+		return max
+	}
+	return delay
+}
+
+type eventSubscription struct {
+	name      string
+	eventType string
+	handler   EventHandler
+}
+
+// OutboxDispatcher delivers outbox events to the subscribed handlers. Every replica runs a
+// dispatcher; events are leased while being delivered, so each is handled by one of them.
+type OutboxDispatcher struct {
+	outboxRepo    repositories.OutboxRepositoryInterface
+	config        *config.OutboxConfig
+	subscriptions []eventSubscription
+	stop          chan struct{}
+	once          sync.Once
+	running       sync.WaitGroup
+}
+
+// NewOutboxDispatcher creates a dispatcher without any handlers
+func NewOutboxDispatcher(outboxRepo repositories.OutboxRepositoryInterface, cfg *config.OutboxConfig) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		outboxRepo: outboxRepo,
+		config:     cfg,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Subscribe registers a named handler for one event type, or for every event when eventType
+// is "*". Handlers must be subscribed before Start is called.
+func (d *OutboxDispatcher) Subscribe(name, eventType string, handler EventHandler) {
+	d.subscriptions = append(d.subscriptions, eventSubscription{
+		name:      name,
+		eventType: eventType,
+		handler:   handler,
+	})
+}
+
+// Start delivers due events in the background until ctx is done or Close is called
+func (d *OutboxDispatcher) Start(ctx context.Context) {
+	d.running.Add(1)
+	go func() {
+		defer d.running.Done()
+		ticker := time.NewTicker(d.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// Keep claiming while batches come back full, so a backlog drains without waiting
+			for d.dispatchBatch(ctx) == d.config.BatchSize {
+				select {
+				case <-d.stop:
+					return
+				default:
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the dispatcher and waits for the events being delivered
+func (d *OutboxDispatcher) Close() {
+	d.once.Do(func() { close(d.stop) })
+	d.running.Wait()
+}
+
+// PurgeProcessed deletes events delivered longer ago than the retention period
+func (d *OutboxDispatcher) PurgeProcessed(ctx context.Context, now time.Time) error {
+	ctx, span := tracing.Start(ctx, "OutboxDispatcher.PurgeProcessed")
+	defer span.End()
+
+	deleted, err := d.outboxRepo.DeleteProcessedBefore(ctx, now.Add(-d.config.Retention))
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Purged delivered outbox events", "count", deleted)
+	return nil
+}
+
+// dispatchBatch claims due events, delivers them concurrently and returns how many it claimed
+func (d *OutboxDispatcher) dispatchBatch(ctx context.Context) int {
+	now := time.Now()
+	// The lease outlasts the delivery deadline, so an event is only claimed again once its
+	// dispatcher has given up on it or died
+	events, err := d.outboxRepo.ClaimDue(ctx, now, now.Add(2*d.config.HandlerTimeout), d.config.BatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			logging.FromContext(ctx).Warn("Failed to claim outbox events", "error", err)
+		}
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for i := range events {
+		wg.Add(1)
+		go func(event *models.OutboxEvent) {
+			defer wg.Done()
+			d.deliver(ctx, event)
+		}(&events[i])
+	}
+	wg.Wait()
+	return len(events)
+}
+
+// deliver passes an event to its handlers and records the outcome. A failed delivery is
+// retried with exponential backoff until the attempts run out.
+func (d *OutboxDispatcher) deliver(ctx context.Context, event *models.OutboxEvent) {
+	// Deliveries in progress are finished on shutdown rather than cut off
+	ctx = logging.With(context.WithoutCancel(ctx), "event_id", event.ID, "event_type", event.Type)
+	ctx, span := tracing.Start(ctx, "OutboxDispatcher.deliver",
+		attribute.String("event.type", event.Type),
+		attribute.Int("event.id", int(event.ID)),
+		attribute.Int("event.attempt", event.Attempts),
+	)
+	defer span.End()
+	log := logging.FromContext(ctx)
+
+	handlerCtx, cancel := context.WithTimeout(ctx, d.config.HandlerTimeout)
+	var errs []error
+	for _, subscription := range d.subscriptions {
+		if subscription.eventType != "*" && subscription.eventType != event.Type {
+			continue
+		}
+		if err := callHandler(handlerCtx, subscription.handler, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", subscription.name, err))
+		}
+	}
+	cancel()
+
+	now := time.Now()
+	err := errors.Join(errs...)
+	switch {
+	case err == nil:
+		if err := d.outboxRepo.MarkProcessed(ctx, event.ID, now); err != nil {
+			log.Warn("Failed to mark outbox event delivered", "error", err)
+		}
+		metrics.OutboxDeliveries.WithLabelValues(event.Type, "delivered").Inc()
+		return
+	case event.Attempts >= d.config.MaxAttempts:
+		if err := d.outboxRepo.MarkGivenUp(ctx, event.ID, err.Error(), now); err != nil {
+			log.Warn("Failed to mark outbox event failed", "error", err)
+		}
+		metrics.OutboxDeliveries.WithLabelValues(event.Type, "failed").Inc()
+		log.Error("Gave up delivering outbox event", "attempts", event.Attempts, "error", err)
+	default:
+		retryAt := now.Add(retryDelay(d.config.RetryBackoff, d.config.MaxBackoff, event.Attempts))
+		if err := d.outboxRepo.MarkFailed(ctx, event.ID, err.Error(), retryAt); err != nil {
+			log.Warn("Failed to schedule outbox event retry", "error", err)
+		}
+		metrics.OutboxDeliveries.WithLabelValues(event.Type, "retried").Inc()
+		log.Warn("Failed to deliver outbox event, retrying", "attempt", event.Attempts, "retry_at", retryAt, "error", err)
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// callHandler runs a handler, turning a panic into an error so the event is retried
+func callHandler(ctx context.Context, handler EventHandler, event *models.OutboxEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, event)
+}
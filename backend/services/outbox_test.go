@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+)
+
+// memoryOutboxRepo keeps outbox events in memory and claims them like the database does
+type memoryOutboxRepo struct {
+	events []*models.OutboxEvent
+}
+
+func (r *memoryOutboxRepo) Create(ctx context.Context, event *models.OutboxEvent) error {
+	event.ID = uint(len(r.events) + 1)
+	stored := *event
+	r.events = append(r.events, &stored)
+	return nil
+}
+
+func (r *memoryOutboxRepo) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	var claimed []models.OutboxEvent
+	for _, event := range r.events {
+		if len(claimed) == limit {
+			break
+		}
+		if event.ProcessedAt == nil && event.FailedAt == nil && !event.AvailableAt.After(now) {
+			event.AvailableAt = leaseUntil
+			event.Attempts++
+			claimed = append(claimed, *event)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *memoryOutboxRepo) MarkProcessed(ctx context.Context, id uint, processedAt time.Time) error {
+	r.events[id-1].ProcessedAt = &processedAt
+	r.events[id-1].LastError = ""
+	return nil
+}
+
+func (r *memoryOutboxRepo) MarkFailed(ctx context.Context, id uint, lastError string, retryAt time.Time) error {
+	r.events[id-1].LastError = lastError
+	r.events[id-1].AvailableAt = retryAt
+	return nil
+}
+
+func (r *memoryOutboxRepo) MarkGivenUp(ctx context.Context, id uint, lastError string, failedAt time.Time) error {
+	r.events[id-1].LastError = lastError
+	r.events[id-1].FailedAt = &failedAt
+	return nil
+}
+
+func (r *memoryOutboxRepo) DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// makeDue moves every pending event's next attempt into the past
+func (r *memoryOutboxRepo) makeDue() {
+	for _, event := range r.events {
+		event.AvailableAt = time.Now().Add(-time.Second)
+	}
+}
+
+func newTestDispatcher() (*OutboxDispatcher, *memoryOutboxRepo) {
+	repo := &memoryOutboxRepo{}
+	dispatcher := NewOutboxDispatcher(repo, &config.OutboxConfig{
+		BatchSize:      10,
+		HandlerTimeout: time.Second,
+		MaxAttempts:    3,
+		RetryBackoff:   time.Minute,
+		MaxBackoff:     time.Hour,
+	})
+	return dispatcher, repo
+}
+
+func TestOutboxDeliversToSubscribedHandlers(t *testing.T) {
+	dispatcher, repo := newTestDispatcher()
+	var received []string
+	dispatcher.Subscribe("notifications", models.EventAchievementUnlocked, func(ctx context.Context, event *models.OutboxEvent) error {
+		received = append(received, "notifications:"+event.Type)
+		return nil
+	})
+	dispatcher.Subscribe("audit", "*", func(ctx context.Context, event *models.OutboxEvent) error {
+		received = append(received, "audit:"+event.Type)
+		return nil
+	})
+	if err := recordEvent(context.Background(), repo, models.EventTaskCompleted, 1, &models.TaskCompletedEvent{UserID: 1}); err != nil {
+		t.Fatalf("recordEvent: %v", err)
+	}
+
+	if claimed := dispatcher.dispatchBatch(context.Background()); claimed != 1 {
+		t.Fatalf("claimed %d events, want 1", claimed)
+	}
+	if len(received) != 1 || received[0] != "audit:"+models.EventTaskCompleted {
+		t.Errorf("received = %v, want only the catch-all handler", received)
+	}
+	if repo.events[0].ProcessedAt == nil {
+		t.Error("the delivered event was not marked processed")
+	}
+	if claimed := dispatcher.dispatchBatch(context.Background()); claimed != 0 {
+		t.Errorf("claimed %d events again after delivery", claimed)
+	}
+}
+
+func TestOutboxRetriesWithBackoffAndGivesUp(t *testing.T) {
+	dispatcher, repo := newTestDispatcher()
+	attempts := 0
+	dispatcher.Subscribe("webhooks", "*", func(ctx context.Context, event *models.OutboxEvent) error {
+		attempts++
+		return errors.New("endpoint unavailable")
+	})
+	if err := recordEvent(context.Background(), repo, models.EventTaskCompleted, 1, &models.TaskCompletedEvent{UserID: 1}); err != nil {
+		t.Fatalf("recordEvent: %v", err)
+	}
+	event := repo.events[0]
+
+	for attempt := 1; attempt < 3; attempt++ {
+		before := time.Now()
+		dispatcher.dispatchBatch(context.Background())
+		backoff := time.Minute << (attempt - 1)
+		if event.FailedAt != nil || event.AvailableAt.Before(before.Add(backoff)) || event.AvailableAt.After(time.Now().Add(backoff)) {
+			t.Fatalf("attempt %d: event = %+v, want a retry in %s", attempt, event, backoff)
+		}
+		if !strings.Contains(event.LastError, "webhooks: endpoint unavailable") {
+			t.Errorf("attempt %d: last error = %q", attempt, event.LastError)
+		}
+		// Not due before the backoff has passed
+		if claimed := dispatcher.dispatchBatch(context.Background()); claimed != 0 {
+			t.Fatalf("attempt %d: claimed the event before its retry was due", attempt)
+		}
+		repo.makeDue()
+	}
+
+	dispatcher.dispatchBatch(context.Background())
+	if attempts != 3 || event.Attempts != 3 {
+		t.Fatalf("delivered %d times over %d attempts, want 3", attempts, event.Attempts)
+	}
+	if event.FailedAt == nil || event.ProcessedAt != nil {
+		t.Fatalf("event = %+v, want it given up", event)
+	}
+	repo.makeDue()
+	if claimed := dispatcher.dispatchBatch(context.Background()); claimed != 0 {
+		t.Error("claimed an event that was given up")
+	}
+}
+
+func TestOutboxRetriesPanickingHandler(t *testing.T) {
+	dispatcher, repo := newTestDispatcher()
+	dispatcher.Subscribe("broken", "*", func(ctx context.Context, event *models.OutboxEvent) error {
+		panic("nil map")
+	})
+	if err := recordEvent(context.Background(), repo, models.EventTaskCompleted, 1, &models.TaskCompletedEvent{UserID: 1}); err != nil {
+		t.Fatalf("recordEvent: %v", err)
+	}
+
+	dispatcher.dispatchBatch(context.Background())
+	if event := repo.events[0]; event.ProcessedAt != nil || !strings.Contains(event.LastError, "handler panicked: nil map") {
+		t.Errorf("event = %+v, want the panic recorded for a retry", event)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{12, time.Hour},
+		{64, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(5*time.Second, time.Hour, tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(attempt %d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
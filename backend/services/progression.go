@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+)
+
+// defaultJobTitle is the job title shown when no upgrade is equipped
+const defaultJobTitle = "Fitness Novice"
+
+// characterForLevel returns the character name for a given level
+func characterForLevel(level int) string {
+	characters := map[int]string{
+		1: "Rookie Hero",
+		2: "Bronze Warrior",
+		3: "Silver Champion",
+		4: "Gold Legend",
+		5: "Platinum Master",
+	}
+
+	if character, exists := characters[level]; exists {
+		return character
+	}
+	return "Rookie Hero" // Default fallback
+}
+
+// hasEquippedCharacter reports whether the user has a character achievement equipped,
+// in which case level-ups must not overwrite their displayed character
+func hasEquippedCharacter(ctx context.Context, achievementRepo repositories.AchievementRepositoryInterface, userID uint) bool {
+	equipped, err := achievementRepo.GetEquippedAchievements(ctx, []uint{userID})
+	if err != nil {
+		return false
+	}
+	for _, ua := range equipped {
+		if ua.Achievement.Type == "character" {
+			return true
+		}
+	}
+	return false
+}
+
+// buildLoadouts groups equipped achievements into a loadout per user
+func buildLoadouts(equipped []models.UserAchievement) map[uint]*models.Loadout {
+	loadouts := make(map[uint]*models.Loadout)
+	for _, ua := range equipped {
+		loadout, exists := loadouts[ua.UserID]
+		if !exists {
+			loadout = &models.Loadout{Badges: []models.Achievement{}}
+			loadouts[ua.UserID] = loadout
+		}
+
+		achievement := ua.Achievement
+		switch achievement.Type {
+		case "character":
+			loadout.Character = &achievement
+		case "upgrade":
+			loadout.JobTitle = &achievement
+		case "badge":
+			loadout.Badges = append(loadout.Badges, achievement)
+		}
+	}
+	return loadouts
+}
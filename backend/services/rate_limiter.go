@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"fithero-backend/config"
+)
+
+// RateLimitResult describes the state of a token bucket after a request was counted
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int           // Bucket capacity
+	Remaining  int           // Whole tokens left
+	ResetAfter time.Duration // Time until the bucket is full again
+	RetryAfter time.Duration // Time until the next token is available, when not allowed
+}
+
+// RateLimitStore keeps token buckets. Implementations backed by a shared store such as Redis
+// let several backend instances enforce the same limits.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit config.RateLimit) (RateLimitResult, error)
+	Close()
+}
+
+// NewRateLimitStore creates the rate limit store selected by the configuration
+func NewRateLimitStore(cfg *config.RateLimitConfig) (RateLimitStore, error) {
+	switch cfg.Store {
+	default:
+		return NewMemoryRateLimitStore(time.Minute), nil
+	}
+}
+
+type tokenBucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64 // Tokens per second
+	updated  time.Time
+}
+
+// MemoryRateLimitStore keeps token buckets in process memory. Full buckets are dropped
+// periodically so idle clients do not use memory.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewMemoryRateLimitStore creates an in-memory store that sweeps idle buckets at the given interval
+func NewMemoryRateLimitStore(sweepInterval time.Duration) *MemoryRateLimitStore {
+	store := &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+	go store.sweep(sweepInterval)
+	return store
+}
+
+// Take removes a token from the bucket for key, if one is available
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit config.RateLimit) (RateLimitResult, error) {
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+	capacity := float64(limit.Burst)
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+	bucket.capacity = capacity
+	bucket.rate = rate
+	bucket.updated = now
+
+	result := RateLimitResult{Limit: limit.Burst}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bucket.tokens) / rate)
+	}
+	result.Remaining = int(bucket.tokens)
+	result.ResetAfter = secondsToDuration((capacity - bucket.tokens) / rate)
+	return result, nil
+}
+
+// Close stops the background sweep
+func (s *MemoryRateLimitStore) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+func (s *MemoryRateLimitStore) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for key, bucket := range s.buckets {
+				if bucket.tokens+now.Sub(bucket.updated).Seconds()*bucket.rate >= bucket.capacity {
+					delete(s.buckets, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
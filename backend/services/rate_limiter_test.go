@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+)
+
+// testRateLimitStore returns a memory store whose clock only moves when advanced
+func testRateLimitStore(t *testing.T) (*MemoryRateLimitStore, func(time.Duration)) {
+	t.Helper()
+	store := NewMemoryRateLimitStore(time.Hour)
+	t.Cleanup(store.Close)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, func(d time.Duration) { now = now.Add(d) }
+}
+
+func take(t *testing.T, store *MemoryRateLimitStore, key string, limit config.RateLimit) RateLimitResult {
+	t.Helper()
+	result, err := store.Take(context.Background(), key, limit)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	return result
+}
+
+func TestMemoryRateLimitStoreBurst(t *testing.T) {
+	store, _ := testRateLimitStore(t)
+	limit := config.RateLimit{Requests: 6, Period: time.Minute, Burst: 3}
+
+	for i := 2; i >= 0; i-- {
+		result := take(t, store, "user:1", limit)
+		if !result.Allowed || result.Remaining != i || result.Limit != 3 {
+			t.Fatalf("request %d: got %+v, want allowed with %d remaining", 3-i, result, i)
+		}
+	}
+
+	result := take(t, store, "user:1", limit)
+	if result.Allowed {
+		t.Fatal("request over the burst was allowed")
+	}
+	// One token is refilled every 10 seconds, the empty bucket is full again after 30
+	if result.RetryAfter != 10*time.Second || result.ResetAfter != 30*time.Second || result.Remaining != 0 {
+		t.Errorf("denied result = %+v, want retry after 10s and reset after 30s", result)
+	}
+
+	if other := take(t, store, "user:2", limit); !other.Allowed {
+		t.Error("another key shares the exhausted bucket")
+	}
+}
+
+func TestMemoryRateLimitStoreRefill(t *testing.T) {
+	store, advance := testRateLimitStore(t)
+	limit := config.RateLimit{Requests: 6, Period: time.Minute, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		take(t, store, "ip:1", limit)
+	}
+
+	advance(5 * time.Second)
+	result := take(t, store, "ip:1", limit)
+	if result.Allowed || result.RetryAfter != 5*time.Second {
+		t.Fatalf("half a token in: got %+v, want denied with retry after 5s", result)
+	}
+
+	advance(5 * time.Second)
+	if result := take(t, store, "ip:1", limit); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("after a refill interval: got %+v, want one request allowed", result)
+	}
+
+	// Idle time never saves up more than the burst
+	advance(time.Hour)
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if take(t, store, "ip:1", limit).Allowed {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("after an hour idle %d requests were allowed, want the burst of 3", allowed)
+	}
+}
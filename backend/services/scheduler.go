@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/logging"
+	"fithero-backend/metrics"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+	"fithero-backend/tracing"
+
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"gorm.io/gorm"
+)
+
+// Names of the scheduled background jobs
+const (
+	JobPregenerateDailyTasks  = "pregenerate-daily-tasks"
+	JobExpireDailyTasks       = "expire-daily-tasks"
+	JobEvaluateStreaks        = "evaluate-streaks"
+	JobLeaderboardSnapshot    = "leaderboard-snapshot"
+	JobPurgeOutbox            = "purge-outbox"
+	JobPurgeWebhookDeliveries = "purge-webhook-deliveries"
+)
+
+// JobFunc does the work of a scheduled job. now is when the run started; jobs must be safe to
+// run again for the same period.
+type JobFunc func(ctx context.Context, now time.Time) error
+
+type scheduledJob struct {
+	name     string
+	spec     string
+	schedule cron.Schedule
+	run      JobFunc
+}
+
+// Scheduler runs background jobs on cron schedules. Every replica runs a scheduler; a Postgres
+// advisory lock makes sure each run of a job happens on one replica only.
+type Scheduler struct {
+	jobRepo  repositories.JobRepositoryInterface
+	config   *config.JobConfig
+	instance string
+	jobs     []*scheduledJob
+	running  sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler without any jobs
+func NewScheduler(jobRepo repositories.JobRepositoryInterface, cfg *config.JobConfig) *Scheduler {
+	instance, _ := os.Hostname()
+	return &Scheduler{
+		jobRepo:  jobRepo,
+		config:   cfg,
+		instance: instance,
+	}
+}
+
+// Register adds a job that runs on the cron schedule spec, evaluated in UTC. Jobs must be
+// registered before Start is called.
+func (s *Scheduler) Register(name, spec string, run JobFunc) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: invalid schedule %q: %w", name, spec, err)
+	}
+	s.jobs = append(s.jobs, &scheduledJob{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		run:      run,
+	})
+	return nil
+}
+
+// Start runs every registered job on its schedule until ctx is done. Runs that are due while
+// the previous run of the same job is still going are skipped.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.running.Add(1)
+		go func(job *scheduledJob) {
+			defer s.running.Done()
+
+			for {
+				due := job.schedule.Next(time.Now().UTC())
+				timer := time.NewTimer(time.Until(due))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+				s.runJob(ctx, job, due)
+			}
+		}(job)
+	}
+}
+
+// Wait blocks until every job started by Start has returned, which happens once the context
+// passed to Start is done and running jobs have given up
+func (s *Scheduler) Wait() {
+	s.running.Wait()
+}
+
+// Jobs returns the registered jobs with their next and latest runs
+func (s *Scheduler) Jobs(ctx context.Context) ([]models.JobInfo, error) {
+	ctx, span := tracing.Start(ctx, "Scheduler.Jobs")
+	defer span.End()
+
+	now := time.Now().UTC()
+	jobs := make([]models.JobInfo, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		info := models.JobInfo{
+			Name:      job.name,
+			Schedule:  job.spec,
+			NextRunAt: job.schedule.Next(now),
+		}
+		lastRun, err := s.jobRepo.GetLastRun(ctx, job.name)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		info.LastRun = lastRun
+		jobs = append(jobs, info)
+	}
+	return jobs, nil
+}
+
+// ListRuns returns the run history of one job, or of all jobs when job is empty, newest first
+func (s *Scheduler) ListRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	ctx, span := tracing.Start(ctx, "Scheduler.ListRuns")
+	defer span.End()
+
+	if job != "" && s.find(job) == nil {
+		return nil, errors.New("job not found")
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	return s.jobRepo.ListRuns(ctx, job, limit)
+}
+
+func (s *Scheduler) find(name string) *scheduledJob {
+	for _, job := range s.jobs {
+		if job.name == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// runJob runs a job for the schedule tick due, retrying failed attempts with exponential
+// backoff, unless another replica holds the job's lock or has already run it for this tick
+func (s *Scheduler) runJob(ctx context.Context, job *scheduledJob, due time.Time) {
+	ctx = logging.With(ctx, "job", job.name)
+	ctx, span := tracing.Start(ctx, "Scheduler.runJob", attribute.String("job.name", job.name))
+	defer span.End()
+	log := logging.FromContext(ctx)
+
+	unlock, locked, err := s.jobRepo.TryLock(ctx, job.name)
+	if err != nil {
+		log.Warn("Failed to take job lock", "error", err)
+		return
+	}
+	if !locked {
+		log.Debug("Job is running on another instance")
+		return
+	}
+	defer unlock()
+
+	// Replicas whose clocks are slightly behind can take the lock after the run finished
+	lastRun, err := s.jobRepo.GetLastRun(ctx, job.name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Warn("Failed to read job history", "error", err)
+		return
+	}
+	if lastRun != nil && lastRun.ScheduledFor.Equal(due) {
+		log.Debug("Job already ran on another instance")
+		return
+	}
+
+	run := &models.JobRun{
+		Job:          job.name,
+		Status:       models.JobStatusRunning,
+		Instance:     s.instance,
+		ScheduledFor: due,
+		StartedAt:    time.Now(),
+	}
+	if err := s.jobRepo.CreateRun(ctx, run); err != nil {
+		log.Warn("Failed to record job run", "error", err)
+		return
+	}
+
+	for {
+		run.Attempts++
+		err = job.run(ctx, run.StartedAt)
+		if err == nil || run.Attempts >= s.config.MaxAttempts {
+			break
+		}
+
+		delay := s.config.RetryBackoff << (run.Attempts - 1)
+		log.Warn("Job failed, retrying", "attempt", run.Attempts, "retry_in", delay.String(), "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = models.JobStatusSucceeded
+	if err != nil {
+		run.Status = models.JobStatusFailed
+		run.Error = err.Error()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	// Record the outcome even when the run was cut short by shutdown
+	if err := s.jobRepo.UpdateRun(context.WithoutCancel(ctx), run); err != nil {
+		log.Warn("Failed to record job result", "error", err)
+	}
+	metrics.JobRuns.WithLabelValues(job.name, run.Status).Inc()
+	metrics.JobDuration.WithLabelValues(job.name).Observe(finishedAt.Sub(run.StartedAt).Seconds())
+
+	if run.Status == models.JobStatusFailed {
+		log.Error("Job failed", "attempts", run.Attempts, "error", err)
+		return
+	}
+	log.Info("Job finished", "attempts", run.Attempts, "duration_ms", finishedAt.Sub(run.StartedAt).Milliseconds())
+}
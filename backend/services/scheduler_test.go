@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+
+	"gorm.io/gorm"
+)
+
+// memoryJobRepo keeps job runs in memory. locked makes every lock look taken by another replica.
+type memoryJobRepo struct {
+	runs   []*models.JobRun
+	locked bool
+}
+
+func (r *memoryJobRepo) CreateRun(ctx context.Context, run *models.JobRun) error {
+	run.ID = uint(len(r.runs) + 1)
+	stored := *run
+	r.runs = append(r.runs, &stored)
+	return nil
+}
+
+func (r *memoryJobRepo) UpdateRun(ctx context.Context, run *models.JobRun) error {
+	stored := *run
+	r.runs[run.ID-1] = &stored
+	return nil
+}
+
+func (r *memoryJobRepo) GetLastRun(ctx context.Context, job string) (*models.JobRun, error) {
+	for i := len(r.runs) - 1; i >= 0; i-- {
+		if r.runs[i].Job == job {
+			copied := *r.runs[i]
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryJobRepo) ListRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	var runs []models.JobRun
+	for i := len(r.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		if job == "" || r.runs[i].Job == job {
+			runs = append(runs, *r.runs[i])
+		}
+	}
+	return runs, nil
+}
+
+func (r *memoryJobRepo) TryLock(ctx context.Context, job string) (func(), bool, error) {
+	if r.locked {
+		return nil, false, nil
+	}
+	return func() {}, true, nil
+}
+
+// newTestScheduler returns a scheduler with one job that fails its first failures attempts
+func newTestScheduler(t *testing.T, failures int) (*Scheduler, *memoryJobRepo, *int) {
+	t.Helper()
+	repo := &memoryJobRepo{}
+	scheduler := NewScheduler(repo, &config.JobConfig{MaxAttempts: 3, RetryBackoff: time.Millisecond})
+	attempts := 0
+	err := scheduler.Register(JobExpireDailyTasks, "10 * * * *", func(ctx context.Context, now time.Time) error {
+		attempts++
+		if attempts <= failures {
+			return errTest
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return scheduler, repo, &attempts
+}
+
+func TestSchedulerRetriesFailedRuns(t *testing.T) {
+	scheduler, repo, attempts := newTestScheduler(t, 2)
+	due := time.Date(2024, 3, 1, 10, 10, 0, 0, time.UTC)
+
+	scheduler.runJob(context.Background(), scheduler.jobs[0], due)
+	if *attempts != 3 {
+		t.Fatalf("job ran %d times, want 3", *attempts)
+	}
+	if len(repo.runs) != 1 {
+		t.Fatalf("recorded %d runs, want 1", len(repo.runs))
+	}
+	run := repo.runs[0]
+	if run.Status != models.JobStatusSucceeded || run.Attempts != 3 || run.Error != "" || run.FinishedAt == nil || !run.ScheduledFor.Equal(due) {
+		t.Errorf("run = %+v", run)
+	}
+}
+
+func TestSchedulerGivesUpAfterMaxAttempts(t *testing.T) {
+	scheduler, repo, attempts := newTestScheduler(t, 10)
+
+	scheduler.runJob(context.Background(), scheduler.jobs[0], time.Date(2024, 3, 1, 10, 10, 0, 0, time.UTC))
+	if *attempts != 3 {
+		t.Fatalf("job ran %d times, want 3", *attempts)
+	}
+	run := repo.runs[0]
+	if run.Status != models.JobStatusFailed || run.Attempts != 3 || run.Error != errTest.Error() {
+		t.Errorf("run = %+v", run)
+	}
+}
+
+func TestSchedulerRunsEachTickOnce(t *testing.T) {
+	scheduler, repo, attempts := newTestScheduler(t, 0)
+	due := time.Date(2024, 3, 1, 10, 10, 0, 0, time.UTC)
+
+	// Another replica holds the lock
+	repo.locked = true
+	scheduler.runJob(context.Background(), scheduler.jobs[0], due)
+	if *attempts != 0 || len(repo.runs) != 0 {
+		t.Fatalf("ran %d times and recorded %d runs while locked elsewhere", *attempts, len(repo.runs))
+	}
+
+	repo.locked = false
+	scheduler.runJob(context.Background(), scheduler.jobs[0], due)
+	// A replica whose clock is behind takes the lock after the run finished
+	scheduler.runJob(context.Background(), scheduler.jobs[0], due)
+	if *attempts != 1 || len(repo.runs) != 1 {
+		t.Errorf("ran %d times and recorded %d runs for one tick, want 1", *attempts, len(repo.runs))
+	}
+
+	scheduler.runJob(context.Background(), scheduler.jobs[0], due.Add(time.Hour))
+	if *attempts != 2 {
+		t.Errorf("the next tick did not run")
+	}
+}
+
+func TestSchedulerListRuns(t *testing.T) {
+	scheduler, _, _ := newTestScheduler(t, 0)
+	due := time.Date(2024, 3, 1, 10, 10, 0, 0, time.UTC)
+	scheduler.runJob(context.Background(), scheduler.jobs[0], due)
+	scheduler.runJob(context.Background(), scheduler.jobs[0], due.Add(time.Hour))
+
+	runs, err := scheduler.ListRuns(context.Background(), JobExpireDailyTasks, 0)
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(runs) != 2 || !runs[0].ScheduledFor.Equal(due.Add(time.Hour)) {
+		t.Errorf("runs = %+v, want both, newest first", runs)
+	}
+	if _, err := scheduler.ListRuns(context.Background(), "unknown", 0); err == nil || err.Error() != "job not found" {
+		t.Errorf("unknown job: err = %v", err)
+	}
+
+	jobs, err := scheduler.Jobs(context.Background())
+	if err != nil {
+		t.Fatalf("Jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].LastRun == nil || !jobs[0].LastRun.ScheduledFor.Equal(due.Add(time.Hour)) {
+		t.Errorf("jobs = %+v", jobs)
+	}
+}
+
+// timezoneUserRepo reports the timezones users live in
+type timezoneUserRepo struct {
+	repositories.UserRepositoryInterface
+	timezones []string
+}
+
+func (r *timezoneUserRepo) GetTimezones(ctx context.Context) ([]string, error) {
+	return r.timezones, nil
+}
+
+// expiringTaskRepo records the local dates daily tasks were expired before, by timezone
+type expiringTaskRepo struct {
+	repositories.TaskRepositoryInterface
+	before map[string]string
+	err    error
+}
+
+func (r *expiringTaskRepo) ExpireDailyTasks(ctx context.Context, timezone, before string, expiredAt time.Time) (int64, error) {
+	r.before[timezone] = before
+	return 1, r.err
+}
+
+func TestExpireDailyTasksUsesLocalDates(t *testing.T) {
+	taskRepo := &expiringTaskRepo{before: map[string]string{}}
+	service := NewTaskService(taskRepo, &timezoneUserRepo{timezones: []string{"UTC", "Pacific/Auckland", "America/Los_Angeles"}}, nil, nil, nil, nil, nil)
+
+	// 20:00 UTC is already the next day in Auckland and still the same morning in Los Angeles
+	now := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	if err := service.ExpireDailyTasks(context.Background(), now); err != nil {
+		t.Fatalf("ExpireDailyTasks: %v", err)
+	}
+	want := map[string]string{"UTC": "2024-03-01", "Pacific/Auckland": "2024-03-02", "America/Los_Angeles": "2024-03-01"}
+	for timezone, date := range want {
+		if taskRepo.before[timezone] != date {
+			t.Errorf("%s: expired tasks before %q, want %q", timezone, taskRepo.before[timezone], date)
+		}
+	}
+
+	// A failure is returned so the scheduler retries the run
+	taskRepo.err = errors.New("connection reset")
+	if err := service.ExpireDailyTasks(context.Background(), now); err == nil {
+		t.Error("ExpireDailyTasks hid the repository failure")
+	}
+}
@@ -1,9 +1,18 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"fithero-backend/logging"
+	"fithero-backend/metrics"
 	"fithero-backend/models"
 	"fithero-backend/repositories"
+	"fithero-backend/tracing"
 	"gorm.io/gorm"
 )
 
@@ -11,26 +20,41 @@ type TaskService struct {
 	taskRepo        repositories.TaskRepositoryInterface
 	userRepo        repositories.UserRepositoryInterface
 	achievementRepo repositories.AchievementRepositoryInterface
+	outboxRepo      repositories.OutboxRepositoryInterface
+	transactor      repositories.TransactorInterface
+	evidenceStore   EvidenceStore
+	antiCheat       *AntiCheatService
 }
 
 // NewTaskService creates a new task service
-func NewTaskService(taskRepo repositories.TaskRepositoryInterface, userRepo repositories.UserRepositoryInterface, achievementRepo repositories.AchievementRepositoryInterface) *TaskService {
+func NewTaskService(taskRepo repositories.TaskRepositoryInterface, userRepo repositories.UserRepositoryInterface, achievementRepo repositories.AchievementRepositoryInterface, outboxRepo repositories.OutboxRepositoryInterface, transactor repositories.TransactorInterface, evidenceStore EvidenceStore, antiCheat *AntiCheatService) *TaskService {
 	return &TaskService{
 		taskRepo:        taskRepo,
 		userRepo:        userRepo,
 		achievementRepo: achievementRepo,
+		outboxRepo:      outboxRepo,
+		transactor:      transactor,
+		evidenceStore:   evidenceStore,
+		antiCheat:       antiCheat,
 	}
 }
 
 // GetAllTasks returns all available tasks (public endpoint)
-func (s *TaskService) GetAllTasks() ([]models.Task, error) {
-	return s.taskRepo.GetAll()
+func (s *TaskService) GetAllTasks(ctx context.Context) ([]models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetAllTasks")
+	defer span.End()
+
+	return s.taskRepo.GetAll(ctx)
 }
 
-// GenerateDailyTasks generates daily tasks for a specific user
-func (s *TaskService) GenerateDailyTasks(userID uint) ([]models.DailyTask, error) {
+// GenerateDailyTasks generates today's daily tasks for a specific user, where today is the
+// current date in the user's timezone
+func (s *TaskService) GenerateDailyTasks(ctx context.Context, userID uint) ([]models.DailyTask, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GenerateDailyTasks")
+	defer span.End()
+
 	// Verify user exists
-	user, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -38,19 +62,24 @@ func (s *TaskService) GenerateDailyTasks(userID uint) ([]models.DailyTask, error
 		return nil, err
 	}
 
-	// Check if user already has daily tasks for today
-	existingTasks, err := s.taskRepo.GetDailyTasksByUserID(userID)
+	return s.generateDailyTasks(ctx, user, localDate(time.Now(), user.Timezone))
+}
+
+// generateDailyTasks generates a user's daily tasks for a local date, unless they already exist
+func (s *TaskService) generateDailyTasks(ctx context.Context, user *models.User, date string) ([]models.DailyTask, error) {
+	// Check if user already has daily tasks for the date
+	existingTasks, err := s.taskRepo.GetDailyTasksForDate(ctx, user.ID, date)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
-	// If user already has tasks for today, return them
+	// If user already has tasks for the date, return them
 	if len(existingTasks) > 0 {
 		return existingTasks, nil
 	}
 
 	// Generate new daily tasks based on user level
-	tasks, err := s.taskRepo.GetTasksByLevel(user.Level)
+	tasks, err := s.taskRepo.GetTasksByLevel(ctx, user.Level)
 	if err != nil {
 		return nil, err
 	}
@@ -69,27 +98,105 @@ func (s *TaskService) GenerateDailyTasks(userID uint) ([]models.DailyTask, error
 	for i := 0; i < maxTasks; i++ {
 		task := tasks[i%len(tasks)] // Cycle through available tasks
 		dailyTask := models.DailyTask{
-			UserID:      userID,
-			TaskID:      task.ID,
-			Task:        task,
-			IsCompleted: false,
-			Points:      task.Points,
+			UserID:       user.ID,
+			TaskID:       task.ID,
+			Task:         task,
+			IsCompleted:  false,
+			Points:       task.Points,
+			AssignedDate: date,
 		}
 
-		createdTask, err := s.taskRepo.CreateDailyTask(&dailyTask)
+		createdTask, err := s.taskRepo.CreateDailyTask(ctx, &dailyTask)
 		if err != nil {
 			return nil, err
 		}
 		dailyTasks = append(dailyTasks, *createdTask)
 	}
+	metrics.TasksGenerated.Add(float64(len(dailyTasks)))
 
 	return dailyTasks, nil
 }
 
-// CompleteTask marks a daily task as completed for a specific user
-func (s *TaskService) CompleteTask(userID uint, dailyTaskID uint) (*models.DailyTask, error) {
+// PregenerateDailyTasks generates today's tasks in advance for users who logged in within the
+// last activeDays days, so they are ready when the user's day starts. Users whose tasks exist
+// already are skipped.
+func (s *TaskService) PregenerateDailyTasks(ctx context.Context, now time.Time, activeDays int) error {
+	ctx, span := tracing.Start(ctx, "TaskService.PregenerateDailyTasks")
+	defer span.End()
+
+	timezones, err := s.userRepo.GetTimezones(ctx)
+	if err != nil {
+		return err
+	}
+
+	activeSince := now.AddDate(0, 0, -activeDays)
+	generated, failed := 0, 0
+	var firstErr error
+	for _, timezone := range timezones {
+		date := localDate(now, timezone)
+		users, err := s.userRepo.GetUsersWithoutDailyTasks(ctx, timezone, date, activeSince)
+		if err != nil {
+			return err
+		}
+
+		for i := range users {
+			_, err := s.generateDailyTasks(ctx, &users[i], date)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			switch {
+			case err == nil:
+				generated++
+			case err.Error() == "no tasks available for user level":
+				// Nothing to generate until tasks are added for the level
+			default:
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+
+	logging.FromContext(ctx).Info("Pre-generated daily tasks", "users", generated, "failed", failed)
+	if firstErr != nil {
+		return fmt.Errorf("failed to generate daily tasks for %d users: %w", failed, firstErr)
+	}
+	return nil
+}
+
+// ExpireDailyTasks marks uncompleted daily tasks from days that have ended in their user's
+// timezone as expired, so they can no longer be completed
+func (s *TaskService) ExpireDailyTasks(ctx context.Context, now time.Time) error {
+	ctx, span := tracing.Start(ctx, "TaskService.ExpireDailyTasks")
+	defer span.End()
+
+	timezones, err := s.userRepo.GetTimezones(ctx)
+	if err != nil {
+		return err
+	}
+
+	var expired int64
+	for _, timezone := range timezones {
+		count, err := s.taskRepo.ExpireDailyTasks(ctx, timezone, localDate(now, timezone), now)
+		if err != nil {
+			return err
+		}
+		expired += count
+	}
+
+	logging.FromContext(ctx).Info("Expired daily tasks", "count", expired)
+	return nil
+}
+
+// CompleteTask marks a daily task as completed for a specific user. Tasks that require
+// verification are held as pending and award no points until approved.
+func (s *TaskService) CompleteTask(ctx context.Context, userID uint, dailyTaskID uint, evidence *models.TaskEvidence) (*models.DailyTask, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.CompleteTask")
+	defer span.End()
+
 	// Get the daily task
-	dailyTask, err := s.taskRepo.GetDailyTaskByID(dailyTaskID)
+	dailyTask, err := s.taskRepo.GetDailyTaskByID(ctx, dailyTaskID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("daily task not found")
@@ -102,31 +209,85 @@ func (s *TaskService) CompleteTask(userID uint, dailyTaskID uint) (*models.Daily
 		return nil, errors.New("access denied: you can only complete your own tasks")
 	}
 
+	// Tasks can only be completed on the day they were assigned
+	if dailyTask.ExpiredAt != nil {
+		return nil, errors.New("task expired")
+	}
+
 	// Check if task is already completed
 	if dailyTask.IsCompleted {
+		if dailyTask.VerificationStatus == models.VerificationStatusPending {
+			return nil, errors.New("task pending verification")
+		}
 		return nil, errors.New("task already completed")
 	}
 
-	// Mark as completed
+	if evidence == nil {
+		evidence = &models.TaskEvidence{}
+	}
+	if err := s.validateEvidence(&dailyTask.Task, evidence); err != nil {
+		return nil, err
+	}
+
+	// Mark as completed and store the evidence
+	now := time.Now()
 	updateReq := &models.UpdateDailyTaskRequest{
-		IsCompleted: &[]bool{true}[0], // Pointer to true
+		IsCompleted:   &[]bool{true}[0], // Pointer to true
+		CompletedAt:   &now,
+		EvidenceNote:  &evidence.Note,
+		EvidenceValue: evidence.Value,
+	}
+	if evidence.Photo != nil {
+		photoURL, err := s.evidenceStore.SavePhoto(userID, evidence.Photo)
+		if err != nil {
+			if errors.Is(err, ErrUnsupportedPhoto) {
+				return nil, err
+			}
+			return nil, errors.New("failed to store evidence photo")
+		}
+		updateReq.EvidencePhotoURL = &photoURL
 	}
 
-	err = s.taskRepo.UpdateDailyTask(dailyTaskID, updateReq)
-	if err != nil {
-		return nil, err
+	pending := requiresVerification(&dailyTask.Task)
+	if pending {
+		status := models.VerificationStatusPending
+		updateReq.VerificationStatus = &status
 	}
 
-	// Award points to user
-	err = s.awardPointsToUser(userID, dailyTask.Points)
+	// The completion, its points and its events are stored together or not at all
+	var award *pointsAward
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.taskRepo.CompleteDailyTask(ctx, dailyTaskID, updateReq); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("task already completed")
+			}
+			return err
+		}
+		// Award points to user unless a reviewer has to approve first
+		if pending {
+			return nil
+		}
+		award, err = s.awardTaskPoints(ctx, dailyTask, now)
+		return err
+	})
 	if err != nil {
-		// Log the error but don't fail the task completion
-		// In production, you might want to implement a retry mechanism
-		return dailyTask, err
+		// The photo was stored for a completion that was not saved
+		if updateReq.EvidencePhotoURL != nil {
+			if err := s.evidenceStore.DeletePhoto(*updateReq.EvidencePhotoURL); err != nil {
+				logging.FromContext(ctx).Warn("Failed to delete evidence photo", "url", *updateReq.EvidencePhotoURL, "error", err)
+			}
+		}
+		return nil, err
+	}
+	if !pending {
+		recordTaskCompleted(&dailyTask.Task, award)
 	}
 
+	// Flag suspicious completion patterns for admin review
+	s.antiCheat.EvaluateCompletion(ctx, userID, dailyTask, now)
+
 	// Get updated task
-	updatedTask, err := s.taskRepo.GetDailyTaskByID(dailyTaskID)
+	updatedTask, err := s.taskRepo.GetDailyTaskByID(ctx, dailyTaskID)
 	if err != nil {
 		return dailyTask, nil // Return original if we can't get updated
 	}
@@ -134,10 +295,128 @@ func (s *TaskService) CompleteTask(userID uint, dailyTaskID uint) (*models.Daily
 	return updatedTask, nil
 }
 
-// GetUserDailyTasks returns daily tasks for a specific user
-func (s *TaskService) GetUserDailyTasks(userID uint) ([]models.DailyTask, error) {
+// GetVerificationQueue returns pending completions the reviewer is allowed to review
+func (s *TaskService) GetVerificationQueue(ctx context.Context, reviewer *models.User) ([]models.DailyTask, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetVerificationQueue")
+	defer span.End()
+
+	return s.taskRepo.GetPendingVerifications(ctx, reviewer.ID, reviewableModes(reviewer))
+}
+
+// ApproveCompletion approves a pending completion and awards its points
+func (s *TaskService) ApproveCompletion(ctx context.Context, reviewer *models.User, dailyTaskID uint) (*models.DailyTask, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.ApproveCompletion")
+	defer span.End()
+
+	dailyTask, err := s.getReviewableTask(ctx, reviewer, dailyTaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	completedAt := now
+	if dailyTask.CompletedAt != nil {
+		completedAt = *dailyTask.CompletedAt
+	}
+	status := models.VerificationStatusApproved
+	var award *pointsAward
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := s.taskRepo.ReviewDailyTask(ctx, dailyTaskID, &models.UpdateDailyTaskRequest{
+			VerificationStatus: &status,
+			VerifiedBy:         &reviewer.ID,
+			VerifiedAt:         &now,
+		})
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("task is not pending verification")
+			}
+			return err
+		}
+		award, err = s.awardTaskPoints(ctx, dailyTask, completedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordTaskCompleted(&dailyTask.Task, award)
+
+	return s.taskRepo.GetDailyTaskByID(ctx, dailyTaskID)
+}
+
+// RejectCompletion rejects a pending completion so the user can resubmit it
+func (s *TaskService) RejectCompletion(ctx context.Context, reviewer *models.User, dailyTaskID uint, reason string) (*models.DailyTask, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.RejectCompletion")
+	defer span.End()
+
+	if _, err := s.getReviewableTask(ctx, reviewer, dailyTaskID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	status := models.VerificationStatusRejected
+	err := s.taskRepo.ReviewDailyTask(ctx, dailyTaskID, &models.UpdateDailyTaskRequest{
+		IsCompleted:        &[]bool{false}[0], // Pointer to false
+		VerificationStatus: &status,
+		VerifiedBy:         &reviewer.ID,
+		VerifiedAt:         &now,
+		RejectionReason:    &reason,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("task is not pending verification")
+		}
+		return nil, err
+	}
+
+	return s.taskRepo.GetDailyTaskByID(ctx, dailyTaskID)
+}
+
+// GetEvidencePhoto opens the evidence photo stored under photoURL for a viewer allowed to see
+// it: the user who completed the task, moderators and admins, and while the completion awaits
+// verification, the reviewers it is queued for
+func (s *TaskService) GetEvidencePhoto(ctx context.Context, viewer *models.User, photoURL string) (io.ReadSeekCloser, string, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetEvidencePhoto")
+	defer span.End()
+
+	dailyTask, err := s.taskRepo.GetDailyTaskByEvidencePhoto(ctx, photoURL)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New("evidence not found")
+		}
+		return nil, "", err
+	}
+
+	allowed := dailyTask.UserID == viewer.ID ||
+		viewer.Role == models.RoleModerator || viewer.Role == models.RoleAdmin
+	if !allowed && dailyTask.VerificationStatus == models.VerificationStatusPending {
+		for _, mode := range reviewableModes(viewer) {
+			if dailyTask.Task.VerificationMode == mode {
+				allowed = true
+			}
+		}
+	}
+	// Photos of other users' tasks are reported as missing rather than forbidden
+	if !allowed {
+		return nil, "", errors.New("evidence not found")
+	}
+
+	photo, contentType, err := s.evidenceStore.OpenPhoto(photoURL)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, "", errors.New("evidence not found")
+		}
+		return nil, "", err
+	}
+	return photo, contentType, nil
+}
+
+// GetUserDailyTasks returns today's daily tasks for a specific user
+func (s *TaskService) GetUserDailyTasks(ctx context.Context, userID uint) ([]models.DailyTask, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetUserDailyTasks")
+	defer span.End()
+
 	// Verify user exists
-	_, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -145,12 +424,15 @@ func (s *TaskService) GetUserDailyTasks(userID uint) ([]models.DailyTask, error)
 		return nil, err
 	}
 
-	return s.taskRepo.GetDailyTasksByUserID(userID)
+	return s.taskRepo.GetDailyTasksForDate(ctx, userID, localDate(time.Now(), user.Timezone))
 }
 
 // GetTaskByID returns a specific task (public endpoint)
-func (s *TaskService) GetTaskByID(taskID uint) (*models.Task, error) {
-	task, err := s.taskRepo.GetByID(taskID)
+func (s *TaskService) GetTaskByID(ctx context.Context, taskID uint) (*models.Task, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.GetTaskByID")
+	defer span.End()
+
+	task, err := s.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("task not found")
@@ -162,24 +444,143 @@ func (s *TaskService) GetTaskByID(taskID uint) (*models.Task, error) {
 
 // Private helper methods
 
-// awardPointsToUser awards points to a user and updates their level
-func (s *TaskService) awardPointsToUser(userID uint, points int) error {
-	user, err := s.userRepo.GetByID(userID)
+// validateEvidence checks that the submitted evidence satisfies the task's requirement
+func (s *TaskService) validateEvidence(task *models.Task, evidence *models.TaskEvidence) error {
+	switch task.EvidenceType {
+	case models.EvidenceNote:
+		if strings.TrimSpace(evidence.Note) == "" {
+			return errors.New("note evidence required")
+		}
+	case models.EvidenceNumeric:
+		if evidence.Value == nil {
+			return errors.New("numeric evidence required")
+		}
+	case models.EvidencePhoto:
+		if evidence.Photo == nil {
+			return errors.New("photo evidence required")
+		}
+	}
+	return nil
+}
+
+// getReviewableTask loads a pending completion and checks the reviewer may act on it
+func (s *TaskService) getReviewableTask(ctx context.Context, reviewer *models.User, dailyTaskID uint) (*models.DailyTask, error) {
+	dailyTask, err := s.taskRepo.GetDailyTaskByID(ctx, dailyTaskID)
 	if err != nil {
-		return err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("daily task not found")
+		}
+		return nil, err
+	}
+
+	if dailyTask.VerificationStatus != models.VerificationStatusPending {
+		return nil, errors.New("task is not pending verification")
+	}
+	if dailyTask.UserID == reviewer.ID {
+		return nil, errors.New("access denied: you cannot verify your own tasks")
+	}
+
+	for _, mode := range reviewableModes(reviewer) {
+		if dailyTask.Task.VerificationMode == mode {
+			return dailyTask, nil
+		}
+	}
+	return nil, errors.New("access denied: insufficient permissions to verify this task")
+}
+
+// requiresVerification reports whether completions of the task need a reviewer
+func requiresVerification(task *models.Task) bool {
+	return task.VerificationMode == models.VerificationPeer || task.VerificationMode == models.VerificationModerator
+}
+
+// reviewableModes returns the verification modes a user may review
+func reviewableModes(reviewer *models.User) []string {
+	if reviewer.Role == models.RoleModerator || reviewer.Role == models.RoleAdmin {
+		return []string{models.VerificationPeer, models.VerificationModerator}
+	}
+	return []string{models.VerificationPeer}
+}
+
+// pointsAward is what awarding points changed, counted in the metrics once the transaction
+// awarding them has committed
+type pointsAward struct {
+	points    int
+	leveledUp bool
+}
+
+// awardTaskPoints awards a completed task's points and records its task.completed event.
+// It must run in the transaction that marks the task completed or approved.
+func (s *TaskService) awardTaskPoints(ctx context.Context, dailyTask *models.DailyTask, completedAt time.Time) (*pointsAward, error) {
+	award, err := s.awardPointsToUser(ctx, dailyTask.UserID, dailyTask.Points)
+	if err != nil {
+		return nil, err
+	}
+	return award, recordEvent(ctx, s.outboxRepo, models.EventTaskCompleted, dailyTask.UserID, &models.TaskCompletedEvent{
+		UserID:      dailyTask.UserID,
+		DailyTaskID: dailyTask.ID,
+		TaskID:      dailyTask.TaskID,
+		Title:       dailyTask.Task.Title,
+		Category:    dailyTask.Task.Category,
+		Difficulty:  dailyTask.Task.Difficulty,
+		Points:      dailyTask.Points,
+		CompletedAt: completedAt,
+	})
+}
+
+// awardPointsToUser awards points to a user and updates their level, recording a
+// user.leveled_up event when the level rises. The user row stays locked until the
+// surrounding transaction ends, so concurrent awards are not lost.
+func (s *TaskService) awardPointsToUser(ctx context.Context, userID uint, points int) (*pointsAward, error) {
+	ctx, span := tracing.Start(ctx, "TaskService.awardPointsToUser")
+	defer span.End()
+
+	user, err := s.userRepo.GetByIDForUpdate(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
 	newPoints := user.Points + points
 	newLevel := s.calculateLevelFromPoints(newPoints)
-	character := s.getCharacterForLevel(newLevel)
 
 	updateReq := &models.UpdateUserRequest{
-		Points:    &newPoints,
-		Level:     &newLevel,
-		Character: &character,
+		Points: &newPoints,
+		Level:  &newLevel,
+	}
+
+	// Keep an equipped character, otherwise evolve with the level
+	if !hasEquippedCharacter(ctx, s.achievementRepo, userID) {
+		character := s.getCharacterForLevel(newLevel)
+		updateReq.Character = &character
+	}
+
+	if err := s.userRepo.Update(ctx, userID, updateReq); err != nil {
+		return nil, err
+	}
+	award := &pointsAward{points: points, leveledUp: newLevel > user.Level}
+	if !award.leveledUp {
+		return award, nil
 	}
 
-	return s.userRepo.Update(userID, updateReq)
+	character := user.Character
+	if updateReq.Character != nil {
+		character = *updateReq.Character
+	}
+	return award, recordEvent(ctx, s.outboxRepo, models.EventUserLeveledUp, userID, &models.UserLeveledUpEvent{
+		UserID:        userID,
+		PreviousLevel: user.Level,
+		Level:         newLevel,
+		Points:        newPoints,
+		Character:     character,
+	})
+}
+
+// recordTaskCompleted counts a committed completion that earned the user points
+func recordTaskCompleted(task *models.Task, award *pointsAward) {
+	metrics.TasksCompleted.WithLabelValues(task.Category, task.Difficulty).Inc()
+	metrics.PointsAwarded.Add(float64(award.points))
+	if award.leveledUp {
+		metrics.LevelUps.Inc()
+	}
 }
 
 // calculateLevelFromPoints calculates user level based on total points
@@ -200,16 +601,5 @@ func (s *TaskService) calculateLevelFromPoints(points int) int {
 
 // getCharacterForLevel returns the character name for a given level
 func (s *TaskService) getCharacterForLevel(level int) string {
-	characters := map[int]string{
-		1: "Rookie Hero",
-		2: "Bronze Warrior",
-		3: "Silver Champion",
-		4: "Gold Legend",
-		5: "Platinum Master",
-	}
-
-	if character, exists := characters[level]; exists {
-		return character
-	}
-	return "Rookie Hero" // Default fallback
+	return characterForLevel(level)
 } 
\ No newline at end of file
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+)
+
+// memoryEvidenceStore keeps evidence photos in memory
+type memoryEvidenceStore struct {
+	photos map[string]string
+	saved  int
+}
+
+func (s *memoryEvidenceStore) SavePhoto(userID uint, content io.Reader) (string, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	s.saved++
+	photoURL := fmt.Sprintf("/evidence/%d-%d.jpg", userID, s.saved)
+	s.photos[photoURL] = string(data)
+	return photoURL, nil
+}
+
+func (s *memoryEvidenceStore) OpenPhoto(photoURL string) (io.ReadSeekCloser, string, error) {
+	return nil, "", errors.New("not implemented")
+}
+
+func (s *memoryEvidenceStore) DeletePhoto(photoURL string) error {
+	delete(s.photos, photoURL)
+	return nil
+}
+
+func newTestTaskService() (*TaskService, *memoryStore, *memoryEvidenceStore) {
+	store := newMemoryStore()
+	taskRepo := &storeTaskRepo{store: store}
+	evidenceStore := &memoryEvidenceStore{photos: map[string]string{}}
+	antiCheat := NewAntiCheatService(nil, taskRepo, &config.AntiCheatConfig{BaselineDays: 14, DailySpikeMultiplier: 3, DailySpikeMinPoints: 150})
+	service := NewTaskService(taskRepo, &storeUserRepo{store: store}, &storeAchievementRepo{store: store}, &storeOutboxRepo{store: store}, &memoryTransactor{store: store}, evidenceStore, antiCheat)
+	return service, store, evidenceStore
+}
+
+func TestCompleteTaskAwardsPoints(t *testing.T) {
+	service, store, _ := newTestTaskService()
+	user := store.addUser(&models.User{Points: 90})
+	dailyTask := store.addDailyTask(&models.DailyTask{UserID: user.ID, Task: models.Task{Title: "Push-ups", Points: 20, Category: "strength", Difficulty: "easy", EvidenceType: models.EvidenceNumeric}})
+	reps := 30.0
+
+	completed, err := service.CompleteTask(context.Background(), user.ID, dailyTask.ID, &models.TaskEvidence{Value: &reps})
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if !completed.IsCompleted || completed.VerificationStatus != "" || completed.EvidenceValue == nil || *completed.EvidenceValue != reps {
+		t.Errorf("completed task = %+v", completed)
+	}
+	if got := store.users[user.ID]; got.Points != 110 || got.Level != 2 {
+		t.Errorf("user has %d points at level %d, want 110 at level 2", got.Points, got.Level)
+	}
+	var types []string
+	for _, event := range store.events {
+		types = append(types, event.Type)
+	}
+	if len(types) != 2 || types[0] != models.EventUserLeveledUp || types[1] != models.EventTaskCompleted {
+		t.Errorf("events = %v, want the level up and the completion", types)
+	}
+
+	if _, err := service.CompleteTask(context.Background(), user.ID, dailyTask.ID, &models.TaskEvidence{Value: &reps}); err == nil || err.Error() != "task already completed" {
+		t.Fatalf("second completion: err = %v", err)
+	}
+	if got := store.users[user.ID].Points; got != 110 {
+		t.Errorf("points after the second completion = %d, want 110", got)
+	}
+}
+
+func TestCompleteTaskRejected(t *testing.T) {
+	service, store, _ := newTestTaskService()
+	user := store.addUser(&models.User{})
+	other := store.addUser(&models.User{})
+	expiredAt := time.Now()
+	noteTask := store.addDailyTask(&models.DailyTask{UserID: user.ID, Task: models.Task{Points: 10, EvidenceType: models.EvidenceNote}})
+	photoTask := store.addDailyTask(&models.DailyTask{UserID: user.ID, Task: models.Task{Points: 10, EvidenceType: models.EvidencePhoto}})
+	expired := store.addDailyTask(&models.DailyTask{UserID: user.ID, Task: models.Task{Points: 10}, ExpiredAt: &expiredAt})
+	othersTask := store.addDailyTask(&models.DailyTask{UserID: other.ID, Task: models.Task{Points: 10}})
+
+	tests := []struct {
+		name      string
+		dailyTask *models.DailyTask
+		evidence  *models.TaskEvidence
+		want      string
+	}{
+		{"blank note", noteTask, &models.TaskEvidence{Note: "  "}, "note evidence required"},
+		{"missing photo", photoTask, nil, "photo evidence required"},
+		{"expired", expired, nil, "task expired"},
+		{"another user's task", othersTask, nil, "access denied: you can only complete your own tasks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.CompleteTask(context.Background(), user.ID, tt.dailyTask.ID, tt.evidence); err == nil || err.Error() != tt.want {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if store.users[user.ID].Points != 0 || len(store.events) != 0 {
+		t.Errorf("rejected completions awarded points or recorded events")
+	}
+}
+
+func TestCompleteTaskLosingRaceReportsAlreadyCompleted(t *testing.T) {
+	service, store, _ := newTestTaskService()
+	user := store.addUser(&models.User{})
+	dailyTask := store.addDailyTask(&models.DailyTask{UserID: user.ID, Task: models.Task{Points: 10}})
+
+	service.taskRepo = &racingTaskRepo{storeTaskRepo{store: store}}
+
+	if _, err := service.CompleteTask(context.Background(), user.ID, dailyTask.ID, nil); err == nil || err.Error() != "task already completed" {
+		t.Fatalf("err = %v, want task already completed", err)
+	}
+	if store.users[user.ID].Points != 0 || len(store.events) != 0 {
+		t.Errorf("the losing completion awarded points or recorded events")
+	}
+}
+
+// racingTaskRepo completes a daily task between the service loading it and completing it
+type racingTaskRepo struct {
+	storeTaskRepo
+}
+
+func (r *racingTaskRepo) CompleteDailyTask(ctx context.Context, id uint, updates *models.UpdateDailyTaskRequest) error {
+	r.store.dailyTasks[id].IsCompleted = true
+	return r.storeTaskRepo.CompleteDailyTask(ctx, id, updates)
+}
+
+func TestCompleteTaskDeletesPhotoWhenNotSaved(t *testing.T) {
+	service, store, evidenceStore := newTestTaskService()
+	user := store.addUser(&models.User{})
+	dailyTask := store.addDailyTask(&models.DailyTask{UserID: user.ID, Task: models.Task{Points: 10, EvidenceType: models.EvidencePhoto}})
+	store.fail["Update"] = errTest
+
+	if _, err := service.CompleteTask(context.Background(), user.ID, dailyTask.ID, &models.TaskEvidence{Photo: strings.NewReader("photo")}); err == nil {
+		t.Fatal("CompleteTask succeeded although the points could not be awarded")
+	}
+	if evidenceStore.saved != 1 || len(evidenceStore.photos) != 0 {
+		t.Errorf("saved %d photos and kept %v, want the photo deleted", evidenceStore.saved, evidenceStore.photos)
+	}
+	if store.dailyTasks[dailyTask.ID].IsCompleted {
+		t.Error("the completion was kept although its transaction failed")
+	}
+}
+
+func TestVerifiedCompletionAwardsPointsOnApproval(t *testing.T) {
+	service, store, evidenceStore := newTestTaskService()
+	user := store.addUser(&models.User{})
+	peer := store.addUser(&models.User{Role: models.RoleUser})
+	dailyTask := store.addDailyTask(&models.DailyTask{UserID: user.ID, Task: models.Task{Points: 40, EvidenceType: models.EvidencePhoto, VerificationMode: models.VerificationPeer}})
+
+	completed, err := service.CompleteTask(context.Background(), user.ID, dailyTask.ID, &models.TaskEvidence{Photo: strings.NewReader("photo")})
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if completed.VerificationStatus != models.VerificationStatusPending || evidenceStore.photos[completed.EvidencePhotoURL] != "photo" {
+		t.Errorf("completed task = %+v", completed)
+	}
+	if store.users[user.ID].Points != 0 || len(store.events) != 0 {
+		t.Fatal("a completion pending verification awarded points")
+	}
+	if _, err := service.CompleteTask(context.Background(), user.ID, dailyTask.ID, nil); err == nil || err.Error() != "task pending verification" {
+		t.Errorf("resubmission while pending: err = %v", err)
+	}
+
+	if _, err := service.ApproveCompletion(context.Background(), user, dailyTask.ID); err == nil || err.Error() != "access denied: you cannot verify your own tasks" {
+		t.Errorf("self approval: err = %v", err)
+	}
+	approved, err := service.ApproveCompletion(context.Background(), peer, dailyTask.ID)
+	if err != nil {
+		t.Fatalf("ApproveCompletion: %v", err)
+	}
+	if approved.VerificationStatus != models.VerificationStatusApproved || approved.VerifiedBy == nil || *approved.VerifiedBy != peer.ID {
+		t.Errorf("approved task = %+v", approved)
+	}
+	if got := store.users[user.ID].Points; got != 40 {
+		t.Errorf("points = %d, want 40", got)
+	}
+
+	if _, err := service.ApproveCompletion(context.Background(), peer, dailyTask.ID); err == nil || err.Error() != "task is not pending verification" {
+		t.Errorf("second approval: err = %v", err)
+	}
+	if got := store.users[user.ID].Points; got != 40 {
+		t.Errorf("points after the second approval = %d, want 40", got)
+	}
+}
+
+func TestRejectedCompletionCanBeResubmitted(t *testing.T) {
+	service, store, _ := newTestTaskService()
+	user := store.addUser(&models.User{})
+	moderator := store.addUser(&models.User{Role: models.RoleModerator})
+	peer := store.addUser(&models.User{Role: models.RoleUser})
+	dailyTask := store.addDailyTask(&models.DailyTask{UserID: user.ID, Task: models.Task{Points: 40, EvidenceType: models.EvidenceNote, VerificationMode: models.VerificationModerator}})
+
+	if _, err := service.CompleteTask(context.Background(), user.ID, dailyTask.ID, &models.TaskEvidence{Note: "Ran 5k"}); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if _, err := service.RejectCompletion(context.Background(), peer, dailyTask.ID, "No route"); err == nil || err.Error() != "access denied: insufficient permissions to verify this task" {
+		t.Errorf("rejection by a peer: err = %v", err)
+	}
+	rejected, err := service.RejectCompletion(context.Background(), moderator, dailyTask.ID, "No route")
+	if err != nil {
+		t.Fatalf("RejectCompletion: %v", err)
+	}
+	if rejected.IsCompleted || rejected.VerificationStatus != models.VerificationStatusRejected || rejected.RejectionReason != "No route" {
+		t.Errorf("rejected task = %+v", rejected)
+	}
+
+	resubmitted, err := service.CompleteTask(context.Background(), user.ID, dailyTask.ID, &models.TaskEvidence{Note: "Ran 5k, route attached"})
+	if err != nil {
+		t.Fatalf("resubmission: %v", err)
+	}
+	if resubmitted.VerificationStatus != models.VerificationStatusPending {
+		t.Errorf("resubmitted status = %q, want pending", resubmitted.VerificationStatus)
+	}
+	if store.users[user.ID].Points != 0 {
+		t.Error("a rejected completion awarded points")
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"time"
+)
+
+// dateLayout is the format of local calendar dates such as DailyTask.AssignedDate
+const dateLayout = "2006-01-02"
+
+// validTimezone reports whether name is an IANA timezone such as "Europe/Berlin"
+func validTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// localDate returns the calendar date at t in the given timezone, falling back to UTC for
+// timezones this system does not know
+func localDate(t time.Time, timezone string) string {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	return t.In(location).Format(dateLayout)
+}
+
+// addDays moves a calendar date by a number of days
+func addDays(date string, days int) string {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, days).Format(dateLayout)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenSigner signs and verifies access tokens. With asymmetric keys configured it signs
+// with the newest active key and accepts any unretired key by kid; otherwise it falls back
+// to HS256 with the shared secret.
+type TokenSigner struct {
+	secret []byte
+	keys   []config.SigningKey
+}
+
+// NewTokenSigner creates a token signer from the authentication configuration
+func NewTokenSigner(authConfig *config.AuthConfig) (*TokenSigner, error) {
+	signer := &TokenSigner{keys: authConfig.SigningKeys}
+	if len(signer.keys) == 0 {
+		if authConfig.JWTSecret == "" {
+			return nil, errors.New("no JWT signing key or secret configured")
+		}
+		signer.secret = []byte(authConfig.JWTSecret)
+		return signer, nil
+	}
+
+	if _, err := signer.signingKey(time.Now()); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// Sign signs the claims with the current key
+func (s *TokenSigner) Sign(claims jwt.Claims) (string, error) {
+	if s.secret != nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	}
+
+	key, err := s.signingKey(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.PrivateKey)
+}
+
+// Parse verifies a token and decodes it into claims
+func (s *TokenSigner) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, s.keyFunc, jwt.WithValidMethods(s.validMethods()))
+}
+
+// JWKS returns the public keys other services use to verify tokens. Keys scheduled
+// for future activation are included so verifiers can cache them before rotation.
+func (s *TokenSigner) JWKS() models.JSONWebKeySet {
+	set := models.JSONWebKeySet{Keys: []models.JSONWebKey{}}
+	now := time.Now()
+	for _, key := range s.keys {
+		if key.IsRetired(now) {
+			continue
+		}
+
+		jwk := models.JSONWebKey{Use: "sig", Alg: key.Algorithm, Kid: key.KID}
+		switch publicKey := key.PublicKey().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// signingKey returns the most recently activated key that is not retired
+func (s *TokenSigner) signingKey(now time.Time) (*config.SigningKey, error) {
+	var current *config.SigningKey
+	for i := range s.keys {
+		key := &s.keys[i]
+		if key.ActiveFrom.After(now) || key.IsRetired(now) {
+			continue
+		}
+		if current == nil || key.ActiveFrom.After(current.ActiveFrom) {
+			current = key
+		}
+	}
+	if current == nil {
+		return nil, errors.New("no active JWT signing key")
+	}
+	return current, nil
+}
+
+func (s *TokenSigner) keyFunc(token *jwt.Token) (interface{}, error) {
+	if s.secret != nil {
+		return s.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	now := time.Now()
+	for _, key := range s.keys {
+		if key.KID != kid || key.IsRetired(now) {
+			continue
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.PublicKey(), nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %q", kid)
+}
+
+func (s *TokenSigner) validMethods() []string {
+	if s.secret != nil {
+		return []string{config.SigningAlgHS256}
+	}
+	return []string{config.SigningAlgRS256, config.SigningAlgEdDSA}
+}
+
+func signingMethod(algorithm string) jwt.SigningMethod {
+	if algorithm == config.SigningAlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
@@ -1,34 +1,68 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fithero-backend/config"
+	"fithero-backend/logging"
 	"fithero-backend/models"
 	"fithero-backend/repositories"
+	"fithero-backend/tracing"
+	"fmt"
+	"strings"
+	"time"
 	"gorm.io/gorm"
 )
 
+// UsernameCooldownError is returned when a user changes their username again too soon
+type UsernameCooldownError struct {
+	NextChangeAt time.Time
+}
+
+func (e *UsernameCooldownError) Error() string {
+	return fmt.Sprintf("username can be changed again at %s", e.NextChangeAt.Format(time.RFC3339))
+}
+
 type UserService struct {
 	userRepo        repositories.UserRepositoryInterface
 	taskRepo        repositories.TaskRepositoryInterface
 	achievementRepo repositories.AchievementRepositoryInterface
+	usernames       *UsernamePolicy
+	usernameConfig  *config.UsernameConfig
+	localAuth       *LocalAuthService
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo repositories.UserRepositoryInterface, taskRepo repositories.TaskRepositoryInterface, achievementRepo repositories.AchievementRepositoryInterface) *UserService {
+func NewUserService(userRepo repositories.UserRepositoryInterface, taskRepo repositories.TaskRepositoryInterface, achievementRepo repositories.AchievementRepositoryInterface, usernames *UsernamePolicy, usernameConfig *config.UsernameConfig, localAuth *LocalAuthService) *UserService {
 	return &UserService{
 		userRepo:        userRepo,
 		taskRepo:        taskRepo,
 		achievementRepo: achievementRepo,
+		usernames:       usernames,
+		usernameConfig:  usernameConfig,
+		localAuth:       localAuth,
 	}
 }
 
 // CreateUser creates a new user with business logic validation
-func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.User, error) {
+func (s *UserService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
 	// Check if email already exists
-	if existingUser, _ := s.userRepo.GetByEmail(req.Email); existingUser != nil {
+	if existingUser, _ := s.userRepo.GetByEmail(ctx, req.Email); existingUser != nil {
 		return nil, errors.New("email already exists")
 	}
 
+	if err := s.usernames.Validate(req.Username); err != nil {
+		return nil, err
+	}
+	if taken, err := s.userRepo.IsUsernameTaken(ctx, req.Username, 0); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, errors.New("username taken")
+	}
+
 	// Create new user with default values
 	user := &models.User{
 		Username:  req.Username,
@@ -40,7 +74,7 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.User, e
 		IsActive:  true,
 	}
 
-	createdUser, err := s.userRepo.Create(user)
+	createdUser, err := s.userRepo.Create(ctx, user)
 	if err != nil {
 		return nil, err
 	}
@@ -49,20 +83,31 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest) (*models.User, e
 }
 
 // GetUserByID retrieves a user by ID
-func (s *UserService) GetUserByID(id uint) (*models.User, error) {
-	user, err := s.userRepo.GetByID(id)
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
 		}
 		return nil, err
 	}
-	return user, nil
+
+	users := []models.User{*user}
+	if err := s.attachLoadouts(ctx, users); err != nil {
+		return nil, err
+	}
+	return &users[0], nil
 }
 
-// UpdateUser updates user information with business logic
-func (s *UserService) UpdateUser(id uint, req *models.UpdateUserRequest) error {
-	user, err := s.userRepo.GetByID(id)
+// UpdateUser updates the user-editable profile fields with business logic
+func (s *UserService) UpdateUser(ctx context.Context, id uint, req *models.UpdateProfileRequest) error {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("user not found")
@@ -71,32 +116,122 @@ func (s *UserService) UpdateUser(id uint, req *models.UpdateUserRequest) error {
 	}
 
 	// Check for email conflicts
-	if req.Email != nil && *req.Email != user.Email {
-		if existingUser, _ := s.userRepo.GetByEmail(*req.Email); existingUser != nil {
+	emailChanged := req.Email != nil && !strings.EqualFold(*req.Email, user.Email)
+	if emailChanged {
+		if existingUser, _ := s.userRepo.GetByEmail(ctx, *req.Email); existingUser != nil {
 			return errors.New("email already exists")
 		}
 	}
 
-	// Update level based on points if points are being updated
-	if req.Points != nil {
-		newLevel := s.calculateLevelFromPoints(*req.Points)
-		req.Level = &newLevel
-		character := s.getCharacterForLevel(newLevel)
-		req.Character = &character
+	if req.Timezone != nil && !validTimezone(*req.Timezone) {
+		return errors.New("invalid timezone")
+	}
+
+	// Username changes follow the username policy and cooldown
+	if req.Username != nil && *req.Username != user.Username {
+		if err := s.changeUsername(ctx, user, *req.Username); err != nil {
+			return err
+		}
+	}
+
+	err = s.userRepo.Update(ctx, id, &models.UpdateUserRequest{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Timezone:  req.Timezone,
+	})
+	if err != nil || !emailChanged {
+		return err
+	}
+
+	// A new address is unverified until the user confirms it from the email sent there, so it
+	// cannot be used to link provider accounts or gain the admin role in the meantime
+	if err := s.userRepo.ChangeEmail(ctx, id, *req.Email); err != nil {
+		return err
+	}
+	user.Email = *req.Email
+	user.EmailVerifiedAt = nil
+	if err := s.localAuth.SendVerification(ctx, user); err != nil {
+		logging.FromContext(ctx).Error("Failed to send verification email", "user_id", id, "error", err)
+	}
+	return nil
+}
+
+// ChangeUsername renames a user. The old username keeps redirecting to the user and cannot
+// be taken by anyone else.
+func (s *UserService) ChangeUsername(ctx context.Context, id uint, username string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ChangeUsername")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
 	}
 
-	// Update level and character if level is being updated directly
-	if req.Level != nil {
-		character := s.getCharacterForLevel(*req.Level)
-		req.Character = &character
+	if username != user.Username {
+		if err := s.changeUsername(ctx, user, username); err != nil {
+			return nil, err
+		}
 	}
+	return s.GetUserByID(ctx, id)
+}
+
+// GetPublicProfile returns the public profile for a username. When the username belongs to a
+// user who has since changed it, the current username is returned instead so the caller can redirect.
+func (s *UserService) GetPublicProfile(ctx context.Context, username string) (*models.PublicProfile, string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetPublicProfile")
+	defer span.End()
 
-	return s.userRepo.Update(id, req)
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		history, historyErr := s.userRepo.GetUsernameHistory(ctx, username)
+		if historyErr != nil {
+			if errors.Is(historyErr, gorm.ErrRecordNotFound) {
+				return nil, "", errors.New("user not found")
+			}
+			return nil, "", historyErr
+		}
+		user, err = s.userRepo.GetByID(ctx, history.UserID)
+		if err == nil && user.IsActive {
+			return nil, user.Username, nil
+		}
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New("user not found")
+		}
+		return nil, "", err
+	}
+	if !user.IsActive {
+		return nil, "", errors.New("user not found")
+	}
+
+	users := []models.User{*user}
+	if err := s.attachLoadouts(ctx, users); err != nil {
+		return nil, "", err
+	}
+	return &models.PublicProfile{
+		ID:        user.ID,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		Picture:   user.Picture,
+		Level:     user.Level,
+		Points:    user.Points,
+		Character: user.Character,
+		JobTitle:  user.JobTitle,
+		Loadout:   users[0].Loadout,
+		CreatedAt: user.CreatedAt,
+	}, "", nil
 }
 
 // DeleteUser soft deletes a user
-func (s *UserService) DeleteUser(id uint) error {
-	_, err := s.userRepo.GetByID(id)
+func (s *UserService) DeleteUser(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
+	_, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("user not found")
@@ -104,11 +239,14 @@ func (s *UserService) DeleteUser(id uint) error {
 		return err
 	}
 
-	return s.userRepo.Delete(id)
+	return s.userRepo.Delete(ctx, id)
 }
 
 // GetLeaderboard returns top users ordered by points
-func (s *UserService) GetLeaderboard(limit int) ([]models.User, error) {
+func (s *UserService) GetLeaderboard(ctx context.Context, limit int) ([]models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetLeaderboard")
+	defer span.End()
+
 	if limit <= 0 {
 		limit = 10 // Default limit
 	}
@@ -117,57 +255,237 @@ func (s *UserService) GetLeaderboard(limit int) ([]models.User, error) {
 	}
 	
 	// Get users sorted by points in descending order
-	return s.userRepo.GetTopUsersByPoints(limit)
+	users, err := s.userRepo.GetTopUsersByPoints(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachLoadouts(ctx, users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// EvaluateStreaks counts every day that has ended in each user's timezone towards their
+// streak: a day with a completed task extends it, any other day resets it. Days missed while
+// the job was not running are caught up on the next run.
+func (s *UserService) EvaluateStreaks(ctx context.Context, now time.Time) error {
+	ctx, span := tracing.Start(ctx, "UserService.EvaluateStreaks")
+	defer span.End()
+
+	timezones, err := s.userRepo.GetTimezones(ctx)
+	if err != nil {
+		return err
+	}
+
+	evaluated := 0
+	for _, timezone := range timezones {
+		yesterday := addDays(localDate(now, timezone), -1)
+		users, err := s.userRepo.GetUsersForStreakEvaluation(ctx, timezone, yesterday)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := s.evaluateStreak(ctx, &user, yesterday); err != nil {
+				return err
+			}
+			evaluated++
+		}
+	}
+
+	logging.FromContext(ctx).Info("Evaluated streaks", "users", evaluated)
+	return nil
+}
+
+// evaluateStreak updates a user's streak with the days after the last evaluated one, up to
+// and including through
+func (s *UserService) evaluateStreak(ctx context.Context, user *models.User, through string) error {
+	last, err := time.Parse(dateLayout, through)
+	if err != nil {
+		return err
+	}
+	// Streaks of users who were never evaluated start with the most recent day
+	from := last.AddDate(0, 0, -1)
+	if evaluatedOn, err := time.Parse(dateLayout, user.StreakEvaluatedOn); err == nil {
+		from = evaluatedOn
+	}
+
+	dates, err := s.taskRepo.GetCompletedDates(ctx, user.ID, from.Format(dateLayout), through)
+	if err != nil {
+		return err
+	}
+	completed := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		completed[date] = true
+	}
+
+	streak, longest := user.CurrentStreak, user.LongestStreak
+	for day := from.AddDate(0, 0, 1); !day.After(last); day = day.AddDate(0, 0, 1) {
+		if completed[day.Format(dateLayout)] {
+			streak++
+			longest = max(longest, streak)
+		} else {
+			streak = 0
+		}
+	}
+
+	return s.userRepo.UpdateStreak(ctx, user.ID, streak, longest, through)
+}
+
+// SnapshotLeaderboard records the current positions of the top size users under today's UTC
+// date. Only the first snapshot of a day is kept.
+func (s *UserService) SnapshotLeaderboard(ctx context.Context, now time.Time, size int) error {
+	ctx, span := tracing.Start(ctx, "UserService.SnapshotLeaderboard")
+	defer span.End()
+
+	users, err := s.userRepo.GetTopUsersByPoints(ctx, size)
+	if err != nil {
+		return err
+	}
+
+	date := now.UTC().Format(dateLayout)
+	entries := make([]models.LeaderboardSnapshot, 0, len(users))
+	for i, user := range users {
+		entries = append(entries, models.LeaderboardSnapshot{
+			Date:   date,
+			UserID: user.ID,
+			Rank:   i + 1,
+			Points: user.Points,
+			Level:  user.Level,
+		})
+	}
+	if err := s.userRepo.CreateLeaderboardSnapshot(ctx, entries); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Recorded leaderboard snapshot", "date", date, "users", len(entries))
+	return nil
 }
 
 // UpdateUserLevel updates user level based on their current level
-func (s *UserService) UpdateUserLevel(userID uint) error {
-	user, err := s.userRepo.GetByID(userID)
+func (s *UserService) UpdateUserLevel(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUserLevel")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return err
 	}
 
 	newLevel := s.calculateLevelFromPoints(user.Points)
 	if newLevel != user.Level {
-		character := s.getCharacterForLevel(newLevel)
 		updateReq := &models.UpdateUserRequest{
-			Level:     &newLevel,
-			Character: &character,
+			Level: &newLevel,
+		}
+		if !hasEquippedCharacter(ctx, s.achievementRepo, userID) {
+			character := s.getCharacterForLevel(newLevel)
+			updateReq.Character = &character
 		}
-		return s.userRepo.Update(userID, updateReq)
+		return s.userRepo.Update(ctx, userID, updateReq)
 	}
 	
 	return nil
 }
 
 // AddPointsToUser adds points to a user and updates their level
-func (s *UserService) AddPointsToUser(userID uint, points int) error {
-	user, err := s.userRepo.GetByID(userID)
+func (s *UserService) AddPointsToUser(ctx context.Context, userID uint, points int) error {
+	ctx, span := tracing.Start(ctx, "UserService.AddPointsToUser")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return err
 	}
 
 	newPoints := user.Points + points
 	newLevel := s.calculateLevelFromPoints(newPoints)
-	character := s.getCharacterForLevel(newLevel)
 	
 	updateReq := &models.UpdateUserRequest{
-		Points:    &newPoints,
-		Level:     &newLevel,
-		Character: &character,
+		Points: &newPoints,
+		Level:  &newLevel,
+	}
+	if !hasEquippedCharacter(ctx, s.achievementRepo, userID) {
+		character := s.getCharacterForLevel(newLevel)
+		updateReq.Character = &character
 	}
 	
-	return s.userRepo.Update(userID, updateReq)
+	return s.userRepo.Update(ctx, userID, updateReq)
 }
 
 // GetUserDailyTasks retrieves daily tasks for a specific user
-func (s *UserService) GetUserDailyTasks(userID uint) ([]models.DailyTask, error) {
-	return s.taskRepo.GetDailyTasksByUserID(userID)
+func (s *UserService) GetUserDailyTasks(ctx context.Context, userID uint) ([]models.DailyTask, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserDailyTasks")
+	defer span.End()
+
+	return s.taskRepo.GetDailyTasksByUserID(ctx, userID)
 }
 
 // GetUserAchievements retrieves achievements for a specific user
-func (s *UserService) GetUserAchievements(userID uint) ([]models.UserAchievement, error) {
-	return s.achievementRepo.GetUserAchievements(userID)
+func (s *UserService) GetUserAchievements(ctx context.Context, userID uint) ([]models.UserAchievement, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserAchievements")
+	defer span.End()
+
+	return s.achievementRepo.GetUserAchievements(ctx, userID)
+}
+
+// attachLoadouts populates the equipped loadout of each user in place
+func (s *UserService) attachLoadouts(ctx context.Context, users []models.User) error {
+	userIDs := make([]uint, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+
+	equipped, err := s.achievementRepo.GetEquippedAchievements(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	loadouts := buildLoadouts(equipped)
+	for i := range users {
+		if loadout, exists := loadouts[users[i].ID]; exists {
+			users[i].Loadout = loadout
+		} else {
+			users[i].Loadout = &models.Loadout{Badges: []models.Achievement{}}
+		}
+	}
+	return nil
+}
+
+// changeUsername validates a new username and applies it. Changing only the letter case
+// does not count towards the cooldown.
+func (s *UserService) changeUsername(ctx context.Context, user *models.User, username string) error {
+	if err := s.usernames.Validate(username); err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(username, user.Username) {
+		lastChange, err := s.userRepo.GetLastUsernameChange(ctx, user.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if lastChange != nil {
+			if next := lastChange.ChangedAt.Add(s.usernameConfig.ChangeCooldown); time.Now().Before(next) {
+				return &UsernameCooldownError{NextChangeAt: next}
+			}
+		}
+
+		taken, err := s.userRepo.IsUsernameTaken(ctx, username, user.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return errors.New("username taken")
+		}
+	}
+
+	if err := s.userRepo.ChangeUsername(ctx, user.ID, user.Username, username, time.Now()); err != nil {
+		if isUniqueViolation(err, "username") {
+			return errors.New("username taken")
+		}
+		return err
+	}
+	return nil
 }
 
 // calculateLevelFromPoints calculates user level based on total points
@@ -188,16 +506,5 @@ func (s *UserService) calculateLevelFromPoints(points int) int {
 
 // getCharacterForLevel returns the character name for a given level
 func (s *UserService) getCharacterForLevel(level int) string {
-	characters := map[int]string{
-		1: "Rookie Hero",
-		2: "Bronze Warrior",
-		3: "Silver Champion",
-		4: "Gold Legend",
-		5: "Platinum Master",
-	}
-	
-	if character, exists := characters[level]; exists {
-		return character
-	}
-	return "Rookie Hero" // Default fallback
+	return characterForLevel(level)
 } 
\ No newline at end of file
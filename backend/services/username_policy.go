@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"fithero-backend/config"
+	"fithero-backend/repositories"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Username length limits
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 30
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// reservedUsernames cannot be taken because they collide with routes or impersonate staff
+var reservedUsernames = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true, "support": true,
+	"help": true, "staff": true, "moderator": true, "mod": true, "official": true,
+	"fithero": true, "api": true, "auth": true, "login": true, "logout": true,
+	"register": true, "signup": true, "me": true, "profile": true, "settings": true,
+	"users": true, "user": true, "public": true, "leaderboard": true, "tasks": true,
+	"achievements": true, "null": true, "undefined": true, "anonymous": true, "everyone": true,
+}
+
+// blockedWords may not appear anywhere in a username, after undoing common letter substitutions
+var blockedWords = []string{
+	"fuck", "shit", "cunt", "bitch", "whore", "slut", "nigger", "nigga", "faggot",
+	"retard", "rapist", "nazi", "hitler", "porn", "penis", "vagina",
+}
+
+var leetReplacer = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "9", "g",
+	"_", "", ".", "", "-", "",
+)
+
+// UsernamePolicy validates usernames and generates unique ones for new accounts
+type UsernamePolicy struct {
+	userRepo  repositories.UserRepositoryInterface
+	blocklist []string
+}
+
+func NewUsernamePolicy(userRepo repositories.UserRepositoryInterface, usernameConfig *config.UsernameConfig) *UsernamePolicy {
+	return &UsernamePolicy{
+		userRepo:  userRepo,
+		blocklist: append(append([]string{}, blockedWords...), usernameConfig.Blocklist...),
+	}
+}
+
+// Validate checks the format of a username and rejects reserved or offensive names
+func (p *UsernamePolicy) Validate(username string) error {
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength || !usernamePattern.MatchString(username) {
+		return errors.New("invalid username")
+	}
+	if !p.allowed(username) {
+		return errors.New("username not allowed")
+	}
+	return nil
+}
+
+// Generate derives an unused username from an email address, adding a numeric suffix
+// when the plain name is already taken
+func (p *UsernamePolicy) Generate(ctx context.Context, email string) (string, error) {
+	base := p.baseFromEmail(email)
+
+	available, err := p.available(ctx, base)
+	if err != nil {
+		return "", err
+	}
+	if available {
+		return base, nil
+	}
+
+	for _, digits := range []int{2, 3, 4, 4, 6, 6, 8} {
+		suffix, err := randomDigits(digits)
+		if err != nil {
+			return "", err
+		}
+		candidate := truncate(base, maxUsernameLength-len(suffix)) + suffix
+		available, err := p.available(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if available {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("could not generate a unique username")
+}
+
+// baseFromEmail keeps the allowed characters of the email's local part, falling back to
+// "hero" when too little remains or the result is not allowed
+func (p *UsernamePolicy) baseFromEmail(email string) string {
+	local := strings.ToLower(email)
+	if at := strings.Index(local, "@"); at >= 0 {
+		local = local[:at]
+	}
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+
+	var b strings.Builder
+	for _, r := range local {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case r == '.' || r == '-':
+			b.WriteRune('_')
+		}
+	}
+	base := truncate(strings.Trim(b.String(), "_"), maxUsernameLength-8)
+
+	if p.Validate(base) != nil {
+		return "hero"
+	}
+	return base
+}
+
+func (p *UsernamePolicy) available(ctx context.Context, username string) (bool, error) {
+	taken, err := p.userRepo.IsUsernameTaken(ctx, username, 0)
+	if err != nil {
+		return false, err
+	}
+	return !taken, nil
+}
+
+func (p *UsernamePolicy) allowed(username string) bool {
+	lower := strings.ToLower(username)
+	if reservedUsernames[lower] || reservedUsernames[strings.Trim(lower, "_.-")] {
+		return false
+	}
+
+	normalized := leetReplacer.Replace(lower)
+	for _, word := range p.blocklist {
+		if strings.Contains(lower, word) || strings.Contains(normalized, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// isUniqueViolation reports whether err is a Postgres unique violation on a constraint
+// whose name contains the given column
+func isUniqueViolation(err error, column string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && strings.Contains(pgErr.ConstraintName, column)
+}
+
+func randomDigits(n int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < n; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	v, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", n, v), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,583 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/logging"
+	"fithero-backend/metrics"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+	"fithero-backend/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"gorm.io/gorm"
+)
+
+// Headers sent with every webhook request
+const (
+	WebhookEventHeader     = "X-FitHero-Event"
+	WebhookDeliveryHeader  = "X-FitHero-Delivery"
+	WebhookTimestampHeader = "X-FitHero-Timestamp"
+	WebhookSignatureHeader = "X-FitHero-Signature"
+)
+
+const (
+	webhookUserAgent = "FitHero-Webhooks/1.0"
+	// webhookResponseLimit is how much of a receiver's response is kept in the delivery log
+	webhookResponseLimit = 1024
+)
+
+// SignWebhookPayload returns the X-FitHero-Signature value for a request body sent at the
+// given Unix timestamp: "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>"
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookService manages webhook subscriptions and sends them the outbox events they subscribe
+// to. Every replica runs a delivery worker; deliveries are leased while being sent, so each
+// attempt is made by one of them.
+type WebhookService struct {
+	webhookRepo repositories.WebhookRepositoryInterface
+	config      *config.WebhookConfig
+	client      *http.Client
+	stop        chan struct{}
+	once        sync.Once
+	running     sync.WaitGroup
+}
+
+// NewWebhookService creates a webhook service that sends requests with client. A nil client
+// uses one that does not follow redirects and, unless private networks are allowed, only
+// connects to public addresses.
+func NewWebhookService(webhookRepo repositories.WebhookRepositoryInterface, cfg *config.WebhookConfig, client *http.Client) *WebhookService {
+	if client == nil {
+		client = newWebhookClient(cfg)
+	}
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		config:      cfg,
+		client:      client,
+		stop:        make(chan struct{}),
+	}
+}
+
+func newWebhookClient(cfg *config.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		// Checked on the resolved address, so a public hostname cannot point at an internal service
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("webhook address %s is not public", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: cfg.Timeout,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// nonPublicNetworks are the ranges not covered by the net.IP predicates that isPublicIP rejects:
+// carrier-grade NAT, and NAT64, which maps IPv4 addresses, private ones included, into IPv6
+var nonPublicNetworks = []*net.IPNet{
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("64:ff9b::/96"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateURL checks that a webhook URL can be delivered to
+func (s *WebhookService) validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+		return errors.New("invalid webhook url")
+	}
+	if s.config.AllowPrivateNetworks {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return errors.New("webhook url must use https")
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(host, "localhost") {
+		return errors.New("webhook url must not point to a private network")
+	}
+	return nil
+}
+
+// CreateWebhook creates a subscription. The returned secret is only shown once.
+func (s *WebhookService) CreateWebhook(ctx context.Context, adminID uint, req *models.CreateWebhookRequest) (*models.CreateWebhookResponse, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateWebhook")
+	defer span.End()
+
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		token, err := generateRandomToken()
+		if err != nil {
+			return nil, err
+		}
+		secret = models.WebhookSecretPrefix + token
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:         req.URL,
+		Events:      uniqueScopes(req.Events),
+		Secret:      secret,
+		Description: strings.TrimSpace(req.Description),
+		IsActive:    true,
+		CreatedBy:   adminID,
+	}
+	if err := s.webhookRepo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Webhook created", "webhook_id", subscription.ID, "url", subscription.URL)
+	return &models.CreateWebhookResponse{
+		Secret:  secret,
+		Webhook: *subscription,
+	}, nil
+}
+
+// ListWebhooks returns every subscription
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListWebhooks")
+	defer span.End()
+
+	return s.webhookRepo.ListSubscriptions(ctx)
+}
+
+// GetWebhook returns one subscription
+func (s *WebhookService) GetWebhook(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetWebhook")
+	defer span.End()
+
+	return s.getSubscription(ctx, id)
+}
+
+// UpdateWebhook changes a subscription. Enabling it clears its failures, and its pending
+// deliveries are sent again.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id uint, req *models.UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.UpdateWebhook")
+	defer span.End()
+
+	subscription, err := s.getSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the fields in the request are written, so a failure the delivery worker records
+	// in the meantime is not overwritten
+	var fields []string
+	if req.URL != nil {
+		if err := s.validateURL(*req.URL); err != nil {
+			return nil, err
+		}
+		subscription.URL = *req.URL
+		fields = append(fields, "url")
+	}
+	if req.Events != nil {
+		subscription.Events = uniqueScopes(req.Events)
+		fields = append(fields, "events")
+	}
+	if req.Description != nil {
+		subscription.Description = strings.TrimSpace(*req.Description)
+		fields = append(fields, "description")
+	}
+	if req.IsActive != nil && *req.IsActive != subscription.IsActive {
+		subscription.IsActive = *req.IsActive
+		if subscription.IsActive {
+			subscription.ConsecutiveFailures = 0
+			subscription.DisabledAt = nil
+			subscription.DisabledReason = ""
+		} else {
+			now := time.Now()
+			subscription.DisabledAt = &now
+			subscription.DisabledReason = "disabled by an admin"
+		}
+		fields = append(fields, "is_active", "consecutive_failures", "disabled_at", "disabled_reason")
+	}
+	if len(fields) == 0 {
+		return subscription, nil
+	}
+
+	if err := s.webhookRepo.UpdateSubscription(ctx, subscription, fields...); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook not found")
+		}
+		return nil, err
+	}
+	return s.getSubscription(ctx, id)
+}
+
+// DeleteWebhook deletes a subscription and its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteWebhook")
+	defer span.End()
+
+	if _, err := s.getSubscription(ctx, id); err != nil {
+		return err
+	}
+	if err := s.webhookRepo.DeleteSubscription(ctx, id); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Webhook deleted", "webhook_id", id)
+	return nil
+}
+
+// ListDeliveries returns a subscription's delivery log, newest first, optionally filtered by status
+func (s *WebhookService) ListDeliveries(ctx context.Context, id uint, status string, limit int) ([]models.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListDeliveries")
+	defer span.End()
+
+	switch status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+	default:
+		return nil, errors.New("invalid delivery status")
+	}
+	if _, err := s.getSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	return s.webhookRepo.ListDeliveries(ctx, id, status, limit)
+}
+
+// Redeliver queues the event of a delivery to be sent to its subscription again, as a new
+// delivery with the same payload
+func (s *WebhookService) Redeliver(ctx context.Context, deliveryID uint) (*models.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Redeliver")
+	defer span.End()
+
+	original, err := s.webhookRepo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("delivery not found")
+		}
+		return nil, err
+	}
+	subscription, err := s.getSubscription(ctx, original.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscription.IsActive {
+		return nil, errors.New("webhook is disabled")
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		RedeliveryOf:   &original.ID,
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now(),
+	}
+	if err := s.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("Webhook redelivery queued", "webhook_id", subscription.ID, "delivery_id", delivery.ID, "redelivery_of", original.ID)
+	return delivery, nil
+}
+
+// HandleEvent is the outbox handler that queues a delivery of the event for every active
+// subscription to its type. Queuing is idempotent, so the outbox may deliver an event again.
+func (s *WebhookService) HandleEvent(ctx context.Context, event *models.OutboxEvent) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.HandleEvent")
+	defer span.End()
+
+	subscriptions, err := s.webhookRepo.GetActiveSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	var errs []error
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		if !subscription.Subscribes(event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(models.WebhookPayload{
+				ID:        event.ID,
+				Type:      event.Type,
+				CreatedAt: event.CreatedAt,
+				Data:      json.RawMessage(event.Payload),
+			}); err != nil {
+				return err
+			}
+		}
+
+		err := s.webhookRepo.CreateDelivery(ctx, &models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d: %w", subscription.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Start sends due deliveries in the background until ctx is done or Close is called
+func (s *WebhookService) Start(ctx context.Context) {
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		ticker := time.NewTicker(s.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			for s.deliverBatch(ctx) == s.config.BatchSize {
+				select {
+				case <-s.stop:
+					return
+				default:
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the delivery worker and waits for the requests in flight
+func (s *WebhookService) Close() {
+	s.once.Do(func() { close(s.stop) })
+	s.running.Wait()
+}
+
+// PurgeDeliveries deletes finished deliveries older than the retention period
+func (s *WebhookService) PurgeDeliveries(ctx context.Context, now time.Time) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.PurgeDeliveries")
+	defer span.End()
+
+	deleted, err := s.webhookRepo.DeleteFinishedDeliveriesBefore(ctx, now.Add(-s.config.Retention))
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Purged webhook deliveries", "count", deleted)
+	return nil
+}
+
+func (s *WebhookService) getSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	subscription, err := s.webhookRepo.GetSubscription(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook not found")
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// deliverBatch claims due deliveries, sends them concurrently and returns how many it claimed
+func (s *WebhookService) deliverBatch(ctx context.Context) int {
+	now := time.Now()
+	deliveries, err := s.webhookRepo.ClaimDueDeliveries(ctx, now, now.Add(2*s.config.Timeout), s.config.BatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			logging.FromContext(ctx).Warn("Failed to claim webhook deliveries", "error", err)
+		}
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			s.deliver(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+	return len(deliveries)
+}
+
+// deliver makes one attempt to send a delivery and records the outcome. Failed attempts are
+// retried with exponential backoff until the attempts run out, and count towards disabling
+// the subscription.
+func (s *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	// Requests in flight are finished on shutdown rather than cut off
+	ctx = logging.With(context.WithoutCancel(ctx), "webhook_id", delivery.SubscriptionID, "delivery_id", delivery.ID)
+	ctx, span := tracing.Start(ctx, "WebhookService.deliver",
+		attribute.String("event.type", delivery.EventType),
+		attribute.Int("webhook.delivery.id", int(delivery.ID)),
+		attribute.Int("webhook.delivery.attempt", delivery.Attempts),
+	)
+	defer span.End()
+	log := logging.FromContext(ctx)
+
+	subscription, err := s.webhookRepo.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		log.Warn("Failed to load webhook", "error", err)
+		return
+	}
+	// Disabled since the delivery was claimed: put it back unsent, to be sent if the
+	// subscription is enabled again
+	if !subscription.IsActive {
+		delivery.Attempts--
+		delivery.NextAttemptAt = time.Now()
+		if err := s.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+			log.Warn("Failed to release webhook delivery", "error", err)
+		}
+		return
+	}
+
+	err = s.send(ctx, subscription, delivery)
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.Error = ""
+		if err := s.webhookRepo.RecordSuccess(ctx, subscription.ID); err != nil {
+			log.Warn("Failed to reset webhook failures", "error", err)
+		}
+		metrics.WebhookDeliveries.WithLabelValues(delivery.EventType, "succeeded").Inc()
+	case delivery.Attempts >= s.config.MaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.Error = err.Error()
+		metrics.WebhookDeliveries.WithLabelValues(delivery.EventType, "failed").Inc()
+		log.Warn("Gave up webhook delivery", "attempts", delivery.Attempts, "error", err)
+	default:
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = now.Add(retryDelay(s.config.RetryBackoff, s.config.MaxBackoff, delivery.Attempts))
+		metrics.WebhookDeliveries.WithLabelValues(delivery.EventType, "retried").Inc()
+		log.Info("Webhook delivery failed, retrying", "attempt", delivery.Attempts, "retry_at", delivery.NextAttemptAt, "error", err)
+	}
+	if err := s.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Warn("Failed to record webhook delivery", "error", err)
+	}
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	reason := fmt.Sprintf("%d consecutive failed deliveries, last: %s", s.config.DisableAfterFailures, err)
+	disabled, recordErr := s.webhookRepo.RecordFailure(ctx, subscription.ID, s.config.DisableAfterFailures, reason, now)
+	if recordErr != nil {
+		log.Warn("Failed to record webhook failure", "error", recordErr)
+	}
+	if disabled {
+		log.Error("Webhook disabled after repeated failures", "url", subscription.URL, "failures", s.config.DisableAfterFailures)
+	}
+}
+
+// send POSTs a delivery's payload to the subscription URL, recording the response on the
+// delivery. Any status other than 2xx is an error.
+func (s *WebhookService) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(subscription.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.recordAttempt(delivery, start, 0, "")
+		return err
+	}
+	defer resp.Body.Close()
+
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	// Drain a little more so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	s.recordAttempt(delivery, start, resp.StatusCode, strings.ReplaceAll(strings.ToValidUTF8(string(head), ""), "\x00", ""))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *WebhookService) recordAttempt(delivery *models.WebhookDelivery, start time.Time, status int, body string) {
+	elapsed := time.Since(start)
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	delivery.DurationMs = elapsed.Milliseconds()
+	metrics.WebhookRequestDuration.Observe(elapsed.Seconds())
+}
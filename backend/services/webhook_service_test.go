@@ -0,0 +1,598 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fithero-backend/config"
+	"fithero-backend/models"
+	"fithero-backend/repositories"
+
+	"gorm.io/gorm"
+)
+
+// memoryWebhookRepo keeps subscriptions and deliveries in memory. Methods the delivery
+// worker and redeliveries do not use are left to the embedded nil interface.
+type memoryWebhookRepo struct {
+	repositories.WebhookRepositoryInterface
+	mu            sync.Mutex
+	subscriptions map[uint]*models.WebhookSubscription
+	deliveries    []*models.WebhookDelivery
+	beforeUpdate  func() // Called by UpdateSubscription before it writes, to interleave other changes
+}
+
+func newMemoryWebhookRepo(subscriptions ...*models.WebhookSubscription) *memoryWebhookRepo {
+	r := &memoryWebhookRepo{subscriptions: map[uint]*models.WebhookSubscription{}}
+	for _, subscription := range subscriptions {
+		r.subscriptions[subscription.ID] = subscription
+	}
+	return r
+}
+
+func (r *memoryWebhookRepo) GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if subscription, ok := r.subscriptions[id]; ok {
+		copied := *subscription
+		return &copied, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryWebhookRepo) GetActiveSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var active []models.WebhookSubscription
+	for _, subscription := range r.subscriptions {
+		if subscription.IsActive {
+			active = append(active, *subscription)
+		}
+	}
+	return active, nil
+}
+
+func (r *memoryWebhookRepo) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription, fields ...string) error {
+	if r.beforeUpdate != nil {
+		r.beforeUpdate()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.subscriptions[subscription.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	for _, field := range fields {
+		switch field {
+		case "url":
+			stored.URL = subscription.URL
+		case "events":
+			stored.Events = subscription.Events
+		case "description":
+			stored.Description = subscription.Description
+		case "is_active":
+			stored.IsActive = subscription.IsActive
+		case "consecutive_failures":
+			stored.ConsecutiveFailures = subscription.ConsecutiveFailures
+		case "disabled_at":
+			stored.DisabledAt = subscription.DisabledAt
+		case "disabled_reason":
+			stored.DisabledReason = subscription.DisabledReason
+		default:
+			panic("unknown webhook field " + field)
+		}
+	}
+	return nil
+}
+
+func (r *memoryWebhookRepo) RecordSuccess(ctx context.Context, subscriptionID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions[subscriptionID].ConsecutiveFailures = 0
+	return nil
+}
+
+func (r *memoryWebhookRepo) RecordFailure(ctx context.Context, subscriptionID uint, disableAfter int, reason string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription := r.subscriptions[subscriptionID]
+	subscription.ConsecutiveFailures++
+	if !subscription.IsActive || subscription.ConsecutiveFailures < disableAfter {
+		return false, nil
+	}
+	subscription.IsActive = false
+	subscription.DisabledAt = &now
+	subscription.DisabledReason = reason
+	return true, nil
+}
+
+func (r *memoryWebhookRepo) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.ID = uint(len(r.deliveries) + 1)
+	copied := *delivery
+	r.deliveries = append(r.deliveries, &copied)
+	return nil
+}
+
+func (r *memoryWebhookRepo) GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.deliveries) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *r.deliveries[id-1]
+	return &copied, nil
+}
+
+func (r *memoryWebhookRepo) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status != models.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) ||
+			!r.subscriptions[delivery.SubscriptionID].IsActive {
+			continue
+		}
+		delivery.NextAttemptAt = leaseUntil
+		delivery.Attempts++
+		claimed = append(claimed, *delivery)
+	}
+	return claimed, nil
+}
+
+func (r *memoryWebhookRepo) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *delivery
+	r.deliveries[delivery.ID-1] = &copied
+	return nil
+}
+
+// delivery returns the stored state of a delivery
+func (r *memoryWebhookRepo) delivery(id uint) models.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.deliveries[id-1]
+}
+
+// makeDue moves the next attempt of every pending delivery to now, as if its backoff had passed
+func (r *memoryWebhookRepo) makeDue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, delivery := range r.deliveries {
+		delivery.NextAttemptAt = time.Now()
+	}
+}
+
+// webhookReceiver records the requests sent to it and answers them with status
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, status int) *webhookReceiver {
+	t.Helper()
+	receiver := &webhookReceiver{status: status}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, receivedWebhook{header: r.Header.Clone(), body: body})
+		status := receiver.status
+		receiver.mu.Unlock()
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
+
+func testWebhookConfig() *config.WebhookConfig {
+	return &config.WebhookConfig{
+		Timeout:              5 * time.Second,
+		MaxAttempts:          3,
+		RetryBackoff:         time.Minute,
+		MaxBackoff:           90 * time.Second,
+		DisableAfterFailures: 10,
+		PollInterval:         time.Second,
+		BatchSize:            10,
+		AllowPrivateNetworks: true,
+	}
+}
+
+func testSubscription(url string) *models.WebhookSubscription {
+	return &models.WebhookSubscription{
+		ID:       1,
+		URL:      url,
+		Events:   []string{"*"},
+		Secret:   "whsec_test",
+		IsActive: true,
+	}
+}
+
+// queueEvent queues an outbox event for the subscriptions and returns the ID of its delivery
+func queueEvent(t *testing.T, service *WebhookService, repo *memoryWebhookRepo, event *models.OutboxEvent) uint {
+	t.Helper()
+	if err := service.HandleEvent(context.Background(), event); err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	return uint(len(repo.deliveries))
+}
+
+func testOutboxEvent() *models.OutboxEvent {
+	return &models.OutboxEvent{
+		ID:        42,
+		Type:      "task.completed",
+		UserID:    7,
+		Payload:   `{"task_id":3}`,
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusNoContent)
+	repo := newMemoryWebhookRepo(testSubscription(receiver.URL))
+	service := NewWebhookService(repo, testWebhookConfig(), nil)
+	id := queueEvent(t, service, repo, testOutboxEvent())
+
+	if claimed := service.deliverBatch(context.Background()); claimed != 1 {
+		t.Fatalf("deliverBatch claimed %d deliveries, want 1", claimed)
+	}
+
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	// Verified the way a receiver would: from the secret, the timestamp header and the raw body
+	timestamp, err := strconv.ParseInt(request.header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid timestamp header %q", request.header.Get(WebhookTimestampHeader))
+	}
+	if got, want := request.header.Get(WebhookSignatureHeader), SignWebhookPayload("whsec_test", timestamp, request.body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := request.header.Get(WebhookSignatureHeader); got == SignWebhookPayload("another secret", timestamp, request.body) {
+		t.Error("signature verifies with another secret")
+	}
+	if got := request.header.Get(WebhookEventHeader); got != "task.completed" {
+		t.Errorf("event header = %q, want task.completed", got)
+	}
+	if got := request.header.Get(WebhookDeliveryHeader); got != strconv.Itoa(int(id)) {
+		t.Errorf("delivery header = %q, want %d", got, id)
+	}
+
+	var payload struct {
+		ID   uint            `json:"id"`
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if payload.ID != 42 || payload.Type != "task.completed" || string(payload.Data) != `{"task_id":3}` {
+		t.Errorf("payload = %s", request.body)
+	}
+
+	delivery := repo.delivery(id)
+	if delivery.Status != models.WebhookDeliverySucceeded || delivery.DeliveredAt == nil {
+		t.Errorf("delivery status = %q, delivered at %v, want succeeded", delivery.Status, delivery.DeliveredAt)
+	}
+	if delivery.ResponseStatus != http.StatusNoContent {
+		t.Errorf("response status = %d, want %d", delivery.ResponseStatus, http.StatusNoContent)
+	}
+}
+
+func TestWebhookDeliveryRetriesWithBackoff(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusServiceUnavailable)
+	repo := newMemoryWebhookRepo(testSubscription(receiver.URL))
+	cfg := testWebhookConfig()
+	service := NewWebhookService(repo, cfg, nil)
+	id := queueEvent(t, service, repo, testOutboxEvent())
+
+	// The first retry waits RetryBackoff, the second twice as long but at most MaxBackoff
+	for attempt, backoff := range []time.Duration{time.Minute, 90 * time.Second} {
+		before := time.Now()
+		service.deliverBatch(context.Background())
+		after := time.Now()
+
+		delivery := repo.delivery(id)
+		if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != attempt+1 {
+			t.Fatalf("after attempt %d: status = %q, attempts = %d, want pending", attempt+1, delivery.Status, delivery.Attempts)
+		}
+		if delivery.NextAttemptAt.Before(before.Add(backoff)) || delivery.NextAttemptAt.After(after.Add(backoff)) {
+			t.Errorf("after attempt %d: next attempt in %v, want %v", attempt+1, delivery.NextAttemptAt.Sub(before), backoff)
+		}
+		if delivery.ResponseStatus != http.StatusServiceUnavailable || !strings.Contains(delivery.Error, "503") {
+			t.Errorf("after attempt %d: response status = %d, error = %q", attempt+1, delivery.ResponseStatus, delivery.Error)
+		}
+
+		// Not sent again before the backoff has passed
+		if claimed := service.deliverBatch(context.Background()); claimed != 0 {
+			t.Fatalf("after attempt %d: a delivery was retried before its backoff passed", attempt+1)
+		}
+		repo.makeDue()
+	}
+
+	service.deliverBatch(context.Background())
+	delivery := repo.delivery(id)
+	if delivery.Status != models.WebhookDeliveryFailed || delivery.Attempts != cfg.MaxAttempts {
+		t.Errorf("status = %q after %d attempts, want failed after %d", delivery.Status, delivery.Attempts, cfg.MaxAttempts)
+	}
+	if got := len(receiver.received()); got != cfg.MaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", got, cfg.MaxAttempts)
+	}
+
+	repo.makeDue()
+	if claimed := service.deliverBatch(context.Background()); claimed != 0 {
+		t.Error("a failed delivery was sent again")
+	}
+}
+
+func TestWebhookDisabledAfterRepeatedFailures(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	repo := newMemoryWebhookRepo(testSubscription(receiver.URL))
+	cfg := testWebhookConfig()
+	cfg.MaxAttempts = 10
+	cfg.DisableAfterFailures = 3
+	service := NewWebhookService(repo, cfg, nil)
+	queueEvent(t, service, repo, testOutboxEvent())
+
+	for i := 1; i < cfg.DisableAfterFailures; i++ {
+		service.deliverBatch(context.Background())
+		repo.makeDue()
+	}
+	if subscription, _ := repo.GetSubscription(context.Background(), 1); !subscription.IsActive {
+		t.Fatalf("disabled after %d failures, want %d", subscription.ConsecutiveFailures, cfg.DisableAfterFailures)
+	}
+
+	// A success resets the count
+	receiver.mu.Lock()
+	receiver.status = http.StatusOK
+	receiver.mu.Unlock()
+	second := testOutboxEvent()
+	second.ID++
+	queueEvent(t, service, repo, second)
+	service.deliverBatch(context.Background())
+	if subscription, _ := repo.GetSubscription(context.Background(), 1); subscription.ConsecutiveFailures != 0 {
+		t.Fatalf("consecutive failures = %d after a success, want 0", subscription.ConsecutiveFailures)
+	}
+
+	receiver.mu.Lock()
+	receiver.status = http.StatusInternalServerError
+	receiver.mu.Unlock()
+	third := testOutboxEvent()
+	third.ID += 2
+	queueEvent(t, service, repo, third)
+	for i := 0; i < cfg.DisableAfterFailures; i++ {
+		repo.makeDue()
+		service.deliverBatch(context.Background())
+	}
+
+	subscription, _ := repo.GetSubscription(context.Background(), 1)
+	if subscription.IsActive || subscription.DisabledAt == nil {
+		t.Fatalf("still active after %d failures", subscription.ConsecutiveFailures)
+	}
+	if !strings.Contains(subscription.DisabledReason, "3 consecutive failed deliveries") {
+		t.Errorf("disabled reason = %q", subscription.DisabledReason)
+	}
+
+	// Deliveries of a disabled subscription are held back
+	sent := len(receiver.received())
+	repo.makeDue()
+	if claimed := service.deliverBatch(context.Background()); claimed != 0 || len(receiver.received()) != sent {
+		t.Error("a delivery was sent to a disabled webhook")
+	}
+	if _, err := service.Redeliver(context.Background(), 1); err == nil || err.Error() != "webhook is disabled" {
+		t.Errorf("Redeliver to a disabled webhook: err = %v", err)
+	}
+}
+
+func TestRedeliverSendsSameEvent(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	repo := newMemoryWebhookRepo(testSubscription(receiver.URL))
+	service := NewWebhookService(repo, testWebhookConfig(), nil)
+	id := queueEvent(t, service, repo, testOutboxEvent())
+	service.deliverBatch(context.Background())
+
+	redelivery, err := service.Redeliver(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if redelivery.ID == id || redelivery.RedeliveryOf == nil || *redelivery.RedeliveryOf != id {
+		t.Errorf("redelivery %d of %v, want a new delivery of %d", redelivery.ID, redelivery.RedeliveryOf, id)
+	}
+	if redelivery.EventID != 42 || redelivery.Status != models.WebhookDeliveryPending {
+		t.Errorf("redelivery of event %d with status %q, want pending event 42", redelivery.EventID, redelivery.Status)
+	}
+	service.deliverBatch(context.Background())
+
+	requests := receiver.received()
+	if len(requests) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(requests))
+	}
+	// Receivers deduplicate on the event ID in the body, which stays the same; the delivery
+	// header identifies the attempt
+	if string(requests[0].body) != string(requests[1].body) {
+		t.Errorf("redelivered body %s differs from %s", requests[1].body, requests[0].body)
+	}
+	if !strings.Contains(string(requests[1].body), `"id":42`) {
+		t.Errorf("redelivered body %s does not carry event ID 42", requests[1].body)
+	}
+	if requests[0].header.Get(WebhookDeliveryHeader) == requests[1].header.Get(WebhookDeliveryHeader) {
+		t.Error("redelivery was sent with the delivery ID of the original")
+	}
+	if got := repo.delivery(redelivery.ID).Status; got != models.WebhookDeliverySucceeded {
+		t.Errorf("redelivery status = %q, want succeeded", got)
+	}
+
+	if _, err := service.Redeliver(context.Background(), 99); err == nil || err.Error() != "delivery not found" {
+		t.Errorf("Redeliver of an unknown delivery: err = %v", err)
+	}
+}
+
+func TestUpdateWebhookOnlyWritesRequestedFields(t *testing.T) {
+	repo := newMemoryWebhookRepo(testSubscription("https://hooks.example.com/fithero"))
+	service := NewWebhookService(repo, testWebhookConfig(), nil)
+
+	// The delivery worker disables the subscription between the admin's read and write
+	repo.beforeUpdate = func() {
+		repo.RecordFailure(context.Background(), 1, 1, "1 consecutive failed deliveries, last: timeout", time.Now())
+	}
+	description := "  Analytics  "
+	updated, err := service.UpdateWebhook(context.Background(), 1, &models.UpdateWebhookRequest{Description: &description})
+	if err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+	if updated.Description != "Analytics" {
+		t.Errorf("description = %q, want Analytics", updated.Description)
+	}
+	if updated.IsActive || updated.ConsecutiveFailures != 1 || updated.DisabledReason == "" {
+		t.Errorf("description change overwrote the worker's failure: active = %v, failures = %d, reason = %q",
+			updated.IsActive, updated.ConsecutiveFailures, updated.DisabledReason)
+	}
+
+	// Enabling it clears the failures
+	repo.beforeUpdate = nil
+	active := true
+	updated, err = service.UpdateWebhook(context.Background(), 1, &models.UpdateWebhookRequest{IsActive: &active})
+	if err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+	if !updated.IsActive || updated.ConsecutiveFailures != 0 || updated.DisabledAt != nil || updated.DisabledReason != "" {
+		t.Errorf("enabled webhook: active = %v, failures = %d, disabled at %v, reason = %q",
+			updated.IsActive, updated.ConsecutiveFailures, updated.DisabledAt, updated.DisabledReason)
+	}
+}
+
+func TestWebhookDeliveryHeldBackWhenDisabledAfterClaim(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	repo := newMemoryWebhookRepo(testSubscription(receiver.URL))
+	service := NewWebhookService(repo, testWebhookConfig(), nil)
+	id := queueEvent(t, service, repo, testOutboxEvent())
+
+	now := time.Now()
+	claimed, err := repo.ClaimDueDeliveries(context.Background(), now, now.Add(time.Minute), 10)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimDueDeliveries = %d deliveries, %v", len(claimed), err)
+	}
+	inactive := false
+	if _, err := service.UpdateWebhook(context.Background(), 1, &models.UpdateWebhookRequest{IsActive: &inactive}); err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+
+	service.deliver(context.Background(), &claimed[0])
+	if got := len(receiver.received()); got != 0 {
+		t.Fatalf("disabled webhook received %d requests", got)
+	}
+	delivery := repo.delivery(id)
+	if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != 0 || delivery.NextAttemptAt.After(time.Now()) {
+		t.Errorf("held back delivery: status = %q, attempts = %d, next attempt at %v", delivery.Status, delivery.Attempts, delivery.NextAttemptAt)
+	}
+
+	// Sent once the subscription is enabled again
+	active := true
+	if _, err := service.UpdateWebhook(context.Background(), 1, &models.UpdateWebhookRequest{IsActive: &active}); err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+	service.deliverBatch(context.Background())
+	if got := len(receiver.received()); got != 1 {
+		t.Errorf("receiver got %d requests after enabling, want 1", got)
+	}
+	if delivery := repo.delivery(id); delivery.Status != models.WebhookDeliverySucceeded || delivery.Attempts != 1 {
+		t.Errorf("delivery status = %q after %d attempts, want succeeded after 1", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	repo := newMemoryWebhookRepo(testSubscription(receiver.URL))
+	cfg := testWebhookConfig()
+	cfg.AllowPrivateNetworks = false
+	service := NewWebhookService(repo, cfg, nil)
+	id := queueEvent(t, service, repo, testOutboxEvent())
+
+	service.deliverBatch(context.Background())
+	if got := len(receiver.received()); got != 0 {
+		t.Fatalf("receiver on a loopback address got %d requests", got)
+	}
+	if delivery := repo.delivery(id); !strings.Contains(delivery.Error, "is not public") {
+		t.Errorf("delivery error = %q, want the address to be refused", delivery.Error)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"100.63.255.255", true},
+		{"100.128.0.0", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"100.64.0.1", false},
+		{"100.127.255.255", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a9fe:a9fe", false},
+		{"64:ff9b::7f00:1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.public {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.public)
+		}
+	}
+}
+
+func TestValidateURLRejectsPrivateNetworks(t *testing.T) {
+	cfg := testWebhookConfig()
+	cfg.AllowPrivateNetworks = false
+	service := NewWebhookService(nil, cfg, nil)
+
+	for _, rawURL := range []string{
+		"https://localhost/hook",
+		"https://127.0.0.1/hook",
+		"https://100.64.12.1/hook",
+		"https://[64:ff9b::a00:1]/hook",
+	} {
+		if err := service.validateURL(rawURL); err == nil || err.Error() != "webhook url must not point to a private network" {
+			t.Errorf("validateURL(%s): err = %v", rawURL, err)
+		}
+	}
+	if err := service.validateURL("https://hooks.example.com/fithero"); err != nil {
+		t.Errorf("validateURL of a public URL: %v", err)
+	}
+}
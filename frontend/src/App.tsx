@@ -6,6 +6,7 @@ import { QueryClient, QueryClientProvider } from 'react-query';
 import { AuthProvider } from './contexts/AuthContext';
 import ProtectedRoute from './components/ProtectedRoute';
 import AuthCallback from './components/AuthCallback';
+import EmailAuth from './components/EmailAuth';
 import Home from './components/Home';
 import Dashboard from './components/Dashboard';
 import Achievements from './components/Achievements';
@@ -112,6 +113,9 @@ function App() {
                 <Route path="/leaderboard" element={<ProtectedRoute><Leaderboard /></ProtectedRoute>} />
                 <Route path="/profile" element={<ProtectedRoute><Profile /></ProtectedRoute>} />
                 <Route path="/auth-callback" element={<AuthCallback />} />
+                <Route path="/auth/magic-link" element={<EmailAuth mode="magic-link" />} />
+                <Route path="/auth/verify-email" element={<EmailAuth mode="verify-email" />} />
+                <Route path="/auth/reset-password" element={<EmailAuth mode="reset-password" />} />
               </Routes>
             </div>
           </Router>
@@ -4,13 +4,27 @@ import {
   Task,
   DailyTask,
   Achievement,
+  AchievementWithStatus,
   UserAchievement,
+  Loadout,
+  UpdateLoadoutRequest,
   CreateUserRequest,
+  UpdateProfileRequest,
+  ChangeUsernameRequest,
+  PublicProfile,
   GenerateDailyTasksRequest,
   UnlockAchievementRequest,
   AuthUser,
   AuthResponse,
+  Session,
+  IdentityProvider,
+  LinkedIdentity,
+  RegisterRequest,
+  PersonalAccessToken,
+  CreateAccessTokenRequest,
+  CreateAccessTokenResponse,
   TaskCompletionResponse,
+  TaskEvidence,
   AchievementUnlockResponse
 } from '../types';
 
@@ -24,10 +38,25 @@ const apiClient = axios.create({
   withCredentials: true, // Enable cookies for authentication
 });
 
+// CSRF token for cookie-authenticated requests, issued by /auth/check
+let csrfToken: string | null = null;
+
+const fetchCsrfToken = (): Promise<void> =>
+  apiClient.get('/auth/check').then(res => {
+    csrfToken = res.data.csrf_token ?? null;
+  });
+
+const isMutatingMethod = (method?: string) =>
+  ['post', 'put', 'patch', 'delete'].includes((method || 'get').toLowerCase());
+
 // Add request interceptor to handle authentication
 apiClient.interceptors.request.use(
   (config) => {
-    // The JWT token will be automatically sent via HTTP-only cookies
+    // The JWT token will be automatically sent via HTTP-only cookies;
+    // state-changing requests also echo the CSRF token
+    if (csrfToken && isMutatingMethod(config.method)) {
+      config.headers['X-CSRF-Token'] = csrfToken;
+    }
     return config;
   },
   (error) => {
@@ -35,10 +64,50 @@ apiClient.interceptors.request.use(
   }
 );
 
+// Access tokens are short-lived; share one refresh between concurrent 401s
+let refreshPromise: Promise<void> | null = null;
+
+const refreshSession = (): Promise<void> => {
+  if (!refreshPromise) {
+    refreshPromise = apiClient
+      .post('/auth/refresh')
+      .then(() => undefined)
+      .finally(() => {
+        refreshPromise = null;
+      });
+  }
+  return refreshPromise;
+};
+
 // Add response interceptor to handle authentication errors
 apiClient.interceptors.response.use(
   (response) => response,
   async (error) => {
+    const originalRequest = error.config;
+    const isAuthRequest = originalRequest?.url?.startsWith('/auth/');
+
+    // The CSRF token is missing or belongs to a previous session; fetch a new one and retry once
+    if (error.response?.status === 403 && error.response.data?.error === 'Invalid or missing CSRF token' &&
+        originalRequest && !originalRequest._csrfRetry) {
+      originalRequest._csrfRetry = true;
+      try {
+        await fetchCsrfToken();
+        return apiClient(originalRequest);
+      } catch {
+        // Not signed in
+      }
+    }
+
+    if (error.response?.status === 401 && originalRequest && !originalRequest._retry && !isAuthRequest) {
+      originalRequest._retry = true;
+      try {
+        await refreshSession();
+        return apiClient(originalRequest);
+      } catch {
+        // Refresh token missing, expired or revoked
+      }
+    }
+
     if (error.response?.status === 401) {
       // Redirect to login or handle unauthorized access
       console.warn('Unauthorized request, user may need to re-authenticate');
@@ -49,21 +118,63 @@ apiClient.interceptors.response.use(
 
 // Authentication API
 export const authAPI = {
-  // Initiate Google OAuth login
-  googleLogin: async (): Promise<void> => {
+  // List configured identity providers
+  getProviders: (): Promise<IdentityProvider[]> =>
+    apiClient.get('/auth/providers').then(res => res.data.providers),
+
+  // Initiate OIDC login, or link the provider to the signed-in user.
+  // returnTo is a path (or allow-listed URL) to open after login.
+  login: async (provider: string = 'google', link: boolean = false, returnTo?: string): Promise<void> => {
     try {
-      const response = await apiClient.get('/auth/google');
+      const params: Record<string, string> = {};
+      if (link) params.link = 'true';
+      if (returnTo) params.return_to = returnTo;
+      const response = await apiClient.get(`/auth/oidc/${provider}`, { params });
       const authUrl = response.data.auth_url;
       window.location.href = authUrl;
     } catch (error) {
-      console.error('Failed to initiate Google login:', error);
+      console.error(`Failed to initiate ${provider} login:`, error);
       throw error;
     }
   },
 
+  // Initiate Google OAuth login
+  googleLogin: (): Promise<void> => authAPI.login('google'),
+
+  // Email a single-use sign-in link
+  requestMagicLink: (email: string): Promise<void> =>
+    apiClient.post('/auth/email/magic-link', { email }).then(res => res.data),
+
+  // Sign in with an emailed link token
+  magicLinkLogin: (token: string): Promise<AuthResponse> =>
+    apiClient.post('/auth/email/magic-link/verify', { token }).then(res => res.data),
+
+  // Sign up with email and password
+  register: (data: RegisterRequest): Promise<void> =>
+    apiClient.post('/auth/register', data).then(res => res.data),
+
+  // Sign in with email and password
+  passwordLogin: (email: string, password: string): Promise<AuthResponse> =>
+    apiClient.post('/auth/login', { email, password }).then(res => res.data),
+
+  // Confirm an email address with an emailed token
+  verifyEmail: (token: string): Promise<void> =>
+    apiClient.post('/auth/email/verify', { token }).then(res => res.data),
+
+  // Request a password reset link
+  forgotPassword: (email: string): Promise<void> =>
+    apiClient.post('/auth/password/forgot', { email }).then(res => res.data),
+
+  // Set a new password with an emailed token
+  resetPassword: (token: string, password: string): Promise<void> =>
+    apiClient.post('/auth/password/reset', { token, password }).then(res => res.data),
+
   // Check authentication status
   checkAuth: (): Promise<AuthUser> =>
-    apiClient.get('/auth/check').then(res => res.data.user),
+    apiClient.get('/auth/check').then(res => {
+      csrfToken = res.data.csrf_token ?? null;
+      return res.data.user;
+    }),
 
   // Get current user profile
   me: (): Promise<AuthUser> =>
@@ -74,8 +185,40 @@ export const authAPI = {
     apiClient.post('/auth/logout').then(res => res.data),
 
   // Refresh token
-  refresh: (): Promise<{ token: string }> =>
+  refresh: (): Promise<{ expires_at: string }> =>
     apiClient.post('/auth/refresh').then(res => res.data),
+
+  // List signed-in devices
+  getSessions: (): Promise<Session[]> =>
+    apiClient.get('/auth/sessions').then(res => res.data.sessions),
+
+  // Sign out a single device
+  revokeSession: (sessionId: number): Promise<void> =>
+    apiClient.delete(`/auth/sessions/${sessionId}`).then(res => res.data),
+
+  // List linked provider accounts
+  getIdentities: (): Promise<LinkedIdentity[]> =>
+    apiClient.get('/auth/identities').then(res => res.data.identities),
+
+  // Unlink a provider account
+  unlinkIdentity: (identityId: number): Promise<void> =>
+    apiClient.delete(`/auth/identities/${identityId}`).then(res => res.data),
+
+  // List personal access tokens
+  getAccessTokens: (): Promise<PersonalAccessToken[]> =>
+    apiClient.get('/auth/tokens').then(res => res.data.tokens),
+
+  // Create a personal access token; the token is only returned once
+  createAccessToken: (data: CreateAccessTokenRequest): Promise<CreateAccessTokenResponse> =>
+    apiClient.post('/auth/tokens', data).then(res => res.data),
+
+  // Revoke a personal access token
+  revokeAccessToken: (tokenId: number): Promise<void> =>
+    apiClient.delete(`/auth/tokens/${tokenId}`).then(res => res.data),
+
+  // Sign out everywhere, optionally staying signed in on this device
+  revokeAllSessions: (keepCurrent: boolean = false): Promise<void> =>
+    apiClient.delete(`/auth/sessions${keepCurrent ? '?keep_current=true' : ''}`).then(res => res.data),
 };
 
 // User API
@@ -86,7 +229,7 @@ export const userAPI = {
   getById: (id: number): Promise<User> =>
     apiClient.get(`/users/${id}`).then(res => res.data),
   
-  update: (id: number, userData: Partial<User>): Promise<void> =>
+  update: (id: number, userData: UpdateProfileRequest): Promise<void> =>
     apiClient.put(`/users/${id}`, userData).then(res => res.data),
 
   // Get current user profile
@@ -94,7 +237,7 @@ export const userAPI = {
     apiClient.get('/profile').then(res => res.data),
 
   // Update current user profile
-  updateCurrentProfile: (userData: Partial<User>): Promise<void> =>
+  updateCurrentProfile: (userData: UpdateProfileRequest): Promise<void> =>
     apiClient.put('/profile', userData).then(res => res.data),
 
   // Get current user's tasks
@@ -110,8 +253,24 @@ export const userAPI = {
     apiClient.get('/profile').then(res => res.data),
 
   // Update user profile
-  updateProfile: (userData: Partial<User>): Promise<User> =>
+  updateProfile: (userData: UpdateProfileRequest): Promise<User> =>
     apiClient.put('/profile', userData).then(res => res.data),
+
+  // Change username (limited to once per cooldown period)
+  changeUsername: (req: ChangeUsernameRequest): Promise<User> =>
+    apiClient.put('/profile/username', req).then(res => res.data.user),
+
+  // Get a public profile; previous usernames redirect to the current one
+  getPublicProfile: (username: string): Promise<PublicProfile> =>
+    apiClient.get(`/public/users/${encodeURIComponent(username)}`).then(res => res.data.profile),
+
+  // Get equipped character, job title and showcase badges
+  getLoadout: (): Promise<Loadout> =>
+    apiClient.get('/profile/loadout').then(res => res.data.loadout),
+
+  // Equip owned achievements (0 unequips a slot)
+  updateLoadout: (loadout: UpdateLoadoutRequest): Promise<Loadout> =>
+    apiClient.put('/profile/loadout', loadout).then(res => res.data.loadout),
 };
 
 // Task API
@@ -125,14 +284,38 @@ export const taskAPI = {
   generateDailyTasks: (): Promise<DailyTask[]> =>
     apiClient.post('/tasks/daily/generate').then(res => res.data.tasks),
   
-  completeTask: (taskId: number): Promise<TaskCompletionResponse> =>
-    apiClient.post(`/tasks/daily/${taskId}/complete`).then(res => res.data),
+  completeTask: (taskId: number, evidence?: TaskEvidence): Promise<TaskCompletionResponse> => {
+    if (evidence?.photo) {
+      const form = new FormData();
+      form.append('photo', evidence.photo);
+      if (evidence.note) form.append('note', evidence.note);
+      if (evidence.value !== undefined) form.append('value', String(evidence.value));
+      return apiClient.post(`/tasks/daily/${taskId}/complete`, form, {
+        headers: { 'Content-Type': 'multipart/form-data' },
+      }).then(res => res.data);
+    }
+    return apiClient.post(`/tasks/daily/${taskId}/complete`, evidence).then(res => res.data);
+  },
+
+  // Pending completions the current user can review
+  getVerificationQueue: (): Promise<DailyTask[]> =>
+    apiClient.get('/verifications').then(res => res.data.tasks),
+
+  approveCompletion: (dailyTaskId: number): Promise<DailyTask> =>
+    apiClient.post(`/verifications/${dailyTaskId}/approve`).then(res => res.data.daily_task),
+
+  rejectCompletion: (dailyTaskId: number, reason: string): Promise<DailyTask> =>
+    apiClient.post(`/verifications/${dailyTaskId}/reject`, { reason }).then(res => res.data.daily_task),
 };
 
 // Achievement API
 export const achievementAPI = {
   getAll: (): Promise<Achievement[]> =>
     apiClient.get('/public/achievements').then(res => res.data),
+
+  // Get all achievements with locked/available/owned status for the current user
+  getForUser: (): Promise<AchievementWithStatus[]> =>
+    apiClient.get('/achievements/').then(res => res.data),
   
   getUserAchievements: (): Promise<UserAchievement[]> =>
     apiClient.get('/achievements/user').then(res => res.data.achievements),
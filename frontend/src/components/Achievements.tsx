@@ -30,7 +30,7 @@ import {
 } from '@mui/icons-material';
 import { useQuery } from 'react-query';
 import { achievementAPI } from '../api/client';
-import { Achievement, UserAchievement } from '../types';
+import { Achievement, AchievementWithStatus } from '../types';
 import { useUserData, useAchievementActions } from '../hooks';
 
 interface TabPanelProps {
@@ -73,10 +73,10 @@ const Achievements: React.FC = () => {
     hideNotification
   } = useAchievementActions();
 
-  // Fetch all achievements
-  const { data: achievements } = useQuery<Achievement[]>(
-    'achievements',
-    achievementAPI.getAll
+  // Fetch all achievements with their status for the current user
+  const { data: achievements } = useQuery<AchievementWithStatus[]>(
+    ['achievements', userAchievements?.length],
+    achievementAPI.getForUser
   );
 
   const handleUnlock = (achievement: Achievement) => {
@@ -92,10 +92,6 @@ const Achievements: React.FC = () => {
     }
   };
 
-  const isUnlocked = (achievementId: number) => {
-    return userAchievements?.some(ua => ua.achievement_id === achievementId) || false;
-  };
-
   const getTypeIcon = (type: string) => {
     switch (type) {
       case 'character': return <PersonPin />;
@@ -187,7 +183,8 @@ const Achievements: React.FC = () => {
         <TabPanel value={tabValue} index={tabValue}>
           <Grid container spacing={3}>
             {filteredAchievements.map((achievement) => {
-              const unlocked = isUnlocked(achievement.id);
+              const unlocked = achievement.status === 'owned';
+              const locked = achievement.status === 'locked';
               const canAfford = currentPoints >= achievement.points_cost;
               
               return (
@@ -260,11 +257,21 @@ const Achievements: React.FC = () => {
                           {achievement.points_cost} Points
                         </Typography>
 
+                        {/* Lock Reason */}
+                        {locked && achievement.reason && (
+                          <Box display="flex" justifyContent="center" alignItems="center" gap={0.5} mb={1}>
+                            <Lock fontSize="small" color="disabled" />
+                            <Typography variant="body2" color="text.secondary">
+                              {achievement.reason}
+                            </Typography>
+                          </Box>
+                        )}
+
                         {/* Action Button */}
                         <Button
                           fullWidth
                           variant={unlocked ? "outlined" : "contained"}
-                          disabled={unlocked || !canAfford || isUnlockingAchievement}
+                          disabled={unlocked || locked || !canAfford || isUnlockingAchievement}
                           onClick={() => handleUnlock(achievement)}
                           sx={{
                             backgroundColor: unlocked ? undefined : 
@@ -272,6 +279,7 @@ const Achievements: React.FC = () => {
                           }}
                         >
                           {unlocked ? 'Unlocked! ✓' : 
+                           locked ? 'Locked' :
                            canAfford ? 'Unlock' : 
                            'Not Enough Points'}
                         </Button>
@@ -16,8 +16,11 @@ const AuthCallback: React.FC = () => {
         // We just need to check if the user is now authenticated
         await checkAuth();
         
-        // If authentication was successful, redirect to dashboard
-        navigate('/dashboard');
+        // If authentication was successful, open the requested page or the dashboard.
+        // Only same-site paths are followed; the backend validated them when login started.
+        const returnTo = searchParams.get('return_to');
+        const isSafePath = !!returnTo && returnTo.startsWith('/') && !returnTo.startsWith('//') && !returnTo.includes('\\');
+        navigate(returnTo && isSafePath ? returnTo : '/dashboard');
       } catch (err) {
         console.error('Authentication error:', err);
         setError('Authentication failed. Please try again.');
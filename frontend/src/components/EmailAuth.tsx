@@ -0,0 +1,101 @@
+import React, { useEffect, useRef, useState } from 'react';
+import { useNavigate, useSearchParams } from 'react-router-dom';
+import { Box, Button, CircularProgress, TextField, Typography, Alert } from '@mui/material';
+import { authAPI } from '../api/client';
+import { useAuth } from '../contexts/AuthContext';
+
+interface EmailAuthProps {
+  mode: 'magic-link' | 'verify-email' | 'reset-password';
+}
+
+// Landing page for links sent by email: magic link sign-in, email verification and password reset
+const EmailAuth: React.FC<EmailAuthProps> = ({ mode }) => {
+  const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
+  const { checkAuth } = useAuth();
+  const token = searchParams.get('token') || '';
+  const [error, setError] = useState<string | null>(null);
+  const [message, setMessage] = useState<string | null>(null);
+  const [password, setPassword] = useState('');
+  const [submitting, setSubmitting] = useState(false);
+  const redeemed = useRef(false);
+
+  useEffect(() => {
+    // Tokens are single use, so only redeem once even if the effect re-runs
+    if (mode === 'reset-password' || redeemed.current) return;
+    redeemed.current = true;
+
+    const redeem = async () => {
+      try {
+        if (mode === 'magic-link') {
+          await authAPI.magicLinkLogin(token);
+          await checkAuth();
+          navigate('/dashboard');
+        } else {
+          await authAPI.verifyEmail(token);
+          setMessage('Your email address is verified. You can now sign in.');
+        }
+      } catch (err) {
+        console.error('Email link error:', err);
+        setError('This link is invalid or has expired. Please request a new one.');
+      }
+    };
+
+    redeem();
+  }, [mode, token, navigate, checkAuth]);
+
+  const handleReset = async (event: React.FormEvent) => {
+    event.preventDefault();
+    setSubmitting(true);
+    try {
+      await authAPI.resetPassword(token, password);
+      setMessage('Your password has been reset. Please sign in again.');
+    } catch (err) {
+      console.error('Password reset error:', err);
+      setError('This link is invalid or has expired, or the password is too short (10 characters minimum).');
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <Box display="flex" flexDirection="column" alignItems="center" justifyContent="center" minHeight="60vh" gap={2} px={2}>
+      {error && <Alert severity="error" sx={{ maxWidth: 400 }}>{error}</Alert>}
+      {message && <Alert severity="success" sx={{ maxWidth: 400 }}>{message}</Alert>}
+
+      {mode === 'reset-password' && !message && (
+        <Box component="form" onSubmit={handleReset} sx={{ width: '100%', maxWidth: 400 }}>
+          <Typography variant="h6" gutterBottom>Choose a new password</Typography>
+          <TextField
+            type="password"
+            label="New password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            inputProps={{ minLength: 10 }}
+            fullWidth
+            required
+            sx={{ mb: 2 }}
+          />
+          <Button type="submit" variant="contained" disabled={submitting} fullWidth>
+            {submitting ? 'Saving...' : 'Reset password'}
+          </Button>
+        </Box>
+      )}
+
+      {mode !== 'reset-password' && !error && !message && (
+        <>
+          <CircularProgress size={60} />
+          <Typography variant="h6" color="text.secondary">
+            {mode === 'magic-link' ? 'Signing you in...' : 'Verifying your email...'}
+          </Typography>
+        </>
+      )}
+
+      {(message || error) && (
+        <Button variant="text" onClick={() => navigate('/')}>Back to home</Button>
+      )}
+    </Box>
+  );
+};
+
+export default EmailAuth;
@@ -1,6 +1,8 @@
-import React from 'react';
+import React, { useState } from 'react';
 import {
   Container,
+  TextField,
+  Divider,
   Typography,
   Button,
   Card,
@@ -21,10 +23,23 @@ import {
 } from '@mui/icons-material';
 import { useNavigate } from 'react-router-dom';
 import { useAuth } from '../contexts/AuthContext';
+import { authAPI } from '../api/client';
 
 const Home: React.FC = () => {
   const navigate = useNavigate();
   const { user, isAuthenticated, login, isLoading } = useAuth();
+  const [email, setEmail] = useState('');
+  const [magicLinkSent, setMagicLinkSent] = useState(false);
+
+  const sendMagicLink = async (event: React.FormEvent) => {
+    event.preventDefault();
+    try {
+      await authAPI.requestMagicLink(email);
+      setMagicLinkSent(true);
+    } catch (error) {
+      console.error('Failed to send sign-in link:', error);
+    }
+  };
 
   const features = [
     {
@@ -135,9 +150,29 @@ const Home: React.FC = () => {
             {isLoading ? 'Loading...' : 'Sign In with Google 🚀'}
           </Button>
           
-          <Typography variant="caption" color="text.secondary" sx={{ display: 'block', mt: 2, textAlign: 'center' }}>
-            Secure authentication powered by Google OAuth
-          </Typography>
+          <Divider sx={{ my: 3 }}>or</Divider>
+
+          {magicLinkSent ? (
+            <Typography variant="body2" color="text.secondary" textAlign="center">
+              Check your inbox for a sign-in link 📬
+            </Typography>
+          ) : (
+            <Box component="form" onSubmit={sendMagicLink}>
+              <TextField
+                type="email"
+                label="Email address"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                size="small"
+                fullWidth
+                required
+                sx={{ mb: 1 }}
+              />
+              <Button type="submit" variant="outlined" fullWidth>
+                Email me a sign-in link
+              </Button>
+            </Box>
+          )}
         </CardContent>
       </Card>
     </motion.div>
@@ -6,14 +6,17 @@ export interface User {
   points: number;
   character: string;
   job_title: string;
-  google_id?: string;
   first_name?: string;
   last_name?: string;
   picture?: string;
+  timezone: string;
+  current_streak: number;
+  longest_streak: number;
   is_active: boolean;
   last_login_at?: string;
   created_at: string;
   updated_at: string;
+  loadout?: Loadout;
 }
 
 export interface AuthUser {
@@ -28,6 +31,7 @@ export interface AuthUser {
   character: string;
   job_title: string;
   is_active: boolean;
+  email_verified_at?: string;
   last_login_at?: string;
 }
 
@@ -37,6 +41,63 @@ export interface AuthResponse {
   expires_at: string;
 }
 
+export interface Session {
+  id: number;
+  user_id: number;
+  user_agent: string;
+  ip_address: string;
+  last_used_at: string;
+  expires_at: string;
+  created_at: string;
+  current: boolean;
+}
+
+export interface IdentityProvider {
+  name: string;
+  display_name: string;
+}
+
+export interface LinkedIdentity {
+  id: number;
+  user_id: number;
+  provider: string;
+  email: string;
+  last_login_at?: string;
+  created_at: string;
+}
+
+export interface RegisterRequest {
+  email: string;
+  password: string;
+  first_name?: string;
+  last_name?: string;
+}
+
+export type AccessTokenScope = 'read:profile' | 'write:tasks' | 'read:leaderboard';
+
+export interface PersonalAccessToken {
+  id: number;
+  user_id: number;
+  name: string;
+  token_prefix: string;
+  scopes: AccessTokenScope[];
+  expires_at?: string;
+  last_used_at?: string;
+  last_used_ip: string;
+  created_at: string;
+}
+
+export interface CreateAccessTokenRequest {
+  name: string;
+  scopes: AccessTokenScope[];
+  expires_in_days?: number;
+}
+
+export interface CreateAccessTokenResponse {
+  token: string;
+  access_token: PersonalAccessToken;
+}
+
 export interface AuthContextType {
   user: AuthUser | null;
   isAuthenticated: boolean;
@@ -53,6 +114,9 @@ export interface Task {
   points: number;
   category: string;
   difficulty: 'easy' | 'medium' | 'hard';
+  evidence_type: 'none' | 'note' | 'numeric' | 'photo';
+  evidence_unit?: string;
+  verification_mode: 'none' | 'peer' | 'moderator';
 }
 
 export interface DailyTask {
@@ -62,6 +126,14 @@ export interface DailyTask {
   task: Task;
   is_completed: boolean;
   points: number;
+  completed_at?: string;
+  assigned_date: string;
+  expired_at?: string;
+  evidence_note?: string;
+  evidence_value?: number;
+  evidence_photo_url?: string;
+  verification_status?: 'pending' | 'approved' | 'rejected';
+  rejection_reason?: string;
   created_at: string;
   updated_at: string;
 }
@@ -73,6 +145,18 @@ export interface Achievement {
   icon: string;
   points_cost: number;
   type: 'character' | 'upgrade' | 'badge';
+  min_level: number;
+  available_from?: string;
+  available_until?: string;
+  stock_limit?: number;
+  prerequisites?: Achievement[];
+}
+
+export interface AchievementWithStatus extends Achievement {
+  status: 'locked' | 'available' | 'owned';
+  reason?: string;
+  can_afford: boolean;
+  stock_remaining?: number;
 }
 
 export interface UserAchievement {
@@ -81,6 +165,20 @@ export interface UserAchievement {
   achievement_id: number;
   achievement: Achievement;
   unlocked_at: string;
+  is_equipped: boolean;
+  slot_order: number;
+}
+
+export interface Loadout {
+  character: Achievement | null;
+  job_title: Achievement | null;
+  badges: Achievement[];
+}
+
+export interface UpdateLoadoutRequest {
+  character_id?: number;
+  job_title_id?: number;
+  badge_ids?: number[];
 }
 
 export interface CreateUserRequest {
@@ -88,6 +186,32 @@ export interface CreateUserRequest {
   email: string;
 }
 
+// Points, level, character and job title are managed by the server
+export interface UpdateProfileRequest {
+  username?: string;
+  email?: string;
+  first_name?: string;
+  last_name?: string;
+  timezone?: string;
+}
+
+export interface ChangeUsernameRequest {
+  username: string;
+}
+
+export interface PublicProfile {
+  id: number;
+  username: string;
+  first_name: string;
+  picture: string;
+  level: number;
+  points: number;
+  character: string;
+  job_title: string;
+  loadout?: Loadout;
+  created_at: string;
+}
+
 export interface GenerateDailyTasksRequest {
   user_id: number;
 }
@@ -97,9 +221,17 @@ export interface UnlockAchievementRequest {
   achievement_id: number;
 }
 
+export interface TaskEvidence {
+  note?: string;
+  value?: number;
+  photo?: File;
+}
+
 export interface TaskCompletionResponse {
   message: string;
   points_earned: number;
+  points_pending?: number;
+  pending_verification?: boolean;
   level_up?: boolean;
   new_level?: number;
   achievement_unlocked?: boolean;